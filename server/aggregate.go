@@ -0,0 +1,77 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"spotify-charter/aggregate"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"time"
+)
+
+const defaultAggregateScheme = "inverse"
+
+func aggregateCommand(sqlDB *sql.DB, args []string) {
+	if len(args) == 0 || len(args) > 3 {
+		log.Panicln("Usage: aggregate <aggregate chart type> [YYYY-MM-DD] [inverse|borda|streams]")
+	}
+
+	chartType, ok := model.ParseChartType(args[0])
+	if !ok || !chartType.IsAggregate() {
+		log.Panicf("Unknown aggregate chart type '%s'\n", args[0])
+	}
+
+	date := time.Now()
+
+	if len(args) > 1 {
+		var err error
+
+		if date, err = time.Parse("2006-01-02", args[1]); err != nil {
+			log.Panicln(err)
+		}
+	}
+
+	schemeName := os.Getenv("SPOTIFY_CHARTER_AGGREGATE_SCHEME")
+
+	if len(args) > 2 {
+		schemeName = args[2]
+	}
+
+	reader := db.NewReader(sqlDB)
+	defer reader.Close()
+
+	aggregateCharts(sqlDB, reader, chartType, model.TimeToDatestamp(date), schemeName)
+}
+
+func aggregateCharts(sqlDB *sql.DB, reader *db.Reader, chartType model.ChartType, date int64, schemeName string) {
+	if len(schemeName) == 0 {
+		schemeName = defaultAggregateScheme
+	}
+
+	scheme, ok := aggregate.ParseScheme(schemeName)
+	if !ok {
+		log.Panicf("Unknown aggregate scheme '%s'\n", schemeName)
+	}
+
+	from, to := chartType.PeriodBounds(date)
+
+	log.Printf("Aggregating '%s' charts from %s to %s using the '%s' scheme\n", chartType,
+		time.Unix(from, 0).UTC().Format(time.DateOnly), time.Unix(to, 0).UTC().Format(time.DateOnly), schemeName)
+
+	entries := reader.GetChartEntries(model.DailyTopTrack, from, to)
+
+	charts := aggregate.Aggregate(entries, scheme, chartType, from)
+
+	writer := db.NewWriter(sqlDB)
+
+	for countryCode, chartTracks := range charts {
+		if err := writer.ReplaceChart(chartTracks); err != nil {
+			log.Printf("[%s/%s] Rejecting aggregated chart: %s\n", countryCode, chartType, err)
+		}
+	}
+
+	writer.Commit()
+
+	log.Printf("Successfully aggregated '%s' charts for %d countries\n", chartType, len(charts))
+}
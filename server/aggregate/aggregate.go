@@ -0,0 +1,156 @@
+package aggregate
+
+import (
+	"cmp"
+	"sort"
+	"spotify-charter/model"
+)
+
+const MaxPositions = 50
+
+type Scheme interface {
+	Score(position int, chart *Chart, streams int64) float64
+}
+
+type Chart struct {
+	Length  int
+	Streams int64
+	Missing int
+}
+
+type InversePosition struct{}
+
+func (InversePosition) Score(position int, chart *Chart, streams int64) float64 {
+	return 1 / float64(position+1)
+}
+
+type Borda struct{}
+
+func (Borda) Score(position int, chart *Chart, streams int64) float64 {
+	return float64(chart.Length-position) / float64(chart.Length*(chart.Length+1)/2)
+}
+
+type StreamWeighted struct{}
+
+func (StreamWeighted) Score(position int, chart *Chart, streams int64) float64 {
+	if chart.Missing != 0 || chart.Streams == 0 {
+		return Borda{}.Score(position, chart, streams)
+	}
+
+	return float64(streams) / float64(chart.Streams)
+}
+
+var schemes = map[string]Scheme{
+	"inverse": InversePosition{},
+	"borda":   Borda{},
+	"streams": StreamWeighted{},
+}
+
+func ParseScheme(name string) (Scheme, bool) {
+	scheme, ok := schemes[name]
+
+	return scheme, ok
+}
+
+type chartKey struct {
+	countryCode string
+	date        int64
+}
+
+type trackScore struct {
+	trackID string
+	score   float64
+	streams int64
+	best    int
+}
+
+func Aggregate(entries []*model.ChartTrack, scheme Scheme, chartType model.ChartType, date int64) map[string][]*model.ChartTrack {
+	charts := make(map[chartKey]*Chart)
+
+	for _, entry := range entries {
+		key := chartKey{entry.Country.Code, entry.Date}
+
+		chart := charts[key]
+		if chart == nil {
+			chart = &Chart{}
+			charts[key] = chart
+		}
+
+		chart.Length = max(chart.Length, entry.Position+1)
+		chart.Streams += entry.Streams
+
+		if entry.Streams == 0 {
+			chart.Missing++
+		}
+	}
+
+	scores := make(map[string]map[string]*trackScore)
+
+	for _, entry := range entries {
+		countryCode := entry.Country.Code
+
+		if scores[countryCode] == nil {
+			scores[countryCode] = make(map[string]*trackScore)
+		}
+
+		canonicalID := cmp.Or(entry.Track.LinkedFromID, entry.Track.SpotifyID)
+
+		score := scores[countryCode][canonicalID]
+		if score == nil {
+			score = &trackScore{
+				trackID: canonicalID,
+				best:    entry.Position,
+			}
+
+			scores[countryCode][canonicalID] = score
+		}
+
+		score.score += scheme.Score(entry.Position, charts[chartKey{countryCode, entry.Date}], entry.Streams)
+		score.streams += entry.Streams
+
+		if entry.Position < score.best {
+			score.best = entry.Position
+		}
+	}
+
+	aggregated := make(map[string][]*model.ChartTrack)
+
+	for countryCode, trackScores := range scores {
+		ranked := make([]*trackScore, 0, len(trackScores))
+
+		for _, score := range trackScores {
+			ranked = append(ranked, score)
+		}
+
+		sort.Slice(ranked, func(i, j int) bool {
+			if ranked[i].score != ranked[j].score {
+				return ranked[i].score > ranked[j].score
+			}
+
+			if ranked[i].best != ranked[j].best {
+				return ranked[i].best < ranked[j].best
+			}
+
+			return ranked[i].trackID < ranked[j].trackID
+		})
+
+		if len(ranked) > MaxPositions {
+			ranked = ranked[:MaxPositions]
+		}
+
+		country := &model.Country{Code: countryCode}
+
+		for position, score := range ranked {
+			aggregated[countryCode] = append(aggregated[countryCode], &model.ChartTrack{
+				Country:   country,
+				Track:     &model.Track{SpotifyID: score.trackID},
+				ChartType: chartType,
+				Date:      date,
+				Position:  position,
+				Streams:   score.streams,
+			})
+		}
+	}
+
+	return aggregated
+}
@@ -0,0 +1,142 @@
+package aggregate
+
+import (
+	"slices"
+	"spotify-charter/model"
+	"testing"
+)
+
+func chartEntries(date int64, trackIDs []string, streams []int64) []*model.ChartTrack {
+	entries := make([]*model.ChartTrack, 0, len(trackIDs))
+
+	for position, trackID := range trackIDs {
+		entry := &model.ChartTrack{
+			Country:   &model.Country{Code: "SK"},
+			Track:     &model.Track{SpotifyID: trackID},
+			ChartType: model.DailyTopTrack,
+			Date:      date,
+			Position:  position,
+		}
+
+		if streams != nil {
+			entry.Streams = streams[position]
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestStreamWeightedMixesChartsWithAndWithoutStreams(t *testing.T) {
+	entries := chartEntries(1, []string{"a", "b"}, []int64{600, 400})
+	entries = append(entries, chartEntries(2, []string{"b", "a"}, nil)...)
+	entries = append(entries, chartEntries(3, []string{"b", "a"}, []int64{5, 0})...)
+
+	chart := Aggregate(entries, StreamWeighted{}, model.WeeklyAggregate, 1)["SK"]
+
+	if len(chart) != 2 || chart[0].Track.SpotifyID != "b" {
+		t.Fatalf("got %v at the top, want 'b' which led two of three charts", chart[0].Track.SpotifyID)
+	}
+
+	if chart[0].Streams != 405 || chart[1].Streams != 600 {
+		t.Errorf("got %d and %d streams, want the known streams summed", chart[0].Streams, chart[1].Streams)
+	}
+}
+
+func TestSchemes(t *testing.T) {
+	complete := &Chart{Length: 4, Streams: 1000}
+	incomplete := &Chart{Length: 4, Streams: 600, Missing: 1}
+
+	tests := []struct {
+		name     string
+		scheme   Scheme
+		position int
+		chart    *Chart
+		streams  int64
+		want     float64
+	}{
+		{"inverse", InversePosition{}, 0, complete, 400, 1},
+		{"inverse", InversePosition{}, 3, complete, 100, 0.25},
+		{"borda", Borda{}, 0, complete, 400, 0.4},
+		{"borda", Borda{}, 3, complete, 100, 0.1},
+		{"borda", Borda{}, 0, &Chart{Length: 1}, 0, 1},
+		{"streams", StreamWeighted{}, 0, complete, 400, 0.4},
+		{"streams", StreamWeighted{}, 3, complete, 100, 0.1},
+		{"streams", StreamWeighted{}, 0, incomplete, 400, 0.4},
+		{"streams", StreamWeighted{}, 3, incomplete, 0, 0.1},
+		{"streams", StreamWeighted{}, 1, &Chart{Length: 4, Missing: 4}, 0, 0.3},
+	}
+
+	for _, test := range tests {
+		scheme, ok := ParseScheme(test.name)
+		if !ok || scheme != test.scheme {
+			t.Fatalf("ParseScheme(%q) = %T, %t", test.name, scheme, ok)
+		}
+
+		if got := scheme.Score(test.position, test.chart, test.streams); got < test.want-1e-9 || got > test.want+1e-9 {
+			t.Errorf("%s.Score(%d, %+v, %d) = %g, want %g", test.name, test.position, test.chart, test.streams, got, test.want)
+		}
+	}
+
+	if _, ok := ParseScheme("median"); ok {
+		t.Errorf("parsed an unknown scheme")
+	}
+}
+
+func TestAggregateRanking(t *testing.T) {
+	entries := chartEntries(1, []string{"a", "b", "c"}, []int64{100, 800, 100})
+	entries = append(entries, chartEntries(2, []string{"c", "a", "b"}, []int64{100, 100, 800})...)
+
+	tests := []struct {
+		scheme Scheme
+		want   []string
+	}{
+		{InversePosition{}, []string{"a", "c", "b"}},
+		{Borda{}, []string{"a", "c", "b"}},
+		{StreamWeighted{}, []string{"b", "a", "c"}},
+	}
+
+	for _, test := range tests {
+		chart := Aggregate(entries, test.scheme, model.WeeklyAggregate, 1)["SK"]
+
+		got := make([]string, 0, len(chart))
+		for _, entry := range chart {
+			got = append(got, entry.Track.SpotifyID)
+		}
+
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%T ranked %v, want %v", test.scheme, got, test.want)
+		}
+	}
+}
+
+func TestSharesDoNotFavourLongerCharts(t *testing.T) {
+	for _, scheme := range []Scheme{Borda{}, StreamWeighted{}} {
+		for _, length := range []int{1, 50, 200} {
+			chart := &Chart{Length: length, Missing: length}
+
+			total := 0.0
+			for position := range length {
+				total += scheme.Score(position, chart, 0)
+			}
+
+			if total < 1-1e-9 || total > 1+1e-9 {
+				t.Errorf("%T gives a %d-row chart %g points in total, want 1", scheme, length, total)
+			}
+		}
+	}
+}
+
+func TestAggregateStoresCanonicalTracks(t *testing.T) {
+	entries := chartEntries(1, []string{"m1", "b"}, []int64{600, 400})
+	entries = append(entries, chartEntries(2, []string{"t1", "b"}, []int64{600, 400})...)
+
+	entries[0].Track.LinkedFromID = "t1"
+
+	chart := Aggregate(entries, Borda{}, model.WeeklyAggregate, 1)["SK"]
+
+	if len(chart) != 2 || chart[0].Track.SpotifyID != "t1" || chart[0].Streams != 1200 {
+		t.Errorf("got %s with %d streams at the top, want 't1' with the streams of both market versions", chart[0].Track.SpotifyID, chart[0].Streams)
+	}
+}
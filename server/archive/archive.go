@@ -0,0 +1,171 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"spotify-charter/model"
+	"sync"
+)
+
+const (
+	indexFile  = "index.jsonl"
+	objectsDir = "objects"
+	objectExt  = ".json.gz"
+)
+
+type Entry struct {
+	Kind        model.ResponseKind `json:"kind"`
+	CountryCode string             `json:"country_code,omitempty"`
+	ChartType   model.ChartType    `json:"chart_type,omitempty"`
+	Date        int64              `json:"date"`
+	CapturedAt  int64              `json:"captured_at"`
+	Hash        string             `json:"hash"`
+}
+
+type Archive struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+func Open(dir string) (*Archive, error) {
+	if err := os.MkdirAll(filepath.Join(dir, objectsDir), 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Archive{
+		dir: dir,
+	}, nil
+}
+
+func (a *Archive) Store(entry *Entry, body []byte) error {
+	hash := sha256.Sum256(body)
+	entry.Hash = hex.EncodeToString(hash[:])
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if err := a.writeObject(entry.Hash, body); err != nil {
+		return err
+	}
+
+	index, err := os.OpenFile(filepath.Join(a.dir, indexFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	defer index.Close()
+
+	_, err = index.Write(append(line, '\n'))
+
+	return err
+}
+
+func (a *Archive) Entries() ([]*Entry, error) {
+	index, err := os.Open(filepath.Join(a.dir, indexFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return make([]*Entry, 0), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer index.Close()
+
+	entries := make([]*Entry, 0)
+
+	scanner := bufio.NewScanner(index)
+
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		entry := &Entry{}
+
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CapturedAt < entries[j].CapturedAt
+	})
+
+	return entries, nil
+}
+
+func (a *Archive) Load(hash string) ([]byte, error) {
+	object, err := os.Open(a.objectPath(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	defer object.Close()
+
+	reader, err := gzip.NewReader(object)
+	if err != nil {
+		return nil, err
+	}
+
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+func (a *Archive) writeObject(hash string, body []byte) error {
+	path := a.objectPath(hash)
+
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	buffer := new(bytes.Buffer)
+
+	writer := gzip.NewWriter(buffer)
+
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+
+	if err := os.WriteFile(tmpPath, buffer.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func (a *Archive) objectPath(hash string) string {
+	return filepath.Join(a.dir, objectsDir, hash[:2], hash+objectExt)
+}
@@ -0,0 +1,117 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"spotify-charter/model"
+	"testing"
+)
+
+func TestStoreDeduplicatesObjects(t *testing.T) {
+	dir := t.TempDir()
+
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(`{"items":[]}`)
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	first := &Entry{Kind: model.PlaylistResponse, CountryCode: "SK", ChartType: model.DailyTopTrack, Date: 1792368000, CapturedAt: 2}
+	second := &Entry{Kind: model.PlaylistResponse, CountryCode: "CZ", ChartType: model.DailyTopTrack, Date: 1792368000, CapturedAt: 1}
+
+	for _, entry := range []*Entry{first, second} {
+		if err := a.Store(entry, body); err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.Hash != hash {
+			t.Errorf("Store set hash %q, want %q", entry.Hash, hash)
+		}
+	}
+
+	objects, err := filepath.Glob(filepath.Join(dir, objectsDir, "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{filepath.Join(dir, objectsDir, hash[:2], hash+objectExt)}; !reflect.DeepEqual(objects, want) {
+		t.Errorf("stored objects %v, want %v", objects, want)
+	}
+
+	loaded, err := a.Load(hash)
+	if err != nil || string(loaded) != string(body) {
+		t.Errorf("Load returned %q, %v, want %q", loaded, err, body)
+	}
+
+	entries, err := a.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(entries, []*Entry{second, first}) {
+		t.Errorf("Entries returned %+v, want both entries ordered by capture time", entries)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		index   *string
+		want    []*Entry
+		wantErr bool
+	}{
+		{
+			name: "missing index",
+			want: []*Entry{},
+		},
+		{
+			name:  "blank lines",
+			index: ptr("\n{\"kind\":\"TRACKS\",\"date\":1,\"captured_at\":5,\"hash\":\"b\"}\n  \n{\"kind\":\"ALBUMS\",\"date\":1,\"captured_at\":5,\"hash\":\"a\"}\n"),
+			want: []*Entry{
+				{Kind: model.TracksResponse, Date: 1, CapturedAt: 5, Hash: "b"},
+				{Kind: model.AlbumsResponse, Date: 1, CapturedAt: 5, Hash: "a"},
+			},
+		},
+		{
+			name:    "corrupt line",
+			index:   ptr("{\"kind\":\"TRACKS\",\"date\":1,\"captured_at\":5,\"hash\":\"b\"}\n{\"kind\":\n"),
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			if test.index != nil {
+				if err := os.WriteFile(filepath.Join(dir, indexFile), []byte(*test.index), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			a, err := Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := a.Entries()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Entries returned error %v, want error %v", err, test.wantErr)
+			}
+
+			if !test.wantErr && !reflect.DeepEqual(entries, test.want) {
+				t.Errorf("Entries returned %+v, want %+v", entries, test.want)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"log"
+	"os"
+	"path/filepath"
+	"spotify-charter/archive"
+	"spotify-charter/backfill"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"time"
+)
+
+func backfillCharts(sqlDB *sql.DB, args []string) {
+	if len(args) != 1 {
+		log.Panicln("Usage: backfill <charts CSV directory>")
+	}
+
+	files, err := filepath.Glob(filepath.Join(args[0], "*.csv"))
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	log.Printf("Backfilling charts from %d CSV files in '%s'\n", len(files), args[0])
+
+	responseArchive := initArchive()
+
+	writer := db.NewWriter(sqlDB)
+
+	imported := 0
+
+	for _, file := range files {
+		chart, ok := backfill.ParseFileName(filepath.Base(file))
+		if !ok {
+			log.Printf("Skipping unrecognized charts file '%s'\n", file)
+			continue
+		}
+
+		if err := backfillChart(writer, responseArchive, chart, file); err != nil {
+			log.Printf("Backfilling charts file '%s' failed: %s\n", file, err)
+			continue
+		}
+
+		imported++
+	}
+
+	writer.Commit()
+
+	log.Printf("Successfully backfilled %d of %d charts files\n", imported, len(files))
+}
+
+func backfillChart(writer *db.Writer, responseArchive *archive.Archive, chart *backfill.Chart, file string) error {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	entries, err := backfill.ReadEntries(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if err := saveBackfillEntries(writer, chart, entries); err != nil {
+		return err
+	}
+
+	log.Printf("[%s/%s] Backfilled %d entries from '%s'\n", chart.CountryCode, chart.ChartType, len(entries), file)
+
+	if responseArchive == nil {
+		return nil
+	}
+
+	entry := &archive.Entry{
+		Kind:        model.ChartsCSVResponse,
+		CountryCode: chart.CountryCode,
+		ChartType:   chart.ChartType,
+		Date:        chart.Date,
+		CapturedAt:  time.Now().Unix(),
+	}
+
+	if err := responseArchive.Store(entry, body); err != nil {
+		log.Printf("Archiving charts file '%s' failed: %s\n", file, err)
+	}
+
+	return nil
+}
+
+func saveBackfillEntries(writer *db.Writer, chart *backfill.Chart, entries []*backfill.Entry) error {
+	country := &model.Country{Code: chart.CountryCode}
+
+	for _, entry := range entries {
+		chartTrack := &model.ChartTrack{
+			Country: country,
+			Track: &model.Track{
+				SpotifyID: entry.TrackID,
+				Name:      entry.TrackName,
+			},
+			ChartType: chart.ChartType,
+			Date:      chart.Date,
+			Position:  entry.Position,
+			Streams:   entry.Streams,
+		}
+
+		if err := writer.SaveChartEntry(chartTrack); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
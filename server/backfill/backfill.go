@@ -0,0 +1,181 @@
+package backfill
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"spotify-charter/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const globalRegion = "global"
+
+var fileNamePattern = regexp.MustCompile(`^(regional|viral)-([a-z]{2}|global)-(daily|weekly)-(\d{4}-\d{2}-\d{2})`)
+
+var chartTypes = map[string]model.ChartType{
+	"regional-daily":  model.DailyTopTrack,
+	"regional-weekly": model.WeeklyTopTrack,
+	"viral-daily":     model.DailyViralTrack,
+}
+
+var columnNames = map[string][]string{
+	"position": {"position", "rank"},
+	"track":    {"track name", "track_name"},
+	"artist":   {"artist", "artist_names"},
+	"streams":  {"streams"},
+	"url":      {"url", "uri"},
+}
+
+var trackIDPattern = regexp.MustCompile(`(?:open\.spotify\.com/track/|spotify:track:)([A-Za-z0-9]+)`)
+
+var ErrNoHeader = errors.New("no header row found")
+
+type Chart struct {
+	CountryCode string
+	ChartType   model.ChartType
+	Date        int64
+}
+
+type Entry struct {
+	Position  int
+	TrackID   string
+	TrackName string
+	Artist    string
+	Streams   int64
+}
+
+func ParseFileName(name string) (*Chart, bool) {
+	match := fileNamePattern.FindStringSubmatch(strings.ToLower(name))
+	if match == nil {
+		return nil, false
+	}
+
+	chartType, ok := chartTypes[match[1]+"-"+match[3]]
+	if !ok {
+		return nil, false
+	}
+
+	date, err := time.Parse("2006-01-02", match[4])
+	if err != nil {
+		return nil, false
+	}
+
+	countryCode := strings.ToUpper(match[2])
+	if match[2] == globalRegion {
+		countryCode = model.GlobalCountryCode
+	}
+
+	start, _ := chartType.PeriodBounds(model.TimeToDatestamp(date))
+
+	return &Chart{
+		CountryCode: countryCode,
+		ChartType:   chartType,
+		Date:        start,
+	}, true
+}
+
+func ReadEntries(r io.Reader) ([]*Entry, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+
+	columns, err := readHeader(csvReader)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0)
+
+	record, err := csvReader.Read()
+
+	for len(record) != 0 && err == nil {
+		entry, entryErr := parseEntry(record, columns)
+		if entryErr != nil {
+			return nil, entryErr
+		}
+
+		entries = append(entries, entry)
+
+		record, err = csvReader.Read()
+	}
+
+	if err != io.EOF {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func readHeader(csvReader *csv.Reader) (map[string]int, error) {
+	record, err := csvReader.Read()
+
+	for len(record) != 0 && err == nil {
+		columns := make(map[string]int)
+
+		for index, name := range record {
+			name = strings.ToLower(strings.TrimSpace(name))
+
+			for column, aliases := range columnNames {
+				for _, alias := range aliases {
+					if name == alias {
+						columns[column] = index
+					}
+				}
+			}
+		}
+
+		_, hasPosition := columns["position"]
+		_, hasURL := columns["url"]
+
+		if hasPosition && hasURL {
+			return columns, nil
+		}
+
+		record, err = csvReader.Read()
+	}
+
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return nil, ErrNoHeader
+}
+
+func parseEntry(record []string, columns map[string]int) (*Entry, error) {
+	field := func(column string) string {
+		index, ok := columns[column]
+		if !ok || index >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[index])
+	}
+
+	position, err := strconv.Atoi(field("position"))
+	if err != nil || position < 1 {
+		return nil, fmt.Errorf("invalid position '%s'", field("position"))
+	}
+
+	match := trackIDPattern.FindStringSubmatch(field("url"))
+	if match == nil {
+		return nil, fmt.Errorf("invalid track URL '%s' at position %d", field("url"), position)
+	}
+
+	entry := &Entry{
+		Position:  position - 1,
+		TrackID:   match[1],
+		TrackName: field("track"),
+		Artist:    field("artist"),
+	}
+
+	if streams := field("streams"); len(streams) != 0 {
+		if entry.Streams, err = strconv.ParseInt(strings.ReplaceAll(streams, ",", ""), 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid streams '%s' at position %d", streams, position)
+		}
+	}
+
+	return entry, nil
+}
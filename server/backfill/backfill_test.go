@@ -0,0 +1,126 @@
+package backfill
+
+import (
+	"errors"
+	"reflect"
+	"spotify-charter/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func datestamp(t *testing.T, date string) int64 {
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return model.TimeToDatestamp(parsed)
+}
+
+func TestParseFileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		chart *Chart
+	}{
+		{"regional-sk-daily-2026-10-19.csv", &Chart{"SK", model.DailyTopTrack, datestamp(t, "2026-10-19")}},
+		{"Regional-CZ-Daily-2026-10-19.csv", &Chart{"CZ", model.DailyTopTrack, datestamp(t, "2026-10-19")}},
+		{"regional-global-daily-2026-10-19.csv", &Chart{model.GlobalCountryCode, model.DailyTopTrack, datestamp(t, "2026-10-19")}},
+		{"regional-sk-weekly-2026-10-22.csv", &Chart{"SK", model.WeeklyTopTrack, datestamp(t, "2026-10-16")}},
+		{"viral-sk-daily-2026-10-19.csv", &Chart{"SK", model.DailyViralTrack, datestamp(t, "2026-10-19")}},
+		{"viral-sk-weekly-2026-10-19.csv", nil},
+		{"regional-sk-daily-2026-13-45.csv", nil},
+		{"regional-svk-daily-2026-10-19.csv", nil},
+		{"charts.csv", nil},
+	}
+
+	for _, test := range tests {
+		chart, ok := ParseFileName(test.name)
+
+		if ok != (test.chart != nil) || !reflect.DeepEqual(chart, test.chart) {
+			t.Errorf("ParseFileName(%q) = %+v, %t, want %+v", test.name, chart, ok, test.chart)
+		}
+	}
+}
+
+func TestReadEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		entries []*Entry
+		err     string
+	}{
+		{
+			name: "legacy export with a note above the header",
+			csv: "Note that these figures are generated using a formula that protects against any artificial inflation of chart positions.,,,,\n" +
+				"Position,Track Name,Artist,Streams,URL\n" +
+				"1,One,Art One,\"12,345\",https://open.spotify.com/track/t1\n" +
+				"2,Two,Art Two,678,https://open.spotify.com/track/t2?si=abc\n",
+			entries: []*Entry{
+				{Position: 0, TrackID: "t1", TrackName: "One", Artist: "Art One", Streams: 12345},
+				{Position: 1, TrackID: "t2", TrackName: "Two", Artist: "Art Two", Streams: 678},
+			},
+		},
+		{
+			name: "current export with URIs and reordered columns",
+			csv: "rank,uri,artist_names,track_name,source,streams\n" +
+				"1,spotify:track:t3,Art Three,Three,Label,900\n",
+			entries: []*Entry{
+				{Position: 0, TrackID: "t3", TrackName: "Three", Artist: "Art Three", Streams: 900},
+			},
+		},
+		{
+			name: "viral export without streams",
+			csv: "Position,Track Name,Artist,URL\n" +
+				"1,Four,Art Four,https://open.spotify.com/track/t4\n",
+			entries: []*Entry{
+				{Position: 0, TrackID: "t4", TrackName: "Four", Artist: "Art Four"},
+			},
+		},
+		{
+			name: "no header",
+			csv:  "1,One,Art One,100,https://open.spotify.com/track/t1\n",
+			err:  ErrNoHeader.Error(),
+		},
+		{
+			name: "invalid position",
+			csv:  "rank,uri\n0,spotify:track:t1\n",
+			err:  "invalid position '0'",
+		},
+		{
+			name: "invalid track URL",
+			csv:  "rank,uri\n1,https://open.spotify.com/album/a1\n",
+			err:  "invalid track URL 'https://open.spotify.com/album/a1' at position 1",
+		},
+		{
+			name: "invalid streams",
+			csv:  "rank,uri,streams\n1,spotify:track:t1,many\n",
+			err:  "invalid streams 'many' at position 1",
+		},
+	}
+
+	for _, test := range tests {
+		entries, err := ReadEntries(strings.NewReader(test.csv))
+
+		if len(test.err) != 0 {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(entries, test.entries) {
+			t.Errorf("%s: got entries %+v, want %+v", test.name, entries, test.entries)
+		}
+	}
+
+	if _, err := ReadEntries(strings.NewReader("")); !errors.Is(err, ErrNoHeader) {
+		t.Errorf("an empty file got error %v, want %v", err, ErrNoHeader)
+	}
+}
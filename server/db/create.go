@@ -13,26 +13,44 @@ const (
 	crTracks
 	crArtistsTracks
 	crChartTracks
+	crChartSources
+	crTrackPopularity
+	crAlbumCopyrights
+	crArtistGenres
+	crArtistStats
+	crAudioFeatures
+	crAudioFeaturesMisses
+	crChartUpdates
+	crIngests
+	crWebhooks
+	crWebhookDeliveries
+	crWebhookAttempts
+	crEvents
 )
 
 var createSqls = map[int]string{
 	crCountries: `
 		CREATE TABLE IF NOT EXISTS countries (
 			code TEXT NOT NULL PRIMARY KEY,
-			name TEXT NOT NULL,
-			top_playlist_id TEXT
+			name TEXT NOT NULL
 		);`,
 
 	crArtists: `
 		CREATE TABLE IF NOT EXISTS artists (
 			spotify_id TEXT NOT NULL PRIMARY KEY,
-			name TEXT NOT NULL
+			name TEXT NOT NULL,
+			enriched_at NUMERIC
 		);`,
 
 	crAlbums: `
 		CREATE TABLE IF NOT EXISTS albums (
 			spotify_id TEXT NOT NULL PRIMARY KEY,
-			name TEXT NOT NULL
+			name TEXT NOT NULL,
+			release_date TEXT,
+			release_date_precision TEXT,
+			album_type TEXT,
+			total_tracks INTEGER,
+			label TEXT
 		);`,
 
 	crImages: `
@@ -51,6 +69,13 @@ var createSqls = map[int]string{
 			spotify_id TEXT NOT NULL PRIMARY KEY,
 			name TEXT NOT NULL,
 			album_id TEXT NOT NULL,
+			duration_ms INTEGER,
+			explicit INTEGER,
+			disc_number INTEGER,
+			track_number INTEGER,
+			isrc TEXT,
+			preview_url TEXT,
+			linked_from_id TEXT,
 
 			FOREIGN KEY(album_id) REFERENCES albums(spotify_id)
 		);`,
@@ -73,12 +98,196 @@ var createSqls = map[int]string{
 			chart_type TEXT NOT NULL,
 			date NUMERIC NOT NULL,
 			position NUMERIC NOT NULL,
+			is_playable INTEGER,
+			streams INTEGER,
 
 			PRIMARY KEY(country_code, chart_type, date, position),
 
 			FOREIGN KEY(country_code) REFERENCES countries(code),
 			FOREIGN KEY(track_id) REFERENCES tracks(spotify_id)
 		);`,
+
+	crChartSources: `
+		CREATE TABLE IF NOT EXISTS chart_sources (
+			country_code TEXT NOT NULL,
+			chart_type TEXT NOT NULL,
+			playlist_id TEXT NOT NULL,
+			broken_at NUMERIC,
+			broken_reason TEXT,
+
+			PRIMARY KEY(country_code, chart_type),
+
+			FOREIGN KEY(country_code) REFERENCES countries(code)
+		);`,
+
+	crTrackPopularity: `
+		CREATE TABLE IF NOT EXISTS track_popularity (
+			track_id TEXT NOT NULL,
+			date NUMERIC NOT NULL,
+			popularity INTEGER NOT NULL,
+
+			PRIMARY KEY(track_id, date),
+
+			FOREIGN KEY(track_id) REFERENCES tracks(spotify_id)
+		);`,
+
+	crAlbumCopyrights: `
+		CREATE TABLE IF NOT EXISTS album_copyrights (
+			album_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			text TEXT NOT NULL,
+
+			PRIMARY KEY(album_id, type),
+
+			FOREIGN KEY(album_id) REFERENCES albums(spotify_id)
+		);`,
+
+	crArtistGenres: `
+		CREATE TABLE IF NOT EXISTS artist_genres (
+			artist_id TEXT NOT NULL,
+			genre TEXT NOT NULL,
+
+			PRIMARY KEY(artist_id, genre),
+
+			FOREIGN KEY(artist_id) REFERENCES artists(spotify_id)
+		);`,
+
+	crArtistStats: `
+		CREATE TABLE IF NOT EXISTS artist_stats (
+			artist_id TEXT NOT NULL,
+			date NUMERIC NOT NULL,
+			popularity INTEGER NOT NULL,
+			followers INTEGER NOT NULL,
+
+			PRIMARY KEY(artist_id, date),
+
+			FOREIGN KEY(artist_id) REFERENCES artists(spotify_id)
+		);`,
+
+	crAudioFeatures: `
+		CREATE TABLE IF NOT EXISTS audio_features (
+			track_id TEXT NOT NULL PRIMARY KEY,
+			tempo REAL NOT NULL,
+			key INTEGER NOT NULL,
+			mode INTEGER NOT NULL,
+			time_signature INTEGER NOT NULL,
+			energy REAL NOT NULL,
+			danceability REAL NOT NULL,
+			valence REAL NOT NULL,
+			acousticness REAL NOT NULL,
+			instrumentalness REAL NOT NULL,
+			liveness REAL NOT NULL,
+			speechiness REAL NOT NULL,
+			loudness REAL NOT NULL,
+
+			FOREIGN KEY(track_id) REFERENCES tracks(spotify_id)
+		);`,
+
+	crAudioFeaturesMisses: `
+		CREATE TABLE IF NOT EXISTS audio_features_misses (
+			track_id TEXT NOT NULL PRIMARY KEY,
+			date NUMERIC NOT NULL,
+
+			FOREIGN KEY(track_id) REFERENCES tracks(spotify_id)
+		);`,
+
+	crChartUpdates: `
+		CREATE TABLE IF NOT EXISTS chart_updates (
+			country_code TEXT NOT NULL,
+			chart_type TEXT NOT NULL,
+			date NUMERIC NOT NULL,
+			updated_at NUMERIC NOT NULL,
+
+			PRIMARY KEY(country_code, chart_type, date),
+
+			FOREIGN KEY(country_code) REFERENCES countries(code)
+		);`,
+
+	crIngests: `
+		CREATE TABLE IF NOT EXISTS ingests (
+			kind TEXT NOT NULL PRIMARY KEY,
+			updated_at NUMERIC NOT NULL
+		);`,
+
+	crWebhooks: `
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			country_code TEXT,
+			artist_id TEXT,
+			chart_type TEXT,
+			created_at NUMERIC NOT NULL,
+			deleted_at NUMERIC
+		);`,
+
+	crWebhookDeliveries: `
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER NOT NULL,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			next_attempt_at NUMERIC,
+			last_status_code INTEGER,
+			last_error TEXT,
+			replay_of INTEGER,
+			created_at NUMERIC NOT NULL,
+			delivered_at NUMERIC,
+
+			FOREIGN KEY(webhook_id) REFERENCES webhooks(id),
+			FOREIGN KEY(replay_of) REFERENCES webhook_deliveries(id)
+		);`,
+
+	crWebhookAttempts: `
+		CREATE TABLE IF NOT EXISTS webhook_attempts (
+			delivery_id INTEGER NOT NULL,
+			attempted_at NUMERIC NOT NULL,
+			status_code INTEGER,
+			error TEXT,
+			duration_ms INTEGER NOT NULL,
+
+			FOREIGN KEY(delivery_id) REFERENCES webhook_deliveries(id)
+		);`,
+
+	crEvents: `
+		CREATE TABLE IF NOT EXISTS events (
+			id INTEGER NOT NULL PRIMARY KEY,
+			type TEXT NOT NULL,
+			payload TEXT NOT NULL
+		);`,
+}
+
+var createIndexSqls = []string{
+	`CREATE INDEX IF NOT EXISTS webhook_deliveries_event ON webhook_deliveries (webhook_id, event_id);`,
+	`CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);`,
+}
+
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+var addedColumns = []column{
+	{"tracks", "duration_ms", "INTEGER"},
+	{"tracks", "explicit", "INTEGER"},
+	{"tracks", "disc_number", "INTEGER"},
+	{"tracks", "track_number", "INTEGER"},
+	{"tracks", "isrc", "TEXT"},
+	{"tracks", "preview_url", "TEXT"},
+	{"albums", "release_date", "TEXT"},
+	{"albums", "release_date_precision", "TEXT"},
+	{"albums", "album_type", "TEXT"},
+	{"albums", "total_tracks", "INTEGER"},
+	{"albums", "label", "TEXT"},
+	{"artists", "enriched_at", "NUMERIC"},
+	{"tracks", "linked_from_id", "TEXT"},
+	{"chart_tracks", "is_playable", "INTEGER"},
+	{"chart_tracks", "streams", "INTEGER"},
 }
 
 func CreateTables(db *sql.DB) {
@@ -93,5 +302,32 @@ func CreateTables(db *sql.DB) {
 		}
 	}
 
+	for _, column := range addedColumns {
+		addColumn(tx, column)
+	}
+
+	for _, sql := range createIndexSqls {
+		if _, err := tx.Exec(sql); err != nil {
+			panic(err)
+		}
+	}
+
 	tx.Commit()
 }
+
+func addColumn(tx *sql.Tx, column column) {
+	var count int
+
+	row := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?;", column.table, column.name)
+	if err := row.Scan(&count); err != nil {
+		panic(err)
+	}
+
+	if count != 0 {
+		return
+	}
+
+	if _, err := tx.Exec("ALTER TABLE " + column.table + " ADD COLUMN " + column.name + " " + column.definition + ";"); err != nil {
+		panic(err)
+	}
+}
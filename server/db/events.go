@@ -0,0 +1,115 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"spotify-charter/model"
+)
+
+const (
+	insEvent = iota
+	delOldEvents
+	selRecentEvents
+)
+
+var eventSqls = map[int]string{
+	insEvent: `
+		INSERT INTO events (id, type, payload)
+			VALUES (:id, :type, :payload);`,
+
+	delOldEvents: `
+		DELETE FROM events
+			WHERE id <= (SELECT e.id FROM events e ORDER BY e.id DESC LIMIT 1 OFFSET :keep);`,
+
+	selRecentEvents: `
+		SELECT e.payload FROM events e
+		ORDER BY e.id DESC
+		LIMIT :limit;`,
+}
+
+type EventStore struct {
+	db    *sql.DB
+	stmts map[int]*sql.Stmt
+}
+
+func NewEventStore(db *sql.DB) *EventStore {
+	var err error
+
+	store := &EventStore{
+		db:    db,
+		stmts: make(map[int]*sql.Stmt),
+	}
+
+	for index, sql := range eventSqls {
+		if store.stmts[index], err = store.db.Prepare(sql); err != nil {
+			panic(err)
+		}
+	}
+
+	return store
+}
+
+func (store *EventStore) Close() {
+	store.db = nil
+
+	for index := range eventSqls {
+		if err := store.stmts[index].Close(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (store *EventStore) SaveEvent(event *model.EventExt, keep int) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = store.stmts[insEvent].Exec(
+		sql.Named("id", event.ID),
+		sql.Named("type", event.Type),
+		sql.Named("payload", payload))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = store.stmts[delOldEvents].Exec(sql.Named("keep", keep))
+
+	return err
+}
+
+func (store *EventStore) GetRecentEvents(limit int) []*model.EventExt {
+	rows, err := store.stmts[selRecentEvents].Query(sql.Named("limit", limit))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	events := make([]*model.EventExt, 0)
+
+	for rows.Next() {
+		var payload []byte
+
+		if err := rows.Scan(&payload); err != nil {
+			panic(err)
+		}
+
+		event := &model.EventExt{}
+		if err := json.Unmarshal(payload, event); err != nil {
+			panic(err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	slices.Reverse(events)
+
+	return events
+}
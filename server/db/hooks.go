@@ -0,0 +1,39 @@
+package db
+
+import (
+	"spotify-charter/model"
+	"sync"
+)
+
+type Commit struct {
+	Charts          []model.ChartKey
+	MetadataUpdated bool
+	CommittedAt     int64
+}
+
+type CommitHook func(commit *Commit)
+
+var (
+	commitHooksLock sync.RWMutex
+	commitHooks     []CommitHook
+)
+
+func AddCommitHook(hook CommitHook) {
+	commitHooksLock.Lock()
+	defer commitHooksLock.Unlock()
+
+	commitHooks = append(commitHooks, hook)
+}
+
+func runCommitHooks(commit *Commit) {
+	if len(commit.Charts) == 0 && !commit.MetadataUpdated {
+		return
+	}
+
+	commitHooksLock.RLock()
+	defer commitHooksLock.RUnlock()
+
+	for _, hook := range commitHooks {
+		hook(commit)
+	}
+}
@@ -2,28 +2,75 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"slices"
 	"spotify-charter/model"
 )
 
 const (
-	selPlaylistCountries = iota
+	selChartSources = iota
 	selChartTracks
 	selArtistsByTrack
 	setImagesByAlbum
+	selCountriesWithoutWorkingSource
+	selCopyrightsByAlbum
+	selAlbumsWithoutLabel
+	selArtistsToEnrich
+	selGenresByArtist
+	selTracksWithoutFeatures
+	selAudioFeaturesByTrack
+	selSoundProfiles
+	selUnresolvedTracks
+	selArtistWeeklyStreams
+	selCountryStreams
+	selChartEntries
+	selCountries
+	selCountry
+	selChartTypesByCountry
+	selTrack
+	selAlbum
+	selArtist
+	selChartLastModified
+	selChartUpdatedAt
+	selFeedUpdatedAt
+	selLatestChartDate
+	selLatestChartDates
+	selTracksByIDs
+	selAlbumsByIDs
+	selImagesByAlbumIDs
+	selCopyrightsByAlbumIDs
+	selArtistsByIDs
+	selGenresByArtistIDs
+	selArtistIDsByTrackIDs
+	selAudioFeaturesByTrackIDs
+	selChartEntriesByCountries
+	selChartEntriesByArtists
+	selPreviousChartDate
+	selChartMovements
+	selChartHighlights
 )
 
 var readerSqls = map[int]string{
-	selPlaylistCountries: `
-		SELECT code, name, top_playlist_id
-			FROM countries
-		WHERE top_playlist_id IS NOT NULL;`,
+	selChartSources: `
+		SELECT c.code, c.name, cs.chart_type, cs.playlist_id, cs.broken_at, cs.broken_reason
+			FROM chart_sources cs
+			INNER JOIN countries c ON c.code = cs.country_code;`,
 
 	selChartTracks: `
-		SELECT ct.country_code, ct.position, ct.track_id, t.name AS track_name, t.album_id, a.name AS album_name
+		SELECT ct.country_code, ct.position, t.spotify_id, t.name AS track_name, t.album_id, a.name AS album_name,
+				COALESCE(t.duration_ms, 0), COALESCE(t.explicit, 0),
+				COALESCE((SELECT tp.popularity FROM track_popularity tp
+					WHERE tp.track_id = t.spotify_id AND tp.date BETWEEN ct.date AND :period_end
+					ORDER BY tp.date DESC LIMIT 1), 0),
+				COALESCE(t.disc_number, 0), COALESCE(t.track_number, 0), COALESCE(t.isrc, ''), COALESCE(t.preview_url, ''),
+				COALESCE(a.release_date, ''), COALESCE(a.release_date_precision, ''), COALESCE(a.album_type, ''),
+				COALESCE(a.total_tracks, 0), COALESCE(a.label, ''), COALESCE(t.linked_from_id, ct.track_id), ct.is_playable, ct.streams
 			FROM chart_tracks ct
-			RIGHT JOIN tracks t ON t.spotify_id = ct.track_id 
+			RIGHT JOIN tracks t ON t.spotify_id = COALESCE(
+				(SELECT et.spotify_id FROM tracks et WHERE et.spotify_id = ct.track_id),
+				(SELECT MIN(lt.spotify_id) FROM tracks lt WHERE lt.linked_from_id = ct.track_id))
 			RIGHT JOIN albums a ON a.spotify_id = t.album_id 
-		WHERE ct.chart_type = :chart_type AND ct.date = :date;`,
+		WHERE ct.chart_type = :chart_type AND ct.date = :date AND (:country_code = '' OR ct.country_code = :country_code);`,
 
 	selArtistsByTrack: `
 		SELECT at.artist_id, a.name 
@@ -34,6 +81,286 @@ var readerSqls = map[int]string{
 	setImagesByAlbum: `
 		SELECT i.url, i.width FROM images i
 			WHERE i.album_id = :album_id;`,
+
+	selCountriesWithoutWorkingSource: `
+		SELECT c.code, c.name
+			FROM countries c
+		WHERE NOT EXISTS (
+			SELECT 1 FROM chart_sources cs
+				WHERE cs.country_code = c.code AND cs.chart_type = :chart_type AND cs.broken_at IS NULL
+		)
+		ORDER BY c.code;`,
+
+	selCopyrightsByAlbum: `
+		SELECT ac.type, ac.text FROM album_copyrights ac
+			WHERE ac.album_id = :album_id
+		ORDER BY ac.type;`,
+
+	selAlbumsWithoutLabel: `
+		SELECT a.spotify_id FROM albums a
+			WHERE a.label IS NULL;`,
+
+	selArtistsToEnrich: `
+		SELECT a.spotify_id FROM artists a
+			WHERE a.enriched_at IS NULL OR (a.enriched_at < :date AND EXISTS (
+				SELECT 1 FROM artists_tracks at
+					INNER JOIN chart_tracks ct ON ct.track_id = at.track_id
+				WHERE at.artist_id = a.spotify_id AND (ct.chart_type, ct.date) IN (
+					SELECT json_extract(p.value, '$[0]'), json_extract(p.value, '$[1]') FROM json_each(:periods) p
+				)
+			));`,
+
+	selGenresByArtist: `
+		SELECT ag.genre FROM artist_genres ag
+			WHERE ag.artist_id = :artist_id
+		ORDER BY ag.genre;`,
+
+	selTracksWithoutFeatures: `
+		SELECT DISTINCT ct.track_id FROM chart_tracks ct
+			WHERE NOT EXISTS (
+				SELECT 1 FROM audio_features af
+					WHERE af.track_id = ct.track_id
+			) AND NOT EXISTS (
+				SELECT 1 FROM audio_features_misses afm
+					WHERE afm.track_id = ct.track_id AND afm.date > :missed_before
+			);`,
+
+	selAudioFeaturesByTrack: `
+		SELECT af.tempo, af.key, af.mode, af.time_signature, af.energy, af.danceability, af.valence,
+				af.acousticness, af.instrumentalness, af.liveness, af.speechiness, af.loudness
+			FROM audio_features af
+		WHERE af.track_id = :track_id;`,
+
+	selSoundProfiles: `
+		SELECT ct.country_code, ct.date, COUNT(af.track_id), AVG(af.tempo), AVG(af.energy), AVG(af.danceability),
+				AVG(af.valence), AVG(af.acousticness), AVG(af.instrumentalness), AVG(af.liveness),
+				AVG(af.speechiness), AVG(af.loudness), AVG(af.mode)
+			FROM chart_tracks ct
+			INNER JOIN audio_features af ON af.track_id = ct.track_id
+		WHERE ct.chart_type = :chart_type AND ct.date BETWEEN :from AND :to
+		GROUP BY ct.country_code, ct.date
+		ORDER BY ct.date, ct.country_code;`,
+
+	selUnresolvedTracks: `
+		SELECT DISTINCT ct.track_id FROM chart_tracks ct
+			WHERE NOT EXISTS (
+				SELECT 1 FROM tracks t
+					WHERE t.spotify_id = ct.track_id
+			);`,
+
+	selArtistWeeklyStreams: `
+		SELECT at.artist_id, a.name, ct.date - ((ct.date / 86400 + 3) % 7) * 86400 AS week_start, SUM(ct.streams) AS streams
+			FROM chart_tracks ct
+			INNER JOIN artists_tracks at ON at.track_id = ct.track_id
+			INNER JOIN artists a ON a.spotify_id = at.artist_id
+		WHERE ct.chart_type = :chart_type AND ct.country_code = :country_code
+			AND ct.date BETWEEN :from AND :to AND ct.streams IS NOT NULL
+		GROUP BY at.artist_id, week_start
+		ORDER BY week_start, streams DESC;`,
+
+	selCountryStreams: `
+		WITH totals AS (
+			SELECT ct.country_code, COALESCE(t.linked_from_id, ct.track_id) AS canonical_id, SUM(ct.streams) AS streams
+				FROM chart_tracks ct
+				LEFT JOIN tracks t ON t.spotify_id = ct.track_id
+			WHERE ct.chart_type = :chart_type AND ct.date BETWEEN :from AND :to
+				AND ct.streams IS NOT NULL AND ct.country_code != :global_code
+			GROUP BY ct.country_code, canonical_id
+		), ranked AS (
+			SELECT country_code, canonical_id AS track_id, streams,
+					SUM(streams) OVER (PARTITION BY country_code) AS country_streams,
+					ROW_NUMBER() OVER (PARTITION BY country_code ORDER BY streams DESC) AS track_rank
+				FROM totals
+		)
+		SELECT country_code, country_streams, track_id, streams
+			FROM ranked
+		WHERE track_rank = 1
+		ORDER BY country_streams DESC;`,
+
+	selChartEntries: `
+		SELECT ct.country_code, ct.track_id, COALESCE(t.linked_from_id, ct.track_id), ct.date, ct.position, COALESCE(ct.streams, 0)
+			FROM chart_tracks ct
+			LEFT JOIN tracks t ON t.spotify_id = ct.track_id
+		WHERE ct.chart_type = :chart_type AND ct.date BETWEEN :from AND :to
+		ORDER BY ct.country_code, ct.date, ct.position;`,
+
+	selCountries: `
+		SELECT c.code, c.name FROM countries c
+			ORDER BY c.code;`,
+
+	selCountry: `
+		SELECT c.code, c.name FROM countries c
+			WHERE c.code = :code;`,
+
+	selChartTypesByCountry: `
+		SELECT DISTINCT ct.chart_type FROM chart_tracks ct
+			WHERE ct.country_code = :code
+		ORDER BY ct.chart_type;`,
+
+	selTrack: `
+		SELECT t.spotify_id, t.name, t.album_id, COALESCE(t.duration_ms, 0), COALESCE(t.explicit, 0),
+				COALESCE((SELECT tp.popularity FROM track_popularity tp WHERE tp.track_id = t.spotify_id ORDER BY tp.date DESC LIMIT 1), 0),
+				COALESCE(t.disc_number, 0), COALESCE(t.track_number, 0), COALESCE(t.isrc, ''), COALESCE(t.preview_url, '')
+			FROM tracks t
+		WHERE t.spotify_id = :track_id;`,
+
+	selAlbum: `
+		SELECT a.spotify_id, a.name, COALESCE(a.release_date, ''), COALESCE(a.release_date_precision, ''),
+				COALESCE(a.album_type, ''), COALESCE(a.total_tracks, 0), COALESCE(a.label, '')
+			FROM albums a
+		WHERE a.spotify_id = :album_id;`,
+
+	selArtist: `
+		SELECT a.spotify_id, a.name FROM artists a
+			WHERE a.spotify_id = :artist_id;`,
+
+	selChartLastModified: `
+		SELECT MAX(u.updated_at) FROM (
+			SELECT cu.updated_at FROM chart_updates cu
+				WHERE cu.chart_type = :chart_type AND cu.date = :date AND (:country_code = '' OR cu.country_code = :country_code)
+			UNION ALL
+			SELECT i.updated_at FROM ingests i
+				WHERE i.kind = 'metadata'
+		) u;`,
+
+	selChartUpdatedAt: `
+		SELECT MAX(cu.updated_at) FROM chart_updates cu
+			WHERE cu.chart_type = :chart_type AND cu.date = :date AND (:country_code = '' OR cu.country_code = :country_code)
+				AND EXISTS (SELECT 1 FROM chart_tracks ct
+					WHERE ct.country_code = cu.country_code AND ct.chart_type = cu.chart_type AND ct.date = cu.date);`,
+
+	selFeedUpdatedAt: `
+		SELECT MAX(cu.updated_at) FROM chart_updates cu
+			WHERE cu.chart_type = :chart_type AND (:country_code = '' OR cu.country_code = :country_code)
+				AND EXISTS (SELECT 1 FROM chart_tracks ct
+					WHERE ct.country_code = cu.country_code AND ct.chart_type = cu.chart_type AND ct.date = cu.date
+						AND (:artist_id = '' OR ct.track_id IN (SELECT at.track_id FROM artists_tracks at WHERE at.artist_id = :artist_id)));`,
+
+	selLatestChartDate: `
+		SELECT MAX(ct.date) FROM chart_tracks ct
+			WHERE ct.chart_type = :chart_type AND (:country_code = '' OR ct.country_code = :country_code);`,
+
+	selLatestChartDates: `
+		SELECT ct.country_code, MAX(ct.date) FROM chart_tracks ct
+			WHERE ct.chart_type = :chart_type AND ct.country_code IN (SELECT value FROM json_each(:ids))
+		GROUP BY ct.country_code;`,
+
+	selTracksByIDs: `
+		SELECT t.spotify_id, t.name, t.album_id, COALESCE(t.duration_ms, 0), COALESCE(t.explicit, 0),
+				COALESCE((SELECT tp.popularity FROM track_popularity tp WHERE tp.track_id = t.spotify_id ORDER BY tp.date DESC LIMIT 1), 0),
+				COALESCE(t.disc_number, 0), COALESCE(t.track_number, 0), COALESCE(t.isrc, ''), COALESCE(t.preview_url, '')
+			FROM tracks t
+		WHERE t.spotify_id IN (SELECT value FROM json_each(:ids));`,
+
+	selAlbumsByIDs: `
+		SELECT a.spotify_id, a.name, COALESCE(a.release_date, ''), COALESCE(a.release_date_precision, ''),
+				COALESCE(a.album_type, ''), COALESCE(a.total_tracks, 0), COALESCE(a.label, '')
+			FROM albums a
+		WHERE a.spotify_id IN (SELECT value FROM json_each(:ids));`,
+
+	selImagesByAlbumIDs: `
+		SELECT i.album_id, i.url, i.width FROM images i
+			WHERE i.album_id IN (SELECT value FROM json_each(:ids));`,
+
+	selCopyrightsByAlbumIDs: `
+		SELECT ac.album_id, ac.type, ac.text FROM album_copyrights ac
+			WHERE ac.album_id IN (SELECT value FROM json_each(:ids))
+		ORDER BY ac.album_id, ac.type;`,
+
+	selArtistsByIDs: `
+		SELECT a.spotify_id, a.name FROM artists a
+			WHERE a.spotify_id IN (SELECT value FROM json_each(:ids));`,
+
+	selGenresByArtistIDs: `
+		SELECT ag.artist_id, ag.genre FROM artist_genres ag
+			WHERE ag.artist_id IN (SELECT value FROM json_each(:ids))
+		ORDER BY ag.artist_id, ag.genre;`,
+
+	selArtistIDsByTrackIDs: `
+		SELECT DISTINCT ids.value, at.artist_id FROM json_each(:ids) ids
+			INNER JOIN artists_tracks at ON at.track_id = ids.value
+				OR at.track_id IN (SELECT t.spotify_id FROM tracks t WHERE t.linked_from_id = ids.value);`,
+
+	selAudioFeaturesByTrackIDs: `
+		SELECT af.track_id, af.tempo, af.key, af.mode, af.time_signature, af.energy, af.danceability, af.valence,
+				af.acousticness, af.instrumentalness, af.liveness, af.speechiness, af.loudness
+			FROM audio_features af
+		WHERE af.track_id IN (SELECT value FROM json_each(:ids));`,
+
+	selChartEntriesByCountries: `
+		SELECT ct.country_code, ct.track_id, COALESCE(t.linked_from_id, ct.track_id), ct.position, COALESCE(ct.streams, 0)
+			FROM chart_tracks ct
+			LEFT JOIN tracks t ON t.spotify_id = ct.track_id
+		WHERE ct.chart_type = :chart_type AND ct.date = :date
+			AND ct.country_code IN (SELECT value FROM json_each(:ids))
+		ORDER BY ct.country_code, ct.position;`,
+
+	selChartEntriesByArtists: `
+		SELECT at.artist_id, ct.country_code, ct.track_id, COALESCE(t.linked_from_id, ct.track_id), ct.position, COALESCE(ct.streams, 0)
+			FROM chart_tracks ct
+			INNER JOIN artists_tracks at ON at.track_id = ct.track_id
+			LEFT JOIN tracks t ON t.spotify_id = ct.track_id
+		WHERE ct.chart_type = :chart_type AND ct.date = :date
+			AND at.artist_id IN (SELECT value FROM json_each(:ids))
+		ORDER BY at.artist_id, ct.country_code, ct.position;`,
+
+	selPreviousChartDate: `
+		SELECT COALESCE(MAX(ct.date), 0) FROM chart_tracks ct
+			WHERE ct.country_code = :country_code AND ct.chart_type = :chart_type AND ct.date < :date;`,
+
+	selChartMovements: `
+		WITH current AS (
+			SELECT COALESCE(t.linked_from_id, ct.track_id) AS track_id, MAX(t.name) AS track_name, MIN(ct.position) AS position
+				FROM chart_tracks ct
+				LEFT JOIN tracks t ON t.spotify_id = ct.track_id
+			WHERE ct.country_code = :country_code AND ct.chart_type = :chart_type AND ct.date = :date
+			GROUP BY COALESCE(t.linked_from_id, ct.track_id)
+		), previous AS (
+			SELECT COALESCE(t.linked_from_id, pt.track_id) AS track_id, MAX(t.name) AS track_name, MIN(pt.position) AS position
+				FROM chart_tracks pt
+				LEFT JOIN tracks t ON t.spotify_id = pt.track_id
+			WHERE pt.country_code = :country_code AND pt.chart_type = :chart_type AND pt.date = :previous_date
+			GROUP BY COALESCE(t.linked_from_id, pt.track_id)
+		)
+		SELECT * FROM (
+			SELECT c.track_id, COALESCE(c.track_name, '') AS track_name, c.position, COALESCE(p.position, -1) AS previous_position
+				FROM current c
+				LEFT JOIN previous p ON p.track_id = c.track_id
+			UNION ALL
+			SELECT p.track_id, COALESCE(p.track_name, ''), -1, p.position
+				FROM previous p
+			WHERE p.track_id NOT IN (SELECT c.track_id FROM current c)
+		) ORDER BY position < 0, position, previous_position;`,
+
+	selChartHighlights: `
+		WITH dates AS (
+			SELECT d.country_code, d.date, LAG(d.date) OVER (PARTITION BY d.country_code ORDER BY d.date) AS previous_date,
+					MAX(:min_climb, (d.length * :climb_percent + 99) / 100) AS min_climb
+				FROM (SELECT ct.country_code, ct.date, MAX(ct.position) + 1 AS length FROM chart_tracks ct
+					WHERE ct.chart_type = :chart_type AND ct.date >= :since
+						AND (:country_code = '' OR ct.country_code = :country_code)
+					GROUP BY ct.country_code, ct.date) d
+		), charted AS (
+			SELECT ct.country_code, ct.date, COALESCE(t.linked_from_id, ct.track_id) AS track_id,
+					MAX(t.name) AS track_name, MIN(ct.position) AS position
+				FROM chart_tracks ct
+				LEFT JOIN tracks t ON t.spotify_id = ct.track_id
+			WHERE ct.chart_type = :chart_type AND ct.date >= :since
+				AND (:country_code = '' OR ct.country_code = :country_code)
+				AND (:artist_id = '' OR ct.track_id IN (SELECT at.track_id FROM artists_tracks at WHERE at.artist_id = :artist_id))
+			GROUP BY ct.country_code, ct.date, COALESCE(t.linked_from_id, ct.track_id)
+		)
+		SELECT c.country_code, co.name, c.date, COALESCE(cu.updated_at, c.date), c.track_id, COALESCE(c.track_name, ''),
+				c.position, COALESCE(p.position, -1)
+			FROM charted c
+			INNER JOIN dates d ON d.country_code = c.country_code AND d.date = c.date
+			INNER JOIN countries co ON co.code = c.country_code
+			LEFT JOIN charted p ON p.country_code = c.country_code AND p.date = d.previous_date AND p.track_id = c.track_id
+			LEFT JOIN chart_updates cu ON cu.country_code = c.country_code AND cu.chart_type = :chart_type AND cu.date = c.date
+		WHERE d.previous_date IS NOT NULL
+			AND (p.position IS NULL OR (c.position = 0 AND p.position != 0) OR p.position - c.position >= d.min_climb)
+		ORDER BY c.date DESC, c.country_code, c.position
+		LIMIT :limit;`,
 }
 
 type Reader struct {
@@ -69,8 +396,68 @@ func (reader *Reader) Close() {
 
 }
 
-func (reader *Reader) GetCountriesWithPlaylist() []*model.Country {
-	rows, err := reader.stmts[selPlaylistCountries].Query()
+func (reader *Reader) GetChartSources() []*model.ChartSource {
+	rows, err := reader.stmts[selChartSources].Query()
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	countries := make(map[string]*model.Country)
+	chartSources := make([]*model.ChartSource, 0)
+
+	for rows.Next() {
+		country := model.Country{}
+		chartSource := model.ChartSource{}
+
+		var brokenAt sql.NullInt64
+		var brokenReason sql.NullString
+
+		if err := rows.Scan(&country.Code, &country.Name, &chartSource.ChartType, &chartSource.PlaylistID, &brokenAt, &brokenReason); err != nil {
+			panic(err)
+		}
+
+		chartSource.BrokenAt = brokenAt.Int64
+		chartSource.BrokenReason = brokenReason.String
+
+		if countries[country.Code] == nil {
+			countries[country.Code] = &country
+		}
+
+		chartSource.Country = countries[country.Code]
+
+		chartSources = append(chartSources, &chartSource)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return chartSources
+}
+
+func (reader *Reader) GetChartSourcesExt() []*model.ChartSourceExt {
+	chartSources := reader.GetChartSources()
+
+	chartSourcesExt := make([]*model.ChartSourceExt, 0, len(chartSources))
+
+	for _, chartSource := range chartSources {
+		chartSourcesExt = append(chartSourcesExt, &model.ChartSourceExt{
+			CountryCode:  chartSource.Country.Code,
+			ChartType:    chartSource.ChartType,
+			PlaylistID:   chartSource.PlaylistID,
+			Broken:       chartSource.IsBroken(),
+			BrokenAt:     chartSource.BrokenAt,
+			BrokenReason: chartSource.BrokenReason,
+		})
+	}
+
+	return chartSourcesExt
+}
+
+func (reader *Reader) GetCountriesWithoutWorkingChartSource(chartType model.ChartType) []*model.Country {
+	rows, err := reader.stmts[selCountriesWithoutWorkingSource].Query(sql.Named("chart_type", chartType))
 	if err != nil {
 		panic(err)
 	}
@@ -81,7 +468,8 @@ func (reader *Reader) GetCountriesWithPlaylist() []*model.Country {
 
 	for rows.Next() {
 		country := model.Country{}
-		if err := rows.Scan(&country.Code, &country.Name, &country.TopPlaylistID); err != nil {
+
+		if err := rows.Scan(&country.Code, &country.Name); err != nil {
 			panic(err)
 		}
 
@@ -95,10 +483,14 @@ func (reader *Reader) GetCountriesWithPlaylist() []*model.Country {
 	return countries
 }
 
-func (reader *Reader) GetChartTracksExt(date int64) *model.ChartTracksExt {
+func (reader *Reader) GetChartTracksExt(chartType model.ChartType, date int64, countryCode string) *model.ChartTracksExt {
+	_, periodEnd := chartType.PeriodBounds(date)
+
 	rows, err := reader.stmts[selChartTracks].Query(
-		sql.Named("chart_type", model.DailyTopTrack),
-		sql.Named("date", date))
+		sql.Named("chart_type", chartType),
+		sql.Named("date", date),
+		sql.Named("period_end", periodEnd),
+		sql.Named("country_code", countryCode))
 
 	if err != nil {
 		panic(err)
@@ -107,32 +499,96 @@ func (reader *Reader) GetChartTracksExt(date int64) *model.ChartTracksExt {
 	defer rows.Close()
 
 	chartTracks := make(model.ChartTracksExt)
+	canonicalIDs := make(map[*model.TrackExt]string)
+	trackIDs := make([]string, 0)
+	albumIDs := make([]string, 0)
 
 	for rows.Next() {
 		track := model.TrackExt{}
 
 		var countryCode string
 		var position int
+		var canonicalID string
+		var isPlayable sql.NullBool
+		var streams sql.NullInt64
 
-		if err := rows.Scan(&countryCode, &position, &track.ID, &track.Name, &track.Album.ID, &track.Album.Name); err != nil {
+		if err := rows.Scan(&countryCode, &position, &track.ID, &track.Name, &track.Album.ID, &track.Album.Name,
+			&track.DurationMs, &track.Explicit, &track.Popularity,
+			&track.DiscNumber, &track.TrackNumber, &track.ISRC, &track.PreviewURL,
+			&track.Album.ReleaseDate, &track.Album.ReleaseDatePrecision, &track.Album.AlbumType,
+			&track.Album.TotalTracks, &track.Album.Label, &canonicalID, &isPlayable, &streams); err != nil {
 			panic(err)
 		}
 
-		if chartTracks[countryCode] == nil {
-			chartTracks[countryCode] = make([]*model.TrackExt, 5)
+		if streams.Valid {
+			track.Streams = &streams.Int64
+		}
+
+		if isPlayable.Valid {
+			track.IsPlayable = &isPlayable.Bool
 		}
 
-		track.Artists = reader.getArtistsForTrack(track.ID)
+		if releaseDate, ok := model.ReleaseDateToDatestamp(track.Album.ReleaseDate, track.Album.ReleaseDatePrecision); ok {
+			daysSinceRelease := model.DaysBetween(releaseDate, date)
+			track.DaysSinceRelease = &daysSinceRelease
+		}
 
-		track.Album.Images = reader.getImagesForAlbum(track.Album.ID)
+		for len(chartTracks[countryCode]) <= position {
+			chartTracks[countryCode] = append(chartTracks[countryCode], nil)
+		}
 
 		chartTracks[countryCode][position] = &track
+
+		trackIDs = append(trackIDs, track.ID)
+		albumIDs = append(albumIDs, track.Album.ID)
+		canonicalIDs[&track] = canonicalID
 	}
 
 	if err := rows.Err(); err != nil {
 		panic(err)
 	}
 
+	artistIDs := reader.GetArtistIDsByTrackIDs(trackIDs)
+
+	allArtistIDs := make([]string, 0)
+	for _, trackArtistIDs := range artistIDs {
+		allArtistIDs = append(allArtistIDs, trackArtistIDs...)
+	}
+
+	artists := reader.GetArtistsExtByIDs(allArtistIDs)
+	albums := reader.GetAlbumsExtByIDs(albumIDs)
+	audioFeatures := reader.GetAudioFeaturesByTrackIDs(trackIDs)
+
+	for _, tracks := range chartTracks {
+		for _, track := range tracks {
+			if track == nil {
+				continue
+			}
+
+			track.Artists = make([]model.ArtistExt, 0)
+
+			for _, artistID := range artistIDs[track.ID] {
+				if artist, ok := artists[artistID]; ok {
+					track.Artists = append(track.Artists, *artist)
+				}
+			}
+
+			track.Album.Images = make([]model.ImageExt, 0)
+			track.Album.Copyrights = make([]model.CopyrightExt, 0)
+
+			if album, ok := albums[track.Album.ID]; ok {
+				track.Album.Images = album.Images
+				track.Album.Copyrights = album.Copyrights
+			}
+
+			track.AudioFeatures = audioFeatures[track.ID]
+
+			if canonicalID := canonicalIDs[track]; canonicalID != track.ID {
+				track.MarketTrackID, track.ID = track.ID, canonicalID
+			}
+		}
+	}
+
 	return &chartTracks
 }
 
@@ -156,9 +612,36 @@ func (reader *Reader) getArtistsForTrack(trackID string) []model.ArtistExt {
 		artists = append(artists, artist)
 	}
 
+	for index := range artists {
+		artists[index].Genres = reader.getGenresForArtist(artists[index].ID)
+	}
+
 	return artists
 }
 
+func (reader *Reader) getGenresForArtist(artistID string) []string {
+	rows, err := reader.stmts[selGenresByArtist].Query(sql.Named("artist_id", artistID))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	genres := make([]string, 0)
+
+	for rows.Next() {
+		var genre string
+
+		if err := rows.Scan(&genre); err != nil {
+			panic(err)
+		}
+
+		genres = append(genres, genre)
+	}
+
+	return genres
+}
+
 func (reader *Reader) getImagesForAlbum(albumID string) []model.ImageExt {
 	rows, err := reader.stmts[setImagesByAlbum].Query(sql.Named("album_id", albumID))
 	if err != nil {
@@ -181,3 +664,921 @@ func (reader *Reader) getImagesForAlbum(albumID string) []model.ImageExt {
 
 	return images
 }
+
+func (reader *Reader) getCopyrightsForAlbum(albumID string) []model.CopyrightExt {
+	rows, err := reader.stmts[selCopyrightsByAlbum].Query(sql.Named("album_id", albumID))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	copyrights := make([]model.CopyrightExt, 0)
+
+	for rows.Next() {
+		copyright := model.CopyrightExt{}
+
+		if err := rows.Scan(&copyright.Type, &copyright.Text); err != nil {
+			panic(err)
+		}
+
+		copyrights = append(copyrights, copyright)
+	}
+
+	return copyrights
+}
+
+func (reader *Reader) GetAlbumIDsWithoutLabel() []string {
+	rows, err := reader.stmts[selAlbumsWithoutLabel].Query()
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	albumIDs := make([]string, 0)
+
+	for rows.Next() {
+		var albumID string
+
+		if err := rows.Scan(&albumID); err != nil {
+			panic(err)
+		}
+
+		albumIDs = append(albumIDs, albumID)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return albumIDs
+}
+
+func (reader *Reader) GetArtistIDsToEnrich(date int64) []string {
+	periods := make([][2]any, 0)
+
+	for _, chartType := range slices.Concat(model.ChartTypes, model.AggregateChartTypes) {
+		start, _ := chartType.PeriodBounds(date)
+		periods = append(periods, [2]any{chartType, start})
+	}
+
+	periodsJSON, err := json.Marshal(periods)
+	if err != nil {
+		panic(err)
+	}
+
+	rows, err := reader.stmts[selArtistsToEnrich].Query(
+		sql.Named("date", date),
+		sql.Named("periods", string(periodsJSON)))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	artistIDs := make([]string, 0)
+
+	for rows.Next() {
+		var artistID string
+
+		if err := rows.Scan(&artistID); err != nil {
+			panic(err)
+		}
+
+		artistIDs = append(artistIDs, artistID)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return artistIDs
+}
+
+func (reader *Reader) GetTrackIDsWithoutAudioFeatures(missedBefore int64) []string {
+	rows, err := reader.stmts[selTracksWithoutFeatures].Query(sql.Named("missed_before", missedBefore))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	trackIDs := make([]string, 0)
+
+	for rows.Next() {
+		var trackID string
+
+		if err := rows.Scan(&trackID); err != nil {
+			panic(err)
+		}
+
+		trackIDs = append(trackIDs, trackID)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return trackIDs
+}
+
+func (reader *Reader) getAudioFeaturesForTrack(trackID string) *model.AudioFeaturesExt {
+	features := model.AudioFeaturesExt{}
+
+	err := reader.stmts[selAudioFeaturesByTrack].QueryRow(sql.Named("track_id", trackID)).Scan(
+		&features.Tempo, &features.Key, &features.Mode, &features.TimeSignature, &features.Energy,
+		&features.Danceability, &features.Valence, &features.Acousticness, &features.Instrumentalness,
+		&features.Liveness, &features.Speechiness, &features.Loudness)
+
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return &features
+}
+
+func (reader *Reader) GetSoundProfiles(chartType model.ChartType, from int64, to int64) []*model.SoundProfileExt {
+	rows, err := reader.stmts[selSoundProfiles].Query(
+		sql.Named("chart_type", chartType),
+		sql.Named("from", from),
+		sql.Named("to", to))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	profiles := make([]*model.SoundProfileExt, 0)
+
+	for rows.Next() {
+		profile := model.SoundProfileExt{}
+
+		if err := rows.Scan(&profile.CountryCode, &profile.Date, &profile.Tracks, &profile.Tempo, &profile.Energy,
+			&profile.Danceability, &profile.Valence, &profile.Acousticness, &profile.Instrumentalness,
+			&profile.Liveness, &profile.Speechiness, &profile.Loudness, &profile.Mode); err != nil {
+			panic(err)
+		}
+
+		profiles = append(profiles, &profile)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return profiles
+}
+
+func (reader *Reader) GetUnresolvedTrackIDs() []string {
+	rows, err := reader.stmts[selUnresolvedTracks].Query()
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	trackIDs := make([]string, 0)
+
+	for rows.Next() {
+		var trackID string
+
+		if err := rows.Scan(&trackID); err != nil {
+			panic(err)
+		}
+
+		trackIDs = append(trackIDs, trackID)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return trackIDs
+}
+
+func (reader *Reader) GetArtistWeeklyStreams(chartType model.ChartType, countryCode string, from int64, to int64) []*model.ArtistStreamsExt {
+	rows, err := reader.stmts[selArtistWeeklyStreams].Query(
+		sql.Named("chart_type", chartType),
+		sql.Named("country_code", countryCode),
+		sql.Named("from", from),
+		sql.Named("to", to))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	artistStreams := make([]*model.ArtistStreamsExt, 0)
+
+	for rows.Next() {
+		streams := model.ArtistStreamsExt{}
+
+		if err := rows.Scan(&streams.ArtistID, &streams.Name, &streams.WeekStart, &streams.Streams); err != nil {
+			panic(err)
+		}
+
+		artistStreams = append(artistStreams, &streams)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return artistStreams
+}
+
+func (reader *Reader) GetCountryStreams(chartType model.ChartType, from int64, to int64) []*model.CountryStreamsExt {
+	rows, err := reader.stmts[selCountryStreams].Query(
+		sql.Named("chart_type", chartType),
+		sql.Named("from", from),
+		sql.Named("to", to),
+		sql.Named("global_code", model.GlobalCountryCode))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	countryStreams := make([]*model.CountryStreamsExt, 0)
+
+	for rows.Next() {
+		streams := model.CountryStreamsExt{}
+
+		if err := rows.Scan(&streams.CountryCode, &streams.Streams, &streams.TopTrackID, &streams.TopTrackStreams); err != nil {
+			panic(err)
+		}
+
+		if streams.Streams != 0 {
+			streams.TopTrackShare = float64(streams.TopTrackStreams) / float64(streams.Streams)
+		}
+
+		countryStreams = append(countryStreams, &streams)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return countryStreams
+}
+
+func (reader *Reader) GetChartEntries(chartType model.ChartType, from int64, to int64) []*model.ChartTrack {
+	rows, err := reader.stmts[selChartEntries].Query(
+		sql.Named("chart_type", chartType),
+		sql.Named("from", from),
+		sql.Named("to", to))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	countries := make(map[string]*model.Country)
+	entries := make([]*model.ChartTrack, 0)
+
+	for rows.Next() {
+		country := model.Country{}
+		entry := model.ChartTrack{
+			Track:     &model.Track{},
+			ChartType: chartType,
+		}
+
+		if err := rows.Scan(&country.Code, &entry.Track.SpotifyID, &entry.Track.LinkedFromID, &entry.Date, &entry.Position, &entry.Streams); err != nil {
+			panic(err)
+		}
+
+		if countries[country.Code] == nil {
+			countries[country.Code] = &country
+		}
+
+		entry.Country = countries[country.Code]
+
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return entries
+}
+
+func (reader *Reader) GetCountries() []*model.CountryExt {
+	rows, err := reader.stmts[selCountries].Query()
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	countries := make([]*model.CountryExt, 0)
+
+	for rows.Next() {
+		country := model.CountryExt{}
+
+		if err := rows.Scan(&country.Code, &country.Name); err != nil {
+			panic(err)
+		}
+
+		countries = append(countries, &country)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return countries
+}
+
+func (reader *Reader) GetCountry(code string) *model.CountryExt {
+	country := model.CountryExt{}
+
+	err := reader.stmts[selCountry].QueryRow(sql.Named("code", code)).Scan(&country.Code, &country.Name)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	rows, err := reader.stmts[selChartTypesByCountry].Query(sql.Named("code", code))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	country.ChartTypes = make([]model.ChartType, 0)
+
+	for rows.Next() {
+		var chartType model.ChartType
+
+		if err := rows.Scan(&chartType); err != nil {
+			panic(err)
+		}
+
+		country.ChartTypes = append(country.ChartTypes, chartType)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return &country
+}
+
+func (reader *Reader) GetTrackExt(trackID string) *model.TrackExt {
+	track := model.TrackExt{}
+
+	err := reader.stmts[selTrack].QueryRow(sql.Named("track_id", trackID)).Scan(
+		&track.ID, &track.Name, &track.Album.ID, &track.DurationMs, &track.Explicit, &track.Popularity,
+		&track.DiscNumber, &track.TrackNumber, &track.ISRC, &track.PreviewURL)
+
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	if album := reader.GetAlbumExt(track.Album.ID); album != nil {
+		track.Album = *album
+	}
+
+	track.Artists = reader.getArtistsForTrack(track.ID)
+
+	track.AudioFeatures = reader.getAudioFeaturesForTrack(track.ID)
+
+	return &track
+}
+
+func (reader *Reader) GetAlbumExt(albumID string) *model.AlbumExt {
+	album := model.AlbumExt{}
+
+	err := reader.stmts[selAlbum].QueryRow(sql.Named("album_id", albumID)).Scan(
+		&album.ID, &album.Name, &album.ReleaseDate, &album.ReleaseDatePrecision,
+		&album.AlbumType, &album.TotalTracks, &album.Label)
+
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	album.Images = reader.getImagesForAlbum(album.ID)
+
+	album.Copyrights = reader.getCopyrightsForAlbum(album.ID)
+
+	return &album
+}
+
+func (reader *Reader) GetArtistExt(artistID string) *model.ArtistExt {
+	artist := model.ArtistExt{}
+
+	err := reader.stmts[selArtist].QueryRow(sql.Named("artist_id", artistID)).Scan(&artist.ID, &artist.Name)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	artist.Genres = reader.getGenresForArtist(artist.ID)
+
+	return &artist
+}
+
+func (reader *Reader) GetChartLastModified(chartType model.ChartType, date int64, countryCode string) int64 {
+	var lastModified sql.NullInt64
+
+	err := reader.stmts[selChartLastModified].QueryRow(
+		sql.Named("chart_type", chartType),
+		sql.Named("date", date),
+		sql.Named("country_code", countryCode)).Scan(&lastModified)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastModified.Int64
+}
+
+func (reader *Reader) GetChartUpdatedAt(chartType model.ChartType, date int64, countryCode string) int64 {
+	var updatedAt sql.NullInt64
+
+	err := reader.stmts[selChartUpdatedAt].QueryRow(
+		sql.Named("chart_type", chartType),
+		sql.Named("date", date),
+		sql.Named("country_code", countryCode)).Scan(&updatedAt)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return updatedAt.Int64
+}
+
+func (reader *Reader) GetFeedUpdatedAt(chartType model.ChartType, countryCode string, artistID string) int64 {
+	var updatedAt sql.NullInt64
+
+	err := reader.stmts[selFeedUpdatedAt].QueryRow(
+		sql.Named("chart_type", chartType),
+		sql.Named("country_code", countryCode),
+		sql.Named("artist_id", artistID)).Scan(&updatedAt)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return updatedAt.Int64
+}
+
+func (reader *Reader) GetLatestChartDate(chartType model.ChartType, countryCode string) int64 {
+	var date sql.NullInt64
+
+	err := reader.stmts[selLatestChartDate].QueryRow(
+		sql.Named("chart_type", chartType),
+		sql.Named("country_code", countryCode)).Scan(&date)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return date.Int64
+}
+
+func (reader *Reader) GetLatestChartDates(chartType model.ChartType, countryCodes []string) map[string]int64 {
+	rows, err := reader.stmts[selLatestChartDates].Query(
+		sql.Named("chart_type", chartType),
+		sql.Named("ids", jsonIDs(countryCodes)))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	dates := make(map[string]int64)
+
+	for rows.Next() {
+		var countryCode string
+		var date int64
+
+		if err := rows.Scan(&countryCode, &date); err != nil {
+			panic(err)
+		}
+
+		dates[countryCode] = date
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return dates
+}
+
+func (reader *Reader) GetTracksExtByIDs(trackIDs []string) map[string]*model.TrackExt {
+	rows, err := reader.stmts[selTracksByIDs].Query(sql.Named("ids", jsonIDs(trackIDs)))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	tracks := make(map[string]*model.TrackExt)
+
+	for rows.Next() {
+		track := model.TrackExt{}
+
+		err := rows.Scan(&track.ID, &track.Name, &track.Album.ID, &track.DurationMs, &track.Explicit, &track.Popularity,
+			&track.DiscNumber, &track.TrackNumber, &track.ISRC, &track.PreviewURL)
+
+		if err != nil {
+			panic(err)
+		}
+
+		tracks[track.ID] = &track
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return tracks
+}
+
+func (reader *Reader) GetAlbumsExtByIDs(albumIDs []string) map[string]*model.AlbumExt {
+	rows, err := reader.stmts[selAlbumsByIDs].Query(sql.Named("ids", jsonIDs(albumIDs)))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	albums := make(map[string]*model.AlbumExt)
+
+	for rows.Next() {
+		album := model.AlbumExt{Images: make([]model.ImageExt, 0), Copyrights: make([]model.CopyrightExt, 0)}
+
+		err := rows.Scan(&album.ID, &album.Name, &album.ReleaseDate, &album.ReleaseDatePrecision,
+			&album.AlbumType, &album.TotalTracks, &album.Label)
+
+		if err != nil {
+			panic(err)
+		}
+
+		albums[album.ID] = &album
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	images, err := reader.stmts[selImagesByAlbumIDs].Query(sql.Named("ids", jsonIDs(albumIDs)))
+	if err != nil {
+		panic(err)
+	}
+
+	defer images.Close()
+
+	for images.Next() {
+		var albumID string
+
+		image := model.ImageExt{}
+
+		if err := images.Scan(&albumID, &image.URL, &image.Width); err != nil {
+			panic(err)
+		}
+
+		if album, ok := albums[albumID]; ok {
+			album.Images = append(album.Images, image)
+		}
+	}
+
+	if err := images.Err(); err != nil {
+		panic(err)
+	}
+
+	copyrights, err := reader.stmts[selCopyrightsByAlbumIDs].Query(sql.Named("ids", jsonIDs(albumIDs)))
+	if err != nil {
+		panic(err)
+	}
+
+	defer copyrights.Close()
+
+	for copyrights.Next() {
+		var albumID string
+
+		copyright := model.CopyrightExt{}
+
+		if err := copyrights.Scan(&albumID, &copyright.Type, &copyright.Text); err != nil {
+			panic(err)
+		}
+
+		if album, ok := albums[albumID]; ok {
+			album.Copyrights = append(album.Copyrights, copyright)
+		}
+	}
+
+	if err := copyrights.Err(); err != nil {
+		panic(err)
+	}
+
+	return albums
+}
+
+func (reader *Reader) GetArtistsExtByIDs(artistIDs []string) map[string]*model.ArtistExt {
+	rows, err := reader.stmts[selArtistsByIDs].Query(sql.Named("ids", jsonIDs(artistIDs)))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	artists := make(map[string]*model.ArtistExt)
+
+	for rows.Next() {
+		artist := model.ArtistExt{Genres: make([]string, 0)}
+
+		if err := rows.Scan(&artist.ID, &artist.Name); err != nil {
+			panic(err)
+		}
+
+		artists[artist.ID] = &artist
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	genres, err := reader.stmts[selGenresByArtistIDs].Query(sql.Named("ids", jsonIDs(artistIDs)))
+	if err != nil {
+		panic(err)
+	}
+
+	defer genres.Close()
+
+	for genres.Next() {
+		var artistID, genre string
+
+		if err := genres.Scan(&artistID, &genre); err != nil {
+			panic(err)
+		}
+
+		if artist, ok := artists[artistID]; ok {
+			artist.Genres = append(artist.Genres, genre)
+		}
+	}
+
+	if err := genres.Err(); err != nil {
+		panic(err)
+	}
+
+	return artists
+}
+
+func (reader *Reader) GetArtistIDsByTrackIDs(trackIDs []string) map[string][]string {
+	rows, err := reader.stmts[selArtistIDsByTrackIDs].Query(sql.Named("ids", jsonIDs(trackIDs)))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	artistIDs := make(map[string][]string)
+
+	for rows.Next() {
+		var trackID, artistID string
+
+		if err := rows.Scan(&trackID, &artistID); err != nil {
+			panic(err)
+		}
+
+		artistIDs[trackID] = append(artistIDs[trackID], artistID)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return artistIDs
+}
+
+func (reader *Reader) GetAudioFeaturesByTrackIDs(trackIDs []string) map[string]*model.AudioFeaturesExt {
+	rows, err := reader.stmts[selAudioFeaturesByTrackIDs].Query(sql.Named("ids", jsonIDs(trackIDs)))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	audioFeatures := make(map[string]*model.AudioFeaturesExt)
+
+	for rows.Next() {
+		var trackID string
+
+		features := model.AudioFeaturesExt{}
+
+		err := rows.Scan(&trackID, &features.Tempo, &features.Key, &features.Mode, &features.TimeSignature,
+			&features.Energy, &features.Danceability, &features.Valence, &features.Acousticness,
+			&features.Instrumentalness, &features.Liveness, &features.Speechiness, &features.Loudness)
+
+		if err != nil {
+			panic(err)
+		}
+
+		audioFeatures[trackID] = &features
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return audioFeatures
+}
+
+func (reader *Reader) GetChartEntriesByCountries(chartType model.ChartType, date int64, countryCodes []string) map[string][]*model.ChartTrack {
+	rows, err := reader.stmts[selChartEntriesByCountries].Query(
+		sql.Named("chart_type", chartType),
+		sql.Named("date", date),
+		sql.Named("ids", jsonIDs(countryCodes)))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	chartEntries := make(map[string][]*model.ChartTrack)
+
+	for rows.Next() {
+		chartEntry := &model.ChartTrack{
+			Country:   &model.Country{},
+			Track:     &model.Track{},
+			ChartType: chartType,
+			Date:      date,
+		}
+
+		err := rows.Scan(&chartEntry.Country.Code, &chartEntry.Track.SpotifyID, &chartEntry.Track.LinkedFromID, &chartEntry.Position, &chartEntry.Streams)
+		if err != nil {
+			panic(err)
+		}
+
+		chartEntries[chartEntry.Country.Code] = append(chartEntries[chartEntry.Country.Code], chartEntry)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return chartEntries
+}
+
+func (reader *Reader) GetChartEntriesByArtists(chartType model.ChartType, date int64, artistIDs []string) map[string][]*model.ChartTrack {
+	rows, err := reader.stmts[selChartEntriesByArtists].Query(
+		sql.Named("chart_type", chartType),
+		sql.Named("date", date),
+		sql.Named("ids", jsonIDs(artistIDs)))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	chartEntries := make(map[string][]*model.ChartTrack)
+
+	for rows.Next() {
+		var artistID string
+
+		chartEntry := &model.ChartTrack{
+			Country:   &model.Country{},
+			Track:     &model.Track{},
+			ChartType: chartType,
+			Date:      date,
+		}
+
+		err := rows.Scan(&artistID, &chartEntry.Country.Code, &chartEntry.Track.SpotifyID, &chartEntry.Track.LinkedFromID, &chartEntry.Position, &chartEntry.Streams)
+		if err != nil {
+			panic(err)
+		}
+
+		chartEntries[artistID] = append(chartEntries[artistID], chartEntry)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return chartEntries
+}
+
+func (reader *Reader) GetChartMovements(chartType model.ChartType, date int64, countryCode string) (int64, []*model.ChartMovement) {
+	var previousDate int64
+
+	err := reader.stmts[selPreviousChartDate].QueryRow(
+		sql.Named("country_code", countryCode),
+		sql.Named("chart_type", chartType),
+		sql.Named("date", date)).Scan(&previousDate)
+
+	if err != nil {
+		panic(err)
+	}
+
+	rows, err := reader.stmts[selChartMovements].Query(
+		sql.Named("country_code", countryCode),
+		sql.Named("chart_type", chartType),
+		sql.Named("date", date),
+		sql.Named("previous_date", previousDate))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	movements := make([]*model.ChartMovement, 0)
+
+	for rows.Next() {
+		movement := &model.ChartMovement{Track: &model.Track{}}
+
+		if err := rows.Scan(&movement.Track.SpotifyID, &movement.Track.Name, &movement.Position, &movement.PreviousPosition); err != nil {
+			panic(err)
+		}
+
+		movements = append(movements, movement)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return previousDate, movements
+}
+
+func (reader *Reader) GetChartHighlights(chartType model.ChartType, since int64, countryCode string, artistID string, minClimb int, climbPercent int, limit int) []*model.ChartHighlight {
+	rows, err := reader.stmts[selChartHighlights].Query(
+		sql.Named("chart_type", chartType),
+		sql.Named("since", since),
+		sql.Named("country_code", countryCode),
+		sql.Named("artist_id", artistID),
+		sql.Named("min_climb", minClimb),
+		sql.Named("climb_percent", climbPercent),
+		sql.Named("limit", limit))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	highlights := make([]*model.ChartHighlight, 0)
+
+	for rows.Next() {
+		highlight := &model.ChartHighlight{
+			Country:   &model.Country{},
+			ChartType: chartType,
+			Movement:  &model.ChartMovement{Track: &model.Track{}},
+		}
+
+		err := rows.Scan(&highlight.Country.Code, &highlight.Country.Name, &highlight.Date, &highlight.UpdatedAt,
+			&highlight.Movement.Track.SpotifyID, &highlight.Movement.Track.Name, &highlight.Movement.Position,
+			&highlight.Movement.PreviousPosition)
+
+		if err != nil {
+			panic(err)
+		}
+
+		highlights = append(highlights, highlight)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return highlights
+}
+
+func jsonIDs[T any](ids []T) string {
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(encoded)
+}
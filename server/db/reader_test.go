@@ -0,0 +1,356 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"spotify-charter/model"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	firstDate  = 1792281600
+	secondDate = 1792368000
+)
+
+func newRelinkedDB(t *testing.T) *sql.DB {
+	sqlDB, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "charter.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { sqlDB.Close() })
+
+	CreateTables(sqlDB)
+
+	artist := model.Artist{SpotifyID: "r1", Name: "Artist"}
+	album := model.Album{SpotifyID: "a1", Name: "Album"}
+
+	original := &model.Track{SpotifyID: "t1", Name: "Song", Album: album, Artists: []model.Artist{artist}}
+	relinked := &model.Track{SpotifyID: "m1", Name: "Song", Album: album, Artists: []model.Artist{artist}, LinkedFromID: "t1"}
+	other := &model.Track{SpotifyID: "t2", Name: "Other", Album: album, Artists: []model.Artist{artist}}
+	marketOnly := &model.Track{SpotifyID: "m3", Name: "Third", Album: album, Artists: []model.Artist{artist}, LinkedFromID: "t3"}
+
+	writer := NewWriter(sqlDB)
+	writer.SaveCountry(&model.Country{Code: "SK", Name: "Slovakia"})
+
+	charts := []struct {
+		date   int64
+		tracks []*model.Track
+	}{
+		{firstDate, []*model.Track{original, other}},
+		{secondDate, []*model.Track{other, relinked, marketOnly}},
+	}
+
+	for _, chart := range charts {
+		for position, track := range chart.tracks {
+			err := writer.SaveChartTrack(&model.ChartTrack{
+				Country:   &model.Country{Code: "SK"},
+				Track:     track,
+				ChartType: model.DailyTopTrack,
+				Date:      chart.date,
+				Position:  position,
+				Streams:   int64(300 - 100*position),
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	writer.Commit()
+
+	return sqlDB
+}
+
+func TestQueriesGroupRelinkedTracks(t *testing.T) {
+	reader := NewReader(newRelinkedDB(t))
+	defer reader.Close()
+
+	streams := reader.GetCountryStreams(model.DailyTopTrack, firstDate, secondDate)
+	if len(streams) != 1 || streams[0].TopTrackID != "t1" || streams[0].TopTrackStreams != 500 {
+		t.Errorf("got top track %+v, want 't1' with the streams of both market versions", streams)
+	}
+
+	for _, entry := range reader.GetChartEntries(model.DailyTopTrack, secondDate, secondDate) {
+		if entry.Track.SpotifyID == "m1" && entry.Track.LinkedFromID != "t1" {
+			t.Errorf("chart entry for 'm1' has canonical ID %q, want 't1'", entry.Track.LinkedFromID)
+		}
+	}
+
+	for _, entry := range reader.GetChartEntriesByCountries(model.DailyTopTrack, secondDate, []string{"SK"})["SK"] {
+		if entry.Track.SpotifyID == "m1" && entry.Track.LinkedFromID != "t1" {
+			t.Errorf("GraphQL chart entry for 'm1' has canonical ID %q, want 't1'", entry.Track.LinkedFromID)
+		}
+	}
+
+	for _, highlight := range reader.GetChartHighlights(model.DailyTopTrack, firstDate, "SK", "", 1, 0, 10) {
+		if highlight.Movement.Track.SpotifyID == "t1" || highlight.Movement.Track.SpotifyID == "m1" {
+			t.Errorf("relinked track is highlighted as moving from %d to %d", highlight.Movement.PreviousPosition, highlight.Movement.Position)
+		}
+	}
+}
+
+func TestMovementsFollowRelinkedTracks(t *testing.T) {
+	reader := NewReader(newRelinkedDB(t))
+	defer reader.Close()
+
+	previousDate, movements := reader.GetChartMovements(model.DailyTopTrack, secondDate, "SK")
+	if previousDate != firstDate {
+		t.Fatalf("got previous date %d, want %d", previousDate, firstDate)
+	}
+
+	want := []struct {
+		trackID          string
+		position         int
+		previousPosition int
+	}{
+		{"t2", 0, 1},
+		{"t1", 1, 0},
+		{"t3", 2, model.OffChart},
+	}
+
+	if len(movements) != len(want) {
+		t.Fatalf("got %d movements, want %d", len(movements), len(want))
+	}
+
+	for index, movement := range movements {
+		if movement.Track.SpotifyID != want[index].trackID || movement.Position != want[index].position || movement.PreviousPosition != want[index].previousPosition {
+			t.Errorf("movement %d is %s from %d to %d, want %+v", index, movement.Track.SpotifyID, movement.PreviousPosition, movement.Position, want[index])
+		}
+	}
+
+	artistIDs := reader.GetArtistIDsByTrackIDs([]string{"t3", "m3"})
+	if len(artistIDs["t3"]) != 1 || len(artistIDs["m3"]) != 1 {
+		t.Errorf("got artists %v, want 'r1' for both the canonical and the market ID", artistIDs)
+	}
+}
+
+func TestAggregatedCanonicalTracksResolveToMarketTracks(t *testing.T) {
+	sqlDB := newRelinkedDB(t)
+
+	writer := NewWriter(sqlDB)
+	writer.ReplaceChart([]*model.ChartTrack{{
+		Country:   &model.Country{Code: "SK"},
+		Track:     &model.Track{SpotifyID: "t3"},
+		ChartType: model.WeeklyAggregate,
+		Date:      firstDate,
+	}})
+	writer.Commit()
+
+	reader := NewReader(sqlDB)
+	defer reader.Close()
+
+	tracks := (*reader.GetChartTracksExt(model.WeeklyAggregate, firstDate, ""))["SK"]
+
+	if len(tracks) == 0 || tracks[0] == nil || tracks[0].ID != "t3" || tracks[0].MarketTrackID != "m3" || tracks[0].Name != "Third" || len(tracks[0].Artists) != 1 {
+		t.Errorf("got aggregated entry %+v, want canonical 't3' with the metadata of 'm3'", tracks)
+	}
+}
+
+func TestChartTracksAreBatchedWithoutPadding(t *testing.T) {
+	sqlDB := newRelinkedDB(t)
+
+	writer := NewWriter(sqlDB)
+	writer.SaveAudioFeatures(&model.AudioFeatures{TrackID: "t2", Tempo: 120})
+
+	if err := writer.SaveArtistDetails(&model.ArtistSnapshot{
+		Artist: &model.Artist{SpotifyID: "r1", Name: "Artist", Genres: []string{"pop", "rock"}},
+		Date:   secondDate,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.SaveAlbumDetails(&model.Album{
+		SpotifyID:  "a1",
+		Name:       "Album",
+		Images:     []model.Image{{URL: "https://i.scdn.co/image/a1", Width: 640}},
+		Label:      "Label",
+		Copyrights: []model.Copyright{{Text: "(C) Label", Type: "C"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	writer.Commit()
+
+	reader := NewReader(sqlDB)
+	defer reader.Close()
+
+	tracks := (*reader.GetChartTracksExt(model.DailyTopTrack, secondDate, ""))["SK"]
+
+	if len(tracks) != 3 {
+		t.Fatalf("got %d chart slots, want the 3 stored entries", len(tracks))
+	}
+
+	for position, track := range tracks {
+		if track == nil {
+			t.Fatalf("position %d is empty", position)
+		}
+
+		if len(track.Artists) != 1 || !slices.Equal(track.Artists[0].Genres, []string{"pop", "rock"}) {
+			t.Errorf("position %d has artists %+v, want 'r1' with its genres", position, track.Artists)
+		}
+
+		if len(track.Album.Copyrights) != 1 || track.Album.Copyrights[0].Text != "(C) Label" {
+			t.Errorf("position %d has album copyrights %+v, want the album copyright", position, track.Album.Copyrights)
+		}
+	}
+
+	if tracks[0].ID != "t2" || tracks[0].AudioFeatures == nil || tracks[0].AudioFeatures.Tempo != 120 {
+		t.Errorf("got first entry %q with audio features %+v, want 't2' with its audio features", tracks[0].ID, tracks[0].AudioFeatures)
+	}
+
+	if tracks[1].ID != "t1" || tracks[1].MarketTrackID != "m1" || tracks[1].AudioFeatures != nil {
+		t.Errorf("got second entry %q (market %q), want canonical 't1' of 'm1' without audio features", tracks[1].ID, tracks[1].MarketTrackID)
+	}
+}
+
+func TestChartTracksOfOneCountry(t *testing.T) {
+	sqlDB := newRelinkedDB(t)
+
+	writer := NewWriter(sqlDB)
+	writer.SaveCountry(&model.Country{Code: "CZ", Name: "Czechia"})
+
+	err := writer.SaveChartTrack(&model.ChartTrack{
+		Country:   &model.Country{Code: "CZ"},
+		Track:     &model.Track{SpotifyID: "t2", Name: "Other", Album: model.Album{SpotifyID: "a1", Name: "Album"}},
+		ChartType: model.DailyTopTrack,
+		Date:      secondDate,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer.Commit()
+
+	reader := NewReader(sqlDB)
+	defer reader.Close()
+
+	chartTracks := *reader.GetChartTracksExt(model.DailyTopTrack, secondDate, "CZ")
+
+	if len(chartTracks) != 1 || len(chartTracks["CZ"]) != 1 || chartTracks["CZ"][0].ID != "t2" {
+		t.Errorf("got chart tracks %+v, want only the CZ chart", chartTracks)
+	}
+}
+
+func TestWeeklyChartPopularityIsTrackedPerCaptureDate(t *testing.T) {
+	sqlDB := newRelinkedDB(t)
+
+	weekStart, _ := model.WeeklyTopTrack.PeriodBounds(secondDate)
+	previousWeekStart, _ := model.WeeklyTopTrack.PeriodBounds(weekStart - 86400)
+
+	captures := []struct {
+		date       int64
+		captured   int64
+		popularity int
+	}{
+		{previousWeekStart, weekStart - 86400, 10},
+		{weekStart, secondDate, 40},
+		{weekStart, secondDate + 86400, 60},
+	}
+
+	writer := NewWriter(sqlDB)
+
+	for _, capture := range captures {
+		err := writer.SaveChartTrack(&model.ChartTrack{
+			Country:     &model.Country{Code: "SK"},
+			Track:       &model.Track{SpotifyID: "w1", Name: "Weekly", Album: model.Album{SpotifyID: "a1", Name: "Album"}, Popularity: capture.popularity},
+			ChartType:   model.WeeklyTopTrack,
+			Date:        capture.date,
+			CaptureDate: capture.captured,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writer.Commit()
+
+	var rows int
+	if err := sqlDB.QueryRow("SELECT COUNT(*) FROM track_popularity WHERE track_id = 'w1'").Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+
+	if rows != len(captures) {
+		t.Errorf("got %d popularity rows, want one per capture date", rows)
+	}
+
+	reader := NewReader(sqlDB)
+	defer reader.Close()
+
+	for date, want := range map[int64]int{previousWeekStart: 10, weekStart: 60} {
+		tracks := (*reader.GetChartTracksExt(model.WeeklyTopTrack, date, "SK"))["SK"]
+
+		if len(tracks) != 1 || tracks[0].Popularity != want {
+			t.Errorf("weekly chart of %d has %+v, want popularity %d from its latest capture", date, tracks, want)
+		}
+	}
+}
+
+func TestTracksWithoutAudioFeaturesSkipRecentMisses(t *testing.T) {
+	sqlDB := newRelinkedDB(t)
+
+	writer := NewWriter(sqlDB)
+	writer.SaveAudioFeatures(&model.AudioFeatures{TrackID: "t2", Tempo: 120})
+	writer.SaveAudioFeaturesMiss(&model.AudioFeaturesMiss{TrackID: "m1", Date: secondDate})
+	writer.SaveAudioFeaturesMiss(&model.AudioFeaturesMiss{TrackID: "m3", Date: firstDate})
+	writer.Commit()
+
+	reader := NewReader(sqlDB)
+	defer reader.Close()
+
+	trackIDs := reader.GetTrackIDsWithoutAudioFeatures(firstDate)
+	slices.Sort(trackIDs)
+
+	if !slices.Equal(trackIDs, []string{"m3", "t1"}) {
+		t.Errorf("got tracks %v without audio features, want [m3 t1] with the miss of m1 not yet due for a retry", trackIDs)
+	}
+}
+
+func TestArtistsOfCurrentPeriodsAreReenriched(t *testing.T) {
+	sqlDB := newRelinkedDB(t)
+
+	date := int64(secondDate)
+	weekStart, _ := model.WeeklyTopTrack.PeriodBounds(date)
+	previousWeekStart, _ := model.WeeklyTopTrack.PeriodBounds(weekStart - 86400)
+
+	charts := map[int64]string{weekStart: "r2", previousWeekStart: "r3"}
+
+	writer := NewWriter(sqlDB)
+
+	for chartDate, artistID := range charts {
+		artist := model.Artist{SpotifyID: artistID, Name: artistID}
+
+		err := writer.SaveChartTrack(&model.ChartTrack{
+			Country:   &model.Country{Code: "SK"},
+			Track:     &model.Track{SpotifyID: "w" + artistID, Name: "Weekly", Album: model.Album{SpotifyID: "a1", Name: "Album"}, Artists: []model.Artist{artist}},
+			ChartType: model.WeeklyTopTrack,
+			Date:      chartDate,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, artistID := range []string{"r1", "r2", "r3"} {
+		writer.SaveArtistDetails(&model.ArtistSnapshot{Artist: &model.Artist{SpotifyID: artistID, Name: artistID}, Date: firstDate})
+	}
+
+	writer.Commit()
+
+	reader := NewReader(sqlDB)
+	defer reader.Close()
+
+	artistIDs := reader.GetArtistIDsToEnrich(date)
+	slices.Sort(artistIDs)
+
+	if !slices.Equal(artistIDs, []string{"r1", "r2"}) {
+		t.Errorf("got artists %v to enrich, want [r1 r2] charting in the current day and week", artistIDs)
+	}
+}
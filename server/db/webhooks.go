@@ -0,0 +1,482 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"spotify-charter/model"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var ErrBusy = errors.New("database is busy")
+
+const (
+	insWebhook = iota
+	delWebhook
+	selWebhooks
+	selWebhook
+	insWebhookDelivery
+	selWebhookDelivery
+	selWebhookDeliveries
+	selDueWebhookDeliveries
+	claimWebhookDelivery
+	updWebhookDelivery
+	insWebhookAttempt
+	selWebhookAttempts
+)
+
+var webhookSqls = map[int]string{
+	insWebhook: `
+		INSERT INTO webhooks (url, secret, event_type, country_code, artist_id, chart_type, created_at)
+			VALUES (:url, :secret, :event_type, NULLIF(:country_code, ''), NULLIF(:artist_id, ''), NULLIF(:chart_type, ''), :created_at);`,
+
+	delWebhook: `
+		UPDATE webhooks SET deleted_at = :deleted_at
+			WHERE id = :id AND deleted_at IS NULL;`,
+
+	selWebhooks: `
+		SELECT w.id, w.url, w.secret, w.event_type, COALESCE(w.country_code, ''), COALESCE(w.artist_id, ''),
+				COALESCE(w.chart_type, ''), w.created_at
+			FROM webhooks w
+		WHERE w.deleted_at IS NULL
+		ORDER BY w.id;`,
+
+	selWebhook: `
+		SELECT w.id, w.url, w.secret, w.event_type, COALESCE(w.country_code, ''), COALESCE(w.artist_id, ''),
+				COALESCE(w.chart_type, ''), w.created_at
+			FROM webhooks w
+		WHERE w.id = :id AND w.deleted_at IS NULL;`,
+
+	insWebhookDelivery: `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, replay_of, created_at)
+			SELECT :webhook_id, :event_id, :event_type, :payload, :status, 0, :created_at, NULLIF(:replay_of, 0), :created_at
+			WHERE :replay_of != 0 OR NOT EXISTS (
+				SELECT 1 FROM webhook_deliveries wd
+					WHERE wd.webhook_id = :webhook_id AND wd.event_id = :event_id AND wd.replay_of IS NULL);`,
+
+	selWebhookDelivery: `
+		SELECT wd.id, wd.webhook_id, wd.event_id, wd.event_type, wd.payload, wd.status, wd.attempts,
+				COALESCE(wd.next_attempt_at, 0), COALESCE(wd.last_status_code, 0), COALESCE(wd.last_error, ''),
+				COALESCE(wd.replay_of, 0), wd.created_at, COALESCE(wd.delivered_at, 0)
+			FROM webhook_deliveries wd
+		WHERE wd.id = :id AND wd.webhook_id = :webhook_id;`,
+
+	selWebhookDeliveries: `
+		SELECT wd.id, wd.webhook_id, wd.event_id, wd.event_type, wd.payload, wd.status, wd.attempts,
+				COALESCE(wd.next_attempt_at, 0), COALESCE(wd.last_status_code, 0), COALESCE(wd.last_error, ''),
+				COALESCE(wd.replay_of, 0), wd.created_at, COALESCE(wd.delivered_at, 0)
+			FROM webhook_deliveries wd
+		WHERE wd.webhook_id = :webhook_id
+		ORDER BY wd.id DESC
+		LIMIT :limit;`,
+
+	selDueWebhookDeliveries: `
+		SELECT wd.id, wd.webhook_id, wd.event_id, wd.event_type, wd.payload, wd.status, wd.attempts,
+				COALESCE(wd.next_attempt_at, 0), COALESCE(wd.last_status_code, 0), COALESCE(wd.last_error, ''),
+				COALESCE(wd.replay_of, 0), wd.created_at, COALESCE(wd.delivered_at, 0), w.url, w.secret
+			FROM webhook_deliveries wd
+			INNER JOIN webhooks w ON w.id = wd.webhook_id
+		WHERE wd.status = 'pending' AND wd.next_attempt_at <= :now AND w.deleted_at IS NULL
+			AND wd.webhook_id NOT IN (SELECT value FROM json_each(:excluded))
+		ORDER BY wd.next_attempt_at, wd.id
+		LIMIT :limit;`,
+
+	claimWebhookDelivery: `
+		UPDATE webhook_deliveries SET next_attempt_at = :lease_until
+			WHERE id = :id AND status = 'pending' AND next_attempt_at <= :now;`,
+
+	updWebhookDelivery: `
+		UPDATE webhook_deliveries
+			SET status = :status, attempts = :attempts, next_attempt_at = NULLIF(:next_attempt_at, 0),
+				last_status_code = NULLIF(:last_status_code, 0), last_error = NULLIF(:last_error, ''),
+				delivered_at = NULLIF(:delivered_at, 0)
+		WHERE id = :id;`,
+
+	insWebhookAttempt: `
+		INSERT INTO webhook_attempts (delivery_id, attempted_at, status_code, error, duration_ms)
+			VALUES (:delivery_id, :attempted_at, NULLIF(:status_code, 0), NULLIF(:error, ''), :duration_ms);`,
+
+	selWebhookAttempts: `
+		SELECT wa.delivery_id, wa.attempted_at, COALESCE(wa.status_code, 0), COALESCE(wa.error, ''), wa.duration_ms
+			FROM webhook_attempts wa
+		WHERE wa.delivery_id IN (SELECT value FROM json_each(:ids))
+		ORDER BY wa.delivery_id, wa.attempted_at;`,
+}
+
+type WebhookStore struct {
+	db    *sql.DB
+	stmts map[int]*sql.Stmt
+}
+
+func NewWebhookStore(db *sql.DB) *WebhookStore {
+	var err error
+
+	store := &WebhookStore{
+		db:    db,
+		stmts: make(map[int]*sql.Stmt),
+	}
+
+	for index, sql := range webhookSqls {
+		if store.stmts[index], err = store.db.Prepare(sql); err != nil {
+			panic(err)
+		}
+	}
+
+	return store
+}
+
+func (store *WebhookStore) Close() {
+	store.db = nil
+
+	for index := range webhookSqls {
+		if err := store.stmts[index].Close(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (store *WebhookStore) CreateWebhook(webhook *model.Webhook) error {
+	result, err := store.stmts[insWebhook].Exec(
+		sql.Named("url", webhook.URL),
+		sql.Named("secret", webhook.Secret),
+		sql.Named("event_type", webhook.EventType),
+		sql.Named("country_code", webhook.CountryCode),
+		sql.Named("artist_id", webhook.ArtistID),
+		sql.Named("chart_type", webhook.ChartType),
+		sql.Named("created_at", webhook.CreatedAt))
+
+	if err != nil {
+		return checkBusy(err)
+	}
+
+	if webhook.ID, err = result.LastInsertId(); err != nil {
+		panic(err)
+	}
+
+	return nil
+}
+
+func (store *WebhookStore) DeleteWebhook(id int64, deletedAt int64) (bool, error) {
+	result, err := store.stmts[delWebhook].Exec(
+		sql.Named("id", id),
+		sql.Named("deleted_at", deletedAt))
+
+	if err != nil {
+		return false, checkBusy(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		panic(err)
+	}
+
+	return affected != 0, nil
+}
+
+func (store *WebhookStore) GetWebhooks() []*model.Webhook {
+	rows, err := store.stmts[selWebhooks].Query()
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	webhooks := make([]*model.Webhook, 0)
+
+	for rows.Next() {
+		webhooks = append(webhooks, scanWebhook(rows))
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return webhooks
+}
+
+func (store *WebhookStore) GetWebhook(id int64) *model.Webhook {
+	rows, err := store.stmts[selWebhook].Query(sql.Named("id", id))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var webhook *model.Webhook
+
+	if rows.Next() {
+		webhook = scanWebhook(rows)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return webhook
+}
+
+func (store *WebhookStore) SaveDelivery(delivery *model.WebhookDelivery) (bool, error) {
+	return saveDelivery(store.stmts[insWebhookDelivery], delivery)
+}
+
+func (store *WebhookStore) SaveDeliveries(deliveries []*model.WebhookDelivery) (int, error) {
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return 0, checkBusy(err)
+	}
+
+	defer tx.Rollback()
+
+	stmt := tx.Stmt(store.stmts[insWebhookDelivery])
+
+	saved := 0
+
+	for _, delivery := range deliveries {
+		ok, err := saveDelivery(stmt, delivery)
+		if err != nil {
+			return 0, err
+		}
+
+		if ok {
+			saved++
+		}
+	}
+
+	if err := checkBusy(tx.Commit()); err != nil {
+		return 0, err
+	}
+
+	return saved, nil
+}
+
+func saveDelivery(stmt *sql.Stmt, delivery *model.WebhookDelivery) (bool, error) {
+	result, err := stmt.Exec(
+		sql.Named("webhook_id", delivery.Webhook.ID),
+		sql.Named("event_id", delivery.EventID),
+		sql.Named("event_type", delivery.EventType),
+		sql.Named("payload", delivery.Payload),
+		sql.Named("status", delivery.Status),
+		sql.Named("replay_of", delivery.ReplayOf),
+		sql.Named("created_at", delivery.CreatedAt))
+
+	if err != nil {
+		return false, checkBusy(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		panic(err)
+	}
+
+	if affected == 0 {
+		return false, nil
+	}
+
+	if delivery.ID, err = result.LastInsertId(); err != nil {
+		panic(err)
+	}
+
+	delivery.NextAttemptAt = delivery.CreatedAt
+
+	return true, nil
+}
+
+func (store *WebhookStore) GetDelivery(webhookID int64, id int64) *model.WebhookDelivery {
+	rows, err := store.stmts[selWebhookDelivery].Query(
+		sql.Named("id", id),
+		sql.Named("webhook_id", webhookID))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var delivery *model.WebhookDelivery
+
+	if rows.Next() {
+		delivery = &model.WebhookDelivery{Webhook: &model.Webhook{}}
+		scanDelivery(rows, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return delivery
+}
+
+func (store *WebhookStore) GetDeliveries(webhookID int64, limit int) []*model.WebhookDelivery {
+	rows, err := store.stmts[selWebhookDeliveries].Query(
+		sql.Named("webhook_id", webhookID),
+		sql.Named("limit", limit))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	deliveries := make([]*model.WebhookDelivery, 0)
+
+	for rows.Next() {
+		delivery := &model.WebhookDelivery{Webhook: &model.Webhook{}}
+		scanDelivery(rows, delivery)
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return deliveries
+}
+
+func (store *WebhookStore) GetDueDeliveries(now int64, excludedWebhookIDs []int64, limit int) []*model.WebhookDelivery {
+	rows, err := store.stmts[selDueWebhookDeliveries].Query(
+		sql.Named("now", now),
+		sql.Named("excluded", jsonIDs(excludedWebhookIDs)),
+		sql.Named("limit", limit))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	deliveries := make([]*model.WebhookDelivery, 0)
+
+	for rows.Next() {
+		delivery := &model.WebhookDelivery{Webhook: &model.Webhook{}}
+		scanDelivery(rows, delivery, &delivery.Webhook.URL, &delivery.Webhook.Secret)
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return deliveries
+}
+
+func (store *WebhookStore) ClaimDelivery(id int64, now int64, leaseUntil int64) (bool, error) {
+	result, err := store.stmts[claimWebhookDelivery].Exec(
+		sql.Named("id", id),
+		sql.Named("now", now),
+		sql.Named("lease_until", leaseUntil))
+
+	if err != nil {
+		return false, checkBusy(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		panic(err)
+	}
+
+	return affected != 0, nil
+}
+
+func (store *WebhookStore) SaveAttempt(delivery *model.WebhookDelivery, attempt *model.WebhookAttempt) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return checkBusy(err)
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Stmt(store.stmts[insWebhookAttempt]).Exec(
+		sql.Named("delivery_id", delivery.ID),
+		sql.Named("attempted_at", attempt.AttemptedAt),
+		sql.Named("status_code", attempt.StatusCode),
+		sql.Named("error", attempt.Error),
+		sql.Named("duration_ms", attempt.DurationMs))
+
+	if err != nil {
+		return checkBusy(err)
+	}
+
+	_, err = tx.Stmt(store.stmts[updWebhookDelivery]).Exec(
+		sql.Named("id", delivery.ID),
+		sql.Named("status", delivery.Status),
+		sql.Named("attempts", delivery.Attempts),
+		sql.Named("next_attempt_at", delivery.NextAttemptAt),
+		sql.Named("last_status_code", delivery.LastStatusCode),
+		sql.Named("last_error", delivery.LastError),
+		sql.Named("delivered_at", delivery.DeliveredAt))
+
+	if err != nil {
+		return checkBusy(err)
+	}
+
+	return checkBusy(tx.Commit())
+}
+
+func (store *WebhookStore) GetAttempts(deliveryIDs []int64) map[int64][]*model.WebhookAttempt {
+	rows, err := store.stmts[selWebhookAttempts].Query(sql.Named("ids", jsonIDs(deliveryIDs)))
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	attempts := make(map[int64][]*model.WebhookAttempt)
+
+	for rows.Next() {
+		attempt := &model.WebhookAttempt{}
+
+		if err := rows.Scan(&attempt.DeliveryID, &attempt.AttemptedAt, &attempt.StatusCode, &attempt.Error, &attempt.DurationMs); err != nil {
+			panic(err)
+		}
+
+		attempts[attempt.DeliveryID] = append(attempts[attempt.DeliveryID], attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	return attempts
+}
+
+func scanWebhook(rows *sql.Rows) *model.Webhook {
+	webhook := &model.Webhook{}
+
+	err := rows.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &webhook.EventType, &webhook.CountryCode,
+		&webhook.ArtistID, &webhook.ChartType, &webhook.CreatedAt)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return webhook
+}
+
+func scanDelivery(rows *sql.Rows, delivery *model.WebhookDelivery, extra ...any) {
+	dest := []any{&delivery.ID, &delivery.Webhook.ID, &delivery.EventID, &delivery.EventType, &delivery.Payload,
+		&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastStatusCode, &delivery.LastError,
+		&delivery.ReplayOf, &delivery.CreatedAt, &delivery.DeliveredAt}
+
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		panic(err)
+	}
+}
+
+func checkBusy(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked) {
+		return ErrBusy
+	}
+
+	panic(err)
+}
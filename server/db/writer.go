@@ -3,9 +3,15 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"spotify-charter/model"
+	"time"
 )
 
+var ErrEmptyID = errors.New("empty spotify id")
+
 const (
 	upsCountry = iota
 	upsArtist
@@ -14,14 +20,28 @@ const (
 	upsTrack
 	upsArtistTrack
 	upsChartTrack
+	upsChartSource
+	updChartSourceStatus
+	upsTrackPopularity
+	updAlbumDetails
+	upsAlbumCopyright
+	delArtistGenres
+	insArtistGenre
+	upsArtistStats
+	updArtistEnrichedAt
+	upsAudioFeatures
+	upsAudioFeaturesMiss
+	delChart
+	upsChartUpdate
+	upsIngest
 )
 
 var writerSqls = map[int]string{
 	upsCountry: `
-		INSERT INTO countries (code, name, top_playlist_id)
-			VALUES (:code, :name, :top_playlist_id)
+		INSERT INTO countries (code, name)
+			VALUES (:code, :name)
 		ON CONFLICT (code) DO UPDATE
-			SET name = :name, top_playlist_id = :top_playlist_id
+			SET name = :name
 		WHERE code = :code;`,
 
 	upsArtist: `
@@ -29,28 +49,36 @@ var writerSqls = map[int]string{
 			VALUES(:spotify_id, :name)
 		ON CONFLICT (spotify_id) DO UPDATE
 			SET name = :name
-		WHERE spotify_id = :spotify_id;`,
+		WHERE spotify_id = :spotify_id AND name IS NOT :name;`,
 
 	upsAlbum: `
-		INSERT INTO albums (spotify_id, name)
-			VALUES(:spotify_id, :name)
+		INSERT INTO albums (spotify_id, name, release_date, release_date_precision, album_type, total_tracks)
+			VALUES(:spotify_id, :name, :release_date, :release_date_precision, :album_type, :total_tracks)
 		ON CONFLICT (spotify_id) DO UPDATE
-			SET name = :name
-		WHERE spotify_id = :spotify_id;`,
+			SET name = :name, release_date = :release_date, release_date_precision = :release_date_precision,
+				album_type = :album_type, total_tracks = :total_tracks
+		WHERE spotify_id = :spotify_id AND (name IS NOT :name OR release_date IS NOT :release_date
+			OR release_date_precision IS NOT :release_date_precision OR album_type IS NOT :album_type
+			OR total_tracks IS NOT :total_tracks);`,
 
 	upsImage: `
 		INSERT INTO images (album_id, width, url)
 			VALUES(:album_id, :width, :url)
 		ON CONFLICT (album_id, width) DO UPDATE
 			SET url = :url
-		WHERE album_id = :album_id AND width = :width;`,
+		WHERE album_id = :album_id AND width = :width AND url IS NOT :url;`,
 
 	upsTrack: `
-		INSERT INTO tracks (spotify_id, name, album_id)
-			VALUES(:spotify_id, :name, :album_id)
+		INSERT INTO tracks (spotify_id, name, album_id, duration_ms, explicit, disc_number, track_number, isrc, preview_url, linked_from_id)
+			VALUES(:spotify_id, :name, :album_id, :duration_ms, :explicit, :disc_number, :track_number, :isrc, :preview_url, :linked_from_id)
 		ON CONFLICT (spotify_id) DO UPDATE
-			SET name = :name, album_id = :album_id
-		WHERE spotify_id = :spotify_id;`,
+			SET name = :name, album_id = :album_id, duration_ms = :duration_ms, explicit = :explicit,
+				disc_number = :disc_number, track_number = :track_number, isrc = :isrc, preview_url = :preview_url,
+				linked_from_id = COALESCE(:linked_from_id, linked_from_id)
+		WHERE spotify_id = :spotify_id AND (name IS NOT :name OR album_id IS NOT :album_id
+			OR duration_ms IS NOT :duration_ms OR explicit IS NOT :explicit OR disc_number IS NOT :disc_number
+			OR track_number IS NOT :track_number OR isrc IS NOT :isrc OR preview_url IS NOT :preview_url
+			OR linked_from_id IS NOT COALESCE(:linked_from_id, linked_from_id));`,
 
 	upsArtistTrack: `
 		INSERT INTO artists_tracks (artist_id, track_id)
@@ -58,31 +86,145 @@ var writerSqls = map[int]string{
 		ON CONFLICT (artist_id, track_id) DO NOTHING;`,
 
 	upsChartTrack: `
-		INSERT INTO chart_tracks (country_code, track_id, chart_type, date, position)
-			VALUES(:country_code, :track_id, :chart_type, :date, :position)
+		INSERT INTO chart_tracks (country_code, track_id, chart_type, date, position, is_playable, streams)
+			VALUES(:country_code, :track_id, :chart_type, :date, :position, :is_playable, :streams)
 		ON CONFLICT (country_code, chart_type, date, position) DO UPDATE
-			SET track_id = :track_id
+			SET track_id = :track_id, is_playable = COALESCE(:is_playable, is_playable), streams = COALESCE(:streams, streams)
 		WHERE country_code = :country_code AND chart_type = :chart_type AND date = :date AND position = :position;`,
+
+	upsChartSource: `
+		INSERT INTO chart_sources (country_code, chart_type, playlist_id)
+			VALUES(:country_code, :chart_type, :playlist_id)
+		ON CONFLICT (country_code, chart_type) DO UPDATE
+			SET playlist_id = :playlist_id,
+				broken_at = CASE WHEN playlist_id = :playlist_id THEN broken_at ELSE NULL END,
+				broken_reason = CASE WHEN playlist_id = :playlist_id THEN broken_reason ELSE NULL END
+		WHERE country_code = :country_code AND chart_type = :chart_type;`,
+
+	updChartSourceStatus: `
+		UPDATE chart_sources
+			SET broken_at = :broken_at, broken_reason = :broken_reason
+		WHERE country_code = :country_code AND chart_type = :chart_type AND playlist_id = :playlist_id;`,
+
+	upsTrackPopularity: `
+		INSERT INTO track_popularity (track_id, date, popularity)
+			VALUES(:track_id, :date, :popularity)
+		ON CONFLICT (track_id, date) DO UPDATE
+			SET popularity = :popularity
+		WHERE track_id = :track_id AND date = :date;`,
+
+	updAlbumDetails: `
+		UPDATE albums
+			SET label = :label
+		WHERE spotify_id = :spotify_id AND label IS NOT :label;`,
+
+	upsAlbumCopyright: `
+		INSERT INTO album_copyrights (album_id, type, text)
+			VALUES(:album_id, :type, :text)
+		ON CONFLICT (album_id, type) DO UPDATE
+			SET text = :text
+		WHERE album_id = :album_id AND type = :type AND text IS NOT :text;`,
+
+	delArtistGenres: `
+		DELETE FROM artist_genres
+			WHERE artist_id = :artist_id AND genre NOT IN (SELECT value FROM json_each(:genres));`,
+
+	insArtistGenre: `
+		INSERT INTO artist_genres (artist_id, genre)
+			VALUES(:artist_id, :genre)
+		ON CONFLICT (artist_id, genre) DO NOTHING;`,
+
+	upsArtistStats: `
+		INSERT INTO artist_stats (artist_id, date, popularity, followers)
+			VALUES(:artist_id, :date, :popularity, :followers)
+		ON CONFLICT (artist_id, date) DO UPDATE
+			SET popularity = :popularity, followers = :followers
+		WHERE artist_id = :artist_id AND date = :date;`,
+
+	updArtistEnrichedAt: `
+		UPDATE artists
+			SET enriched_at = :date
+		WHERE spotify_id = :artist_id;`,
+
+	upsAudioFeatures: `
+		INSERT INTO audio_features (track_id, tempo, key, mode, time_signature, energy, danceability, valence,
+				acousticness, instrumentalness, liveness, speechiness, loudness)
+			VALUES(:track_id, :tempo, :key, :mode, :time_signature, :energy, :danceability, :valence,
+				:acousticness, :instrumentalness, :liveness, :speechiness, :loudness)
+		ON CONFLICT (track_id) DO UPDATE
+			SET tempo = :tempo, key = :key, mode = :mode, time_signature = :time_signature, energy = :energy,
+				danceability = :danceability, valence = :valence, acousticness = :acousticness,
+				instrumentalness = :instrumentalness, liveness = :liveness, speechiness = :speechiness, loudness = :loudness
+		WHERE track_id = :track_id AND (tempo IS NOT :tempo OR key IS NOT :key OR mode IS NOT :mode
+			OR time_signature IS NOT :time_signature OR energy IS NOT :energy OR danceability IS NOT :danceability
+			OR valence IS NOT :valence OR acousticness IS NOT :acousticness OR instrumentalness IS NOT :instrumentalness
+			OR liveness IS NOT :liveness OR speechiness IS NOT :speechiness OR loudness IS NOT :loudness);`,
+
+	upsAudioFeaturesMiss: `
+		INSERT INTO audio_features_misses (track_id, date)
+			VALUES(:track_id, :date)
+		ON CONFLICT (track_id) DO UPDATE
+			SET date = :date
+		WHERE track_id = :track_id;`,
+
+	delChart: `
+		DELETE FROM chart_tracks
+			WHERE country_code = :country_code AND chart_type = :chart_type AND date = :date;`,
+
+	upsChartUpdate: `
+		INSERT INTO chart_updates (country_code, chart_type, date, updated_at)
+			VALUES (:country_code, :chart_type, :date, :updated_at)
+		ON CONFLICT (country_code, chart_type, date) DO UPDATE
+			SET updated_at = :updated_at;`,
+
+	upsIngest: `
+		INSERT INTO ingests (kind, updated_at)
+			VALUES (:kind, :updated_at)
+		ON CONFLICT (kind) DO UPDATE
+			SET updated_at = :updated_at;`,
 }
 
+const metadataIngest = "metadata"
+
 type Writer struct {
-	db               *sql.DB
-	tx               *sql.Tx
-	stmts            map[int]*sql.Stmt
-	done             chan bool
-	countryToSave    chan *model.Country
-	chartTrackToSave chan *model.ChartTrack
+	db                *sql.DB
+	tx                *sql.Tx
+	stmts             map[int]*sql.Stmt
+	done              chan bool
+	countryToSave     chan *model.Country
+	chartSourceToSave chan *model.ChartSource
+	statusToSave      chan *model.ChartSource
+	chartTrackToSave  chan *model.ChartTrack
+	chartEntryToSave  chan *model.ChartTrack
+	trackToSave       chan *model.Track
+	albumToSave       chan *model.Album
+	artistToSave      chan *model.ArtistSnapshot
+	featuresToSave    chan *model.AudioFeatures
+	missToSave        chan *model.AudioFeaturesMiss
+	chartToReplace    chan []*model.ChartTrack
+	updatedCharts     map[model.ChartKey]bool
+	metadataUpdated   bool
 }
 
 func NewWriter(db *sql.DB) *Writer {
 	var err error
 
 	writer := &Writer{
-		db:               db,
-		stmts:            make(map[int]*sql.Stmt),
-		done:             make(chan bool),
-		countryToSave:    make(chan *model.Country),
-		chartTrackToSave: make(chan *model.ChartTrack),
+		db:                db,
+		stmts:             make(map[int]*sql.Stmt),
+		done:              make(chan bool),
+		countryToSave:     make(chan *model.Country),
+		chartSourceToSave: make(chan *model.ChartSource),
+		statusToSave:      make(chan *model.ChartSource),
+		chartTrackToSave:  make(chan *model.ChartTrack),
+		chartEntryToSave:  make(chan *model.ChartTrack),
+		trackToSave:       make(chan *model.Track),
+		albumToSave:       make(chan *model.Album),
+		artistToSave:      make(chan *model.ArtistSnapshot),
+		featuresToSave:    make(chan *model.AudioFeatures),
+		missToSave:        make(chan *model.AudioFeaturesMiss),
+		chartToReplace:    make(chan []*model.ChartTrack),
+		updatedCharts:     make(map[model.ChartKey]bool),
 	}
 
 	if writer.tx, err = writer.db.BeginTx(context.Background(), nil); err != nil {
@@ -105,8 +247,26 @@ func (writer *Writer) writingRoutine() {
 		select {
 		case country := <-writer.countryToSave:
 			writer.upsertCountry(country)
+		case chartSource := <-writer.chartSourceToSave:
+			writer.upsertChartSource(chartSource)
+		case chartSource := <-writer.statusToSave:
+			writer.updateChartSourceStatus(chartSource)
 		case chartTrack := <-writer.chartTrackToSave:
 			writer.upsertChartTrack(chartTrack)
+		case chartTrack := <-writer.chartEntryToSave:
+			writer.upsertChartEntry(chartTrack, sql.NullBool{})
+		case track := <-writer.trackToSave:
+			writer.upsertTrack(track)
+		case album := <-writer.albumToSave:
+			writer.updateAlbumDetails(album)
+		case artistSnapshot := <-writer.artistToSave:
+			writer.updateArtistDetails(artistSnapshot)
+		case audioFeatures := <-writer.featuresToSave:
+			writer.upsertAudioFeatures(audioFeatures)
+		case miss := <-writer.missToSave:
+			writer.upsertAudioFeaturesMiss(miss)
+		case chartTracks := <-writer.chartToReplace:
+			writer.replaceChart(chartTracks)
 		case <-writer.done:
 			return
 		}
@@ -119,9 +279,22 @@ func (writer *Writer) Commit() {
 	writer.done <- true
 
 	close(writer.countryToSave)
+	close(writer.chartSourceToSave)
+	close(writer.statusToSave)
 	close(writer.chartTrackToSave)
+	close(writer.chartEntryToSave)
+	close(writer.trackToSave)
+	close(writer.albumToSave)
+	close(writer.artistToSave)
+	close(writer.featuresToSave)
+	close(writer.missToSave)
+	close(writer.chartToReplace)
 	close(writer.done)
 
+	committedAt := time.Now().Unix()
+
+	writer.recordUpdates(committedAt)
+
 	for index := range writerSqls {
 		if err = writer.stmts[index].Close(); err != nil {
 			panic(err)
@@ -133,21 +306,158 @@ func (writer *Writer) Commit() {
 	}
 
 	writer.tx = nil
+
+	commit := &Commit{
+		Charts:          make([]model.ChartKey, 0, len(writer.updatedCharts)),
+		MetadataUpdated: writer.metadataUpdated,
+		CommittedAt:     committedAt,
+	}
+
+	for chartKey := range writer.updatedCharts {
+		commit.Charts = append(commit.Charts, chartKey)
+	}
+
+	runCommitHooks(commit)
+}
+
+func (writer *Writer) recordUpdates(updatedAt int64) {
+	for chartKey := range writer.updatedCharts {
+		_, err := writer.stmts[upsChartUpdate].Exec(
+			sql.Named("country_code", chartKey.CountryCode),
+			sql.Named("chart_type", chartKey.ChartType),
+			sql.Named("date", chartKey.Date),
+			sql.Named("updated_at", updatedAt))
+
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if !writer.metadataUpdated {
+		return
+	}
+
+	_, err := writer.stmts[upsIngest].Exec(
+		sql.Named("kind", metadataIngest),
+		sql.Named("updated_at", updatedAt))
+
+	if err != nil {
+		panic(err)
+	}
 }
 
 func (writer *Writer) SaveCountry(country *model.Country) {
 	writer.countryToSave <- country
 }
 
-func (writer *Writer) SaveChartTrack(chartTrack *model.ChartTrack) {
+func (writer *Writer) SaveChartSource(chartSource *model.ChartSource) {
+	writer.chartSourceToSave <- chartSource
+}
+
+func (writer *Writer) SaveChartSourceStatus(chartSource *model.ChartSource) {
+	writer.statusToSave <- chartSource
+}
+
+func (writer *Writer) SaveChartTrack(chartTrack *model.ChartTrack) error {
+	if err := validateTrack(chartTrack.Track); err != nil {
+		return err
+	}
+
 	writer.chartTrackToSave <- chartTrack
+
+	return nil
+}
+
+func (writer *Writer) SaveChartEntry(chartTrack *model.ChartTrack) error {
+	if len(chartTrack.Track.SpotifyID) == 0 {
+		return fmt.Errorf("track '%s': %w", chartTrack.Track.Name, ErrEmptyID)
+	}
+
+	writer.chartEntryToSave <- chartTrack
+
+	return nil
+}
+
+func (writer *Writer) ReplaceChart(chartTracks []*model.ChartTrack) error {
+	for _, chartTrack := range chartTracks {
+		if len(chartTrack.Track.SpotifyID) == 0 {
+			return fmt.Errorf("track '%s': %w", chartTrack.Track.Name, ErrEmptyID)
+		}
+	}
+
+	if len(chartTracks) != 0 {
+		writer.chartToReplace <- chartTracks
+	}
+
+	return nil
+}
+
+func (writer *Writer) SaveTrack(track *model.Track) error {
+	if err := validateTrack(track); err != nil {
+		return err
+	}
+
+	writer.trackToSave <- track
+
+	return nil
+}
+
+func (writer *Writer) SaveAlbumDetails(album *model.Album) error {
+	if err := validateAlbum(album); err != nil {
+		return err
+	}
+
+	writer.albumToSave <- album
+
+	return nil
+}
+
+func (writer *Writer) SaveArtistDetails(artistSnapshot *model.ArtistSnapshot) error {
+	if err := validateArtist(artistSnapshot.Artist); err != nil {
+		return err
+	}
+
+	writer.artistToSave <- artistSnapshot
+
+	return nil
+}
+
+func (writer *Writer) SaveAudioFeatures(audioFeatures *model.AudioFeatures) {
+	writer.featuresToSave <- audioFeatures
+}
+
+func (writer *Writer) SaveAudioFeaturesMiss(miss *model.AudioFeaturesMiss) {
+	writer.missToSave <- miss
 }
 
 func (writer *Writer) upsertCountry(country *model.Country) {
 	_, err := writer.stmts[upsCountry].Exec(
 		sql.Named("code", country.Code),
-		sql.Named("name", country.Name),
-		sql.Named("top_playlist_id", newNullString(country.TopPlaylistID)))
+		sql.Named("name", country.Name))
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (writer *Writer) upsertChartSource(chartSource *model.ChartSource) {
+	_, err := writer.stmts[upsChartSource].Exec(
+		sql.Named("country_code", chartSource.Country.Code),
+		sql.Named("chart_type", chartSource.ChartType),
+		sql.Named("playlist_id", chartSource.PlaylistID))
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (writer *Writer) updateChartSourceStatus(chartSource *model.ChartSource) {
+	_, err := writer.stmts[updChartSourceStatus].Exec(
+		sql.Named("country_code", chartSource.Country.Code),
+		sql.Named("chart_type", chartSource.ChartType),
+		sql.Named("playlist_id", chartSource.PlaylistID),
+		sql.Named("broken_at", newNullInt64(chartSource.BrokenAt)),
+		sql.Named("broken_reason", newNullString(chartSource.BrokenReason)))
 
 	if err != nil {
 		panic(err)
@@ -157,12 +467,46 @@ func (writer *Writer) upsertCountry(country *model.Country) {
 func (writer *Writer) upsertChartTrack(chartTrack *model.ChartTrack) {
 	writer.upsertTrack(chartTrack.Track)
 
+	captureDate := chartTrack.CaptureDate
+	if captureDate == 0 {
+		captureDate = model.TimeToDatestamp(time.Now())
+	}
+
+	writer.upsertTrackPopularity(chartTrack.Track, captureDate)
+
+	writer.upsertChartEntry(chartTrack, newIsPlayable(chartTrack))
+}
+
+func (writer *Writer) replaceChart(chartTracks []*model.ChartTrack) {
+	_, err := writer.stmts[delChart].Exec(
+		sql.Named("country_code", chartTracks[0].Country.Code),
+		sql.Named("chart_type", chartTracks[0].ChartType),
+		sql.Named("date", chartTracks[0].Date))
+
+	if err != nil {
+		panic(err)
+	}
+
+	for _, chartTrack := range chartTracks {
+		writer.upsertChartEntry(chartTrack, sql.NullBool{})
+	}
+}
+
+func (writer *Writer) upsertChartEntry(chartTrack *model.ChartTrack, isPlayable sql.NullBool) {
+	writer.updatedCharts[model.ChartKey{
+		CountryCode: chartTrack.Country.Code,
+		ChartType:   chartTrack.ChartType,
+		Date:        chartTrack.Date,
+	}] = true
+
 	_, err := writer.stmts[upsChartTrack].Exec(
 		sql.Named("country_code", chartTrack.Country.Code),
 		sql.Named("track_id", chartTrack.Track.SpotifyID),
 		sql.Named("chart_type", chartTrack.ChartType),
 		sql.Named("date", chartTrack.Date),
-		sql.Named("position", chartTrack.Position))
+		sql.Named("position", chartTrack.Position),
+		sql.Named("is_playable", isPlayable),
+		sql.Named("streams", newNullInt64(chartTrack.Streams)))
 
 	if err != nil {
 		panic(err)
@@ -180,10 +524,17 @@ func (writer *Writer) upsertTrack(track *model.Track) {
 		writer.upsertImage(&image, track.Album.SpotifyID)
 	}
 
-	_, err := writer.stmts[upsTrack].Exec(
+	err := writer.execMetadata(upsTrack,
 		sql.Named("spotify_id", track.SpotifyID),
 		sql.Named("name", track.Name),
-		sql.Named("album_id", track.Album.SpotifyID))
+		sql.Named("album_id", track.Album.SpotifyID),
+		sql.Named("duration_ms", track.DurationMs),
+		sql.Named("explicit", track.Explicit),
+		sql.Named("disc_number", track.DiscNumber),
+		sql.Named("track_number", track.TrackNumber),
+		sql.Named("isrc", newNullString(track.ISRC)),
+		sql.Named("preview_url", newNullString(track.PreviewURL)),
+		sql.Named("linked_from_id", newNullString(track.LinkedFromID)))
 
 	if err != nil {
 		panic(err)
@@ -194,8 +545,19 @@ func (writer *Writer) upsertTrack(track *model.Track) {
 	}
 }
 
+func (writer *Writer) upsertTrackPopularity(track *model.Track, date int64) {
+	_, err := writer.stmts[upsTrackPopularity].Exec(
+		sql.Named("track_id", track.SpotifyID),
+		sql.Named("date", date),
+		sql.Named("popularity", track.Popularity))
+
+	if err != nil {
+		panic(err)
+	}
+}
+
 func (writer *Writer) upsertArtist(artist *model.Artist) {
-	_, err := writer.stmts[upsArtist].Exec(
+	err := writer.execMetadata(upsArtist,
 		sql.Named("spotify_id", artist.SpotifyID),
 		sql.Named("name", artist.Name))
 
@@ -204,10 +566,123 @@ func (writer *Writer) upsertArtist(artist *model.Artist) {
 	}
 }
 
+func (writer *Writer) updateArtistDetails(artistSnapshot *model.ArtistSnapshot) {
+	artist := artistSnapshot.Artist
+
+	writer.upsertArtist(artist)
+
+	genres, err := json.Marshal(artist.Genres)
+	if err != nil {
+		panic(err)
+	}
+
+	err = writer.execMetadata(delArtistGenres,
+		sql.Named("artist_id", artist.SpotifyID),
+		sql.Named("genres", string(genres)))
+
+	if err != nil {
+		panic(err)
+	}
+
+	for _, genre := range artist.Genres {
+		err := writer.execMetadata(insArtistGenre,
+			sql.Named("artist_id", artist.SpotifyID),
+			sql.Named("genre", genre))
+
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	_, err = writer.stmts[upsArtistStats].Exec(
+		sql.Named("artist_id", artist.SpotifyID),
+		sql.Named("date", artistSnapshot.Date),
+		sql.Named("popularity", artist.Popularity),
+		sql.Named("followers", artist.Followers))
+
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = writer.stmts[updArtistEnrichedAt].Exec(
+		sql.Named("artist_id", artist.SpotifyID),
+		sql.Named("date", artistSnapshot.Date))
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (writer *Writer) upsertAudioFeatures(audioFeatures *model.AudioFeatures) {
+	err := writer.execMetadata(upsAudioFeatures,
+		sql.Named("track_id", audioFeatures.TrackID),
+		sql.Named("tempo", audioFeatures.Tempo),
+		sql.Named("key", audioFeatures.Key),
+		sql.Named("mode", audioFeatures.Mode),
+		sql.Named("time_signature", audioFeatures.TimeSignature),
+		sql.Named("energy", audioFeatures.Energy),
+		sql.Named("danceability", audioFeatures.Danceability),
+		sql.Named("valence", audioFeatures.Valence),
+		sql.Named("acousticness", audioFeatures.Acousticness),
+		sql.Named("instrumentalness", audioFeatures.Instrumentalness),
+		sql.Named("liveness", audioFeatures.Liveness),
+		sql.Named("speechiness", audioFeatures.Speechiness),
+		sql.Named("loudness", audioFeatures.Loudness))
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (writer *Writer) upsertAudioFeaturesMiss(miss *model.AudioFeaturesMiss) {
+	_, err := writer.stmts[upsAudioFeaturesMiss].Exec(
+		sql.Named("track_id", miss.TrackID),
+		sql.Named("date", miss.Date))
+
+	if err != nil {
+		panic(err)
+	}
+}
+
 func (writer *Writer) upsertAlbum(album *model.Album) {
-	_, err := writer.stmts[upsAlbum].Exec(
+	err := writer.execMetadata(upsAlbum,
+		sql.Named("spotify_id", album.SpotifyID),
+		sql.Named("name", album.Name),
+		sql.Named("release_date", newNullString(album.ReleaseDate)),
+		sql.Named("release_date_precision", newNullString(album.ReleaseDatePrecision)),
+		sql.Named("album_type", newNullString(album.AlbumType)),
+		sql.Named("total_tracks", album.TotalTracks))
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (writer *Writer) updateAlbumDetails(album *model.Album) {
+	writer.upsertAlbum(album)
+
+	for _, image := range album.Images {
+		writer.upsertImage(&image, album.SpotifyID)
+	}
+
+	err := writer.execMetadata(updAlbumDetails,
 		sql.Named("spotify_id", album.SpotifyID),
-		sql.Named("name", album.Name))
+		sql.Named("label", album.Label))
+
+	if err != nil {
+		panic(err)
+	}
+
+	for _, copyright := range album.Copyrights {
+		writer.upsertAlbumCopyright(&copyright, album.SpotifyID)
+	}
+}
+
+func (writer *Writer) upsertAlbumCopyright(copyright *model.Copyright, albumID string) {
+	err := writer.execMetadata(upsAlbumCopyright,
+		sql.Named("album_id", albumID),
+		sql.Named("type", copyright.Type),
+		sql.Named("text", copyright.Text))
 
 	if err != nil {
 		panic(err)
@@ -215,7 +690,7 @@ func (writer *Writer) upsertAlbum(album *model.Album) {
 }
 
 func (writer *Writer) upsertImage(image *model.Image, albumID string) {
-	_, err := writer.stmts[upsImage].Exec(
+	err := writer.execMetadata(upsImage,
 		sql.Named("album_id", albumID),
 		sql.Named("width", image.Width),
 		sql.Named("url", image.URL))
@@ -226,7 +701,7 @@ func (writer *Writer) upsertImage(image *model.Image, albumID string) {
 }
 
 func (writer *Writer) upsertArtistTrack(artistID string, trackID string) {
-	_, err := writer.stmts[upsArtistTrack].Exec(
+	err := writer.execMetadata(upsArtistTrack,
 		sql.Named("artist_id", artistID),
 		sql.Named("track_id", trackID))
 
@@ -235,6 +710,20 @@ func (writer *Writer) upsertArtistTrack(artistID string, trackID string) {
 	}
 }
 
+func (writer *Writer) execMetadata(stmt int, args ...any) error {
+	result, err := writer.stmts[stmt].Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if affected != 0 {
+		writer.metadataUpdated = true
+	}
+
+	return err
+}
+
 func newNullString(s string) sql.NullString {
 	if len(s) == 0 {
 		return sql.NullString{}
@@ -245,3 +734,59 @@ func newNullString(s string) sql.NullString {
 		Valid:  true,
 	}
 }
+
+func newNullInt64(i int64) sql.NullInt64 {
+	if i == 0 {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{
+		Int64: i,
+		Valid: true,
+	}
+}
+
+func newIsPlayable(chartTrack *model.ChartTrack) sql.NullBool {
+	if len(chartTrack.Country.Market()) == 0 {
+		return sql.NullBool{}
+	}
+
+	return sql.NullBool{
+		Bool:  chartTrack.Track.IsPlayable,
+		Valid: true,
+	}
+}
+
+func validateTrack(track *model.Track) error {
+	if len(track.SpotifyID) == 0 {
+		return fmt.Errorf("track '%s': %w", track.Name, ErrEmptyID)
+	}
+
+	if err := validateAlbum(&track.Album); err != nil {
+		return fmt.Errorf("track '%s': %w", track.SpotifyID, err)
+	}
+
+	for _, artist := range track.Artists {
+		if err := validateArtist(&artist); err != nil {
+			return fmt.Errorf("track '%s': %w", track.SpotifyID, err)
+		}
+	}
+
+	return nil
+}
+
+func validateAlbum(album *model.Album) error {
+	if len(album.SpotifyID) == 0 {
+		return fmt.Errorf("album '%s': %w", album.Name, ErrEmptyID)
+	}
+
+	return nil
+}
+
+func validateArtist(artist *model.Artist) error {
+	if len(artist.SpotifyID) == 0 {
+		return fmt.Errorf("artist '%s': %w", artist.Name, ErrEmptyID)
+	}
+
+	return nil
+}
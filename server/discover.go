@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"spotify-charter/db"
+	"spotify-charter/discovery"
+	"spotify-charter/model"
+	"spotify-charter/spotify"
+	"strconv"
+	"strings"
+)
+
+const toplistsCategoryID = "toplists"
+
+func discoverChartSources(sqlDB *sql.DB, apiClient *spotify.APICLient, args []string) {
+	chartType := model.DailyTopTrack
+
+	if len(args) != 0 {
+		var ok bool
+
+		if chartType, ok = model.ParseChartType(args[0]); !ok {
+			log.Panicf("Unknown chart type '%s'\n", args[0])
+		}
+	}
+
+	if _, ok := discovery.SearchQuery(&model.Country{}, chartType); !ok {
+		log.Panicf("Chart type '%s' does not support discovery\n", chartType)
+	}
+
+	reader := db.NewReader(sqlDB)
+	defer reader.Close()
+
+	countries := reader.GetCountriesWithoutWorkingChartSource(chartType)
+
+	log.Printf("Discovering '%s' playlists for %d countries\n", chartType, len(countries))
+
+	stdin := bufio.NewScanner(os.Stdin)
+	accepted := make([]*model.ChartSource, 0)
+
+	for _, country := range countries {
+		candidates, err := findCandidates(apiClient, country, chartType)
+		if err != nil {
+			log.Printf("[%s] Discovery failed: %s\n", country.Code, err)
+			continue
+		}
+
+		if len(candidates) == 0 {
+			log.Printf("[%s] No candidate playlists found\n", country.Code)
+			continue
+		}
+
+		candidate := reviewCandidates(stdin, country, candidates)
+		if candidate == nil {
+			continue
+		}
+
+		accepted = append(accepted, &model.ChartSource{
+			Country:    country,
+			ChartType:  chartType,
+			PlaylistID: candidate.Playlist.SpotifyID,
+		})
+	}
+
+	writer := db.NewWriter(sqlDB)
+
+	for _, chartSource := range accepted {
+		log.Printf("Upserting chart source '%s' for country '%s' to the DB\n", chartSource.ChartType, chartSource.Country.Code)
+
+		writer.SaveChartSource(chartSource)
+	}
+
+	writer.Commit()
+
+	log.Printf("Successfully discovered %d of %d '%s' playlists\n", len(accepted), len(countries), chartType)
+}
+
+func rediscoverChartSource(apiClient *spotify.APICLient, chartSource *model.ChartSource) *model.ChartSource {
+	if _, ok := discovery.SearchQuery(chartSource.Country, chartSource.ChartType); !ok {
+		return nil
+	}
+
+	candidates, err := findCandidates(apiClient, chartSource.Country, chartSource.ChartType)
+	if err != nil {
+		log.Printf("[%s/%s] Rediscovery failed: %s\n", chartSource.Country.Code, chartSource.ChartType, err)
+		return nil
+	}
+
+	if len(candidates) == 0 || candidates[0].Score < discovery.AutoAcceptScore || candidates[0].Playlist.SpotifyID == chartSource.PlaylistID {
+		log.Printf("[%s/%s] No replacement playlist found\n", chartSource.Country.Code, chartSource.ChartType)
+		return nil
+	}
+
+	log.Printf("[%s/%s] Replacing playlist '%s' with '%s' (%s)\n", chartSource.Country.Code, chartSource.ChartType,
+		chartSource.PlaylistID, candidates[0].Playlist.SpotifyID, candidates[0].Playlist.Name)
+
+	return &model.ChartSource{
+		Country:    chartSource.Country,
+		ChartType:  chartSource.ChartType,
+		PlaylistID: candidates[0].Playlist.SpotifyID,
+	}
+}
+
+func findCandidates(apiClient *spotify.APICLient, country *model.Country, chartType model.ChartType) ([]*discovery.Candidate, error) {
+	query, _ := discovery.SearchQuery(country, chartType)
+
+	playlists, err := apiClient.SearchPlaylists(query, country.Market())
+	if err != nil {
+		return nil, err
+	}
+
+	categoryPlaylists, err := apiClient.GetCategoryPlaylists(toplistsCategoryID, country.Market())
+	if err != nil {
+		log.Printf("[%s] Browsing category '%s' failed: %s\n", country.Code, toplistsCategoryID, err)
+	} else {
+		playlists = append(playlists, categoryPlaylists...)
+	}
+
+	return discovery.Rank(country, chartType, playlists), nil
+}
+
+func reviewCandidates(stdin *bufio.Scanner, country *model.Country, candidates []*discovery.Candidate) *discovery.Candidate {
+	fmt.Printf("\n%s (%s):\n", country.Name, country.Code)
+
+	for index, candidate := range candidates {
+		fmt.Printf("  [%d] %s (%s) by '%s', score %d\n", index+1, candidate.Playlist.Name,
+			candidate.Playlist.SpotifyID, candidate.Playlist.OwnerID, candidate.Score)
+	}
+
+	fmt.Printf("Accept candidate [1-%d], or skip [n]: ", len(candidates))
+
+	if !stdin.Scan() {
+		return nil
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(stdin.Text()))
+	if err != nil || choice < 1 || choice > len(candidates) {
+		return nil
+	}
+
+	return candidates[choice-1]
+}
@@ -0,0 +1,100 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"spotify-charter/model"
+	"strings"
+)
+
+const (
+	spotifyOwnerID = "spotify"
+	minScore       = 4
+	maxCandidates  = 3
+
+	AutoAcceptScore = 9
+)
+
+var namePatterns = map[model.ChartType]string{
+	model.DailyTopTrack:   "Top 50 - %s",
+	model.WeeklyTopTrack:  "Top Songs - %s",
+	model.DailyViralTrack: "Viral 50 - %s",
+}
+
+type Candidate struct {
+	Playlist *model.Playlist
+	Score    int
+}
+
+func SearchQuery(country *model.Country, chartType model.ChartType) (string, bool) {
+	pattern, ok := namePatterns[chartType]
+	if !ok {
+		return "", false
+	}
+
+	return fmt.Sprintf(pattern, country.Name), true
+}
+
+func Rank(country *model.Country, chartType model.ChartType, playlists []*model.Playlist) []*Candidate {
+	expectedName, ok := SearchQuery(country, chartType)
+	if !ok {
+		return nil
+	}
+
+	prefix := strings.ToLower(strings.SplitN(namePatterns[chartType], " - ", 2)[0])
+
+	seen := make(map[string]bool)
+	candidates := make([]*Candidate, 0)
+
+	for _, playlist := range playlists {
+		if seen[playlist.SpotifyID] {
+			continue
+		}
+
+		seen[playlist.SpotifyID] = true
+
+		score := scorePlaylist(playlist, strings.ToLower(expectedName), prefix, strings.ToLower(country.Name))
+		if score < minScore {
+			continue
+		}
+
+		candidates = append(candidates, &Candidate{
+			Playlist: playlist,
+			Score:    score,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+
+	return candidates
+}
+
+func scorePlaylist(playlist *model.Playlist, expectedName string, prefix string, countryName string) int {
+	name := strings.ToLower(strings.TrimSpace(playlist.Name))
+
+	score := 0
+
+	if name == expectedName {
+		score += 6
+	} else {
+		if strings.Contains(name, prefix) {
+			score += 2
+		}
+
+		if strings.Contains(name, countryName) {
+			score += 2
+		}
+	}
+
+	if score != 0 && playlist.OwnerID == spotifyOwnerID {
+		score += 3
+	}
+
+	return score
+}
@@ -0,0 +1,75 @@
+package discovery
+
+import (
+	"spotify-charter/model"
+	"testing"
+)
+
+func TestRank(t *testing.T) {
+	slovakia := &model.Country{Code: "SK", Name: "Slovakia"}
+
+	playlists := []*model.Playlist{
+		{SpotifyID: "fan", Name: "Top 50 - Slovakia", OwnerID: "fan"},
+		{SpotifyID: "official", Name: "Top 50 - Slovakia", OwnerID: spotifyOwnerID},
+		{SpotifyID: "official", Name: "Top 50 - Slovakia", OwnerID: spotifyOwnerID},
+		{SpotifyID: "partial", Name: "Slovakia Top 50 Hits", OwnerID: spotifyOwnerID},
+		{SpotifyID: "viral", Name: "Viral 50 - Slovakia", OwnerID: spotifyOwnerID},
+		{SpotifyID: "other", Name: "Top 50 - Czechia", OwnerID: spotifyOwnerID},
+		{SpotifyID: "unrelated", Name: "Chill Hits", OwnerID: spotifyOwnerID},
+	}
+
+	tests := []struct {
+		chartType model.ChartType
+		want      []string
+		scores    []int
+	}{
+		{model.DailyTopTrack, []string{"official", "partial", "fan"}, []int{9, 7, 6}},
+		{model.DailyViralTrack, []string{"viral", "official", "partial"}, []int{9, 5, 5}},
+		{model.WeeklyTopTrack, []string{"official", "partial", "viral"}, []int{5, 5, 5}},
+		{model.WeeklyAggregate, nil, nil},
+	}
+
+	for _, test := range tests {
+		candidates := Rank(slovakia, test.chartType, playlists)
+
+		if len(candidates) != len(test.want) {
+			t.Errorf("%s: got %d candidates, want %v", test.chartType, len(candidates), test.want)
+			continue
+		}
+
+		for index, candidate := range candidates {
+			if candidate.Playlist.SpotifyID != test.want[index] || candidate.Score != test.scores[index] {
+				t.Errorf("%s: candidate %d is %s scoring %d, want %s scoring %d",
+					test.chartType, index, candidate.Playlist.SpotifyID, candidate.Score, test.want[index], test.scores[index])
+			}
+		}
+	}
+}
+
+func TestAutoAcceptScore(t *testing.T) {
+	tests := []struct {
+		country  *model.Country
+		playlist *model.Playlist
+		accepted bool
+	}{
+		{&model.Country{Code: "SK", Name: "Slovakia"}, &model.Playlist{Name: "Top 50 - Slovakia", OwnerID: spotifyOwnerID}, true},
+		{&model.Country{Code: "SK", Name: "Slovakia"}, &model.Playlist{Name: " TOP 50 - SLOVAKIA ", OwnerID: spotifyOwnerID}, true},
+		{&model.Country{Code: "SK", Name: "Slovakia"}, &model.Playlist{Name: "Top 50 - Slovakia", OwnerID: "fan"}, false},
+		{&model.Country{Code: "SK", Name: "Slovakia"}, &model.Playlist{Name: "Top 50 - Slovakia 2026", OwnerID: spotifyOwnerID}, false},
+		{&model.Country{Code: "NE", Name: "Niger"}, &model.Playlist{Name: "Top 50 - Nigeria", OwnerID: spotifyOwnerID}, false},
+		{&model.Country{Code: "CD", Name: "DR Congo"}, &model.Playlist{Name: "Top 50 - DR Congo", OwnerID: spotifyOwnerID}, true},
+		{&model.Country{Code: "CG", Name: "Republic of the Congo"}, &model.Playlist{Name: "Top 50 - DR Congo", OwnerID: spotifyOwnerID}, false},
+		{&model.Country{Code: "CD", Name: "DR Congo"}, &model.Playlist{Name: "Top 50 - Republic of the Congo", OwnerID: spotifyOwnerID}, false},
+	}
+
+	for _, test := range tests {
+		test.playlist.SpotifyID = "p1"
+
+		candidates := Rank(test.country, model.DailyTopTrack, []*model.Playlist{test.playlist})
+
+		accepted := len(candidates) != 0 && candidates[0].Score >= AutoAcceptScore
+		if accepted != test.accepted {
+			t.Errorf("%q for %s: auto-accepted %t, want %t", test.playlist.Name, test.country.Name, accepted, test.accepted)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"spotify-charter/spotify"
+	"sync"
+	"time"
+)
+
+func enrichTracks(ctx context.Context, sqlDB *sql.DB, apiClient *spotify.APICLient, reader *db.Reader) {
+	trackIDs := reader.GetUnresolvedTrackIDs()
+
+	log.Printf("Resolving %d tracks\n", len(trackIDs))
+
+	writer := db.NewWriter(sqlDB)
+
+	enrichEach(ctx, trackIDs, func(trackID string) {
+		track, err := apiClient.GetTrack(trackID)
+		if err != nil {
+			log.Printf("Fetching track '%s' failed: %s\n", trackID, err)
+			return
+		}
+
+		if err := writer.SaveTrack(track); err != nil {
+			log.Printf("Rejecting track '%s': %s\n", trackID, err)
+		}
+	})
+
+	writer.Commit()
+
+	log.Println("Successfully finished resolving tracks")
+}
+
+func enrichAlbums(ctx context.Context, sqlDB *sql.DB, apiClient *spotify.APICLient, reader *db.Reader) {
+	albumIDs := reader.GetAlbumIDsWithoutLabel()
+
+	log.Printf("Enriching %d albums\n", len(albumIDs))
+
+	writer := db.NewWriter(sqlDB)
+
+	enrichEach(ctx, albumIDs, func(albumID string) {
+		album, err := apiClient.GetAlbum(albumID)
+		if err != nil {
+			log.Printf("Fetching album '%s' failed: %s\n", albumID, err)
+			return
+		}
+
+		if err := writer.SaveAlbumDetails(album); err != nil {
+			log.Printf("Rejecting album '%s': %s\n", albumID, err)
+		}
+	})
+
+	writer.Commit()
+
+	log.Println("Successfully finished enriching albums")
+}
+
+func enrichArtists(ctx context.Context, sqlDB *sql.DB, apiClient *spotify.APICLient, reader *db.Reader, date int64) {
+	artistIDs := reader.GetArtistIDsToEnrich(date)
+
+	log.Printf("Enriching %d artists\n", len(artistIDs))
+
+	writer := db.NewWriter(sqlDB)
+
+	enrichEach(ctx, artistIDs, func(artistID string) {
+		artist, err := apiClient.GetArtist(artistID)
+		if err != nil {
+			log.Printf("Fetching artist '%s' failed: %s\n", artistID, err)
+			return
+		}
+
+		artistSnapshot := &model.ArtistSnapshot{
+			Artist: artist,
+			Date:   date,
+		}
+
+		if err := writer.SaveArtistDetails(artistSnapshot); err != nil {
+			log.Printf("Rejecting artist '%s': %s\n", artistID, err)
+		}
+	})
+
+	writer.Commit()
+
+	log.Println("Successfully finished enriching artists")
+}
+
+func enrichAudioFeatures(ctx context.Context, sqlDB *sql.DB, apiClient *spotify.APICLient, reader *db.Reader, date int64) {
+	missedBefore := model.TimeToDatestamp(time.Unix(date, 0).AddDate(0, 0, -audioFeaturesRetryDays))
+
+	trackIDs := reader.GetTrackIDsWithoutAudioFeatures(missedBefore)
+
+	log.Printf("Enriching %d tracks with audio features\n", len(trackIDs))
+
+	writer := db.NewWriter(sqlDB)
+
+	enrichEach(ctx, trackIDs, func(trackID string) {
+		audioFeatures, err := apiClient.GetTrackAudioFeatures(trackID)
+		if errors.Is(err, spotify.ErrNotFound) || spotify.IsUnavailable(err) {
+			writer.SaveAudioFeaturesMiss(&model.AudioFeaturesMiss{TrackID: trackID, Date: date})
+			return
+		}
+
+		if err != nil {
+			log.Printf("Fetching audio features for track '%s' failed: %s\n", trackID, err)
+			return
+		}
+
+		writer.SaveAudioFeatures(audioFeatures)
+	})
+
+	writer.Commit()
+
+	log.Println("Successfully finished enriching audio features")
+}
+
+const (
+	enrichWorkers          = 100
+	audioFeaturesRetryDays = 30
+)
+
+func enrichEach(ctx context.Context, ids []string, enrich func(id string)) {
+	queue := make(chan string)
+
+	wg := new(sync.WaitGroup)
+
+	for range min(enrichWorkers, len(ids)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for id := range queue {
+				enrich(id)
+			}
+		}()
+	}
+
+	defer wg.Wait()
+	defer close(queue)
+
+	for _, id := range ids {
+		select {
+		case queue <- id:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
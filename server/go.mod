@@ -2,4 +2,7 @@ module spotify-charter
 
 go 1.22.0
 
-require github.com/mattn/go-sqlite3 v1.14.22
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/mattn/go-sqlite3 v1.14.22
+)
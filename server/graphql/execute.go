@@ -0,0 +1,467 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+type Error struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+type Schema struct {
+	Query     *Object
+	MaxDepth  int
+	MaxFields int
+}
+
+type Object struct {
+	Name   string
+	Fields map[string]*FieldDef
+}
+
+type FieldDef struct {
+	Type    *Object
+	List    bool
+	Args    map[string]any
+	Resolve func(ctx context.Context, source any, args map[string]any) (any, error)
+}
+
+type Thunk func() (any, error)
+
+type node struct {
+	source any
+	result *orderedMap
+	path   []any
+}
+
+type executor struct {
+	ctx       context.Context
+	schema    *Schema
+	doc       *Document
+	variables map[string]any
+	errors    []*Error
+	fields    int
+}
+
+func (schema *Schema) Execute(ctx context.Context, doc *Document, operationName string, variables map[string]any) *Response {
+	operation, err := selectOperation(doc, operationName)
+	if err != nil {
+		return &Response{Errors: []*Error{err}}
+	}
+
+	if operation.Type != "query" {
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("unsupported operation type '%s'", operation.Type)}}}
+	}
+
+	e := &executor{
+		ctx:       ctx,
+		schema:    schema,
+		doc:       doc,
+		variables: make(map[string]any),
+	}
+
+	for name, defaultValue := range operation.Variables {
+		if value, ok := variables[name]; ok {
+			e.variables[name] = value
+		} else {
+			e.variables[name] = e.value(defaultValue)
+		}
+	}
+
+	depth := e.validate(schema.Query, operation.Selections, make(map[string]bool))
+	if schema.MaxDepth != 0 && depth > schema.MaxDepth {
+		e.fail(nil, "query depth %d exceeds the maximum of %d", depth, schema.MaxDepth)
+	}
+
+	if e.tooManyFields() {
+		e.fail(nil, "query selects more than the maximum of %d fields", schema.MaxFields)
+	}
+
+	if len(e.errors) != 0 {
+		return &Response{Errors: e.errors}
+	}
+
+	result := newOrderedMap()
+
+	e.executeLevel(schema.Query, []*node{{result: result}}, operation.Selections)
+
+	return &Response{Data: result, Errors: e.errors}
+}
+
+func selectOperation(doc *Document, operationName string) (*Operation, *Error) {
+	if len(operationName) == 0 {
+		if len(doc.Operations) != 1 {
+			return nil, &Error{Message: "operation name is required for documents with multiple operations"}
+		}
+
+		return doc.Operations[0], nil
+	}
+
+	for _, operation := range doc.Operations {
+		if operation.Name == operationName {
+			return operation, nil
+		}
+	}
+
+	return nil, &Error{Message: fmt.Sprintf("unknown operation '%s'", operationName)}
+}
+
+func (e *executor) validate(object *Object, selections []Selection, fragments map[string]bool) int {
+	depth := 0
+
+	for _, selection := range selections {
+		if e.tooManyFields() {
+			break
+		}
+
+		switch selection := selection.(type) {
+		case *Field:
+			depth = max(depth, e.validateField(object, selection, fragments))
+		case *InlineFragment:
+			depth = max(depth, e.validateFragment(object, selection.TypeCondition, selection.Selections, fragments))
+		case *FragmentSpread:
+			fragment, ok := e.doc.Fragments[selection.Name]
+			if !ok {
+				e.fail(nil, "unknown fragment '%s'", selection.Name)
+				continue
+			}
+
+			if fragments[selection.Name] {
+				e.fail(nil, "fragment '%s' spreads itself", selection.Name)
+				continue
+			}
+
+			fragments[selection.Name] = true
+			depth = max(depth, e.validateFragment(object, fragment.TypeCondition, fragment.Selections, fragments))
+			delete(fragments, selection.Name)
+		}
+	}
+
+	return depth
+}
+
+func (e *executor) tooManyFields() bool {
+	return e.schema.MaxFields != 0 && e.fields > e.schema.MaxFields
+}
+
+func (e *executor) validateFragment(object *Object, typeCondition string, selections []Selection, fragments map[string]bool) int {
+	if len(typeCondition) != 0 && typeCondition != object.Name {
+		e.fail(nil, "fragment on '%s' cannot be spread on '%s'", typeCondition, object.Name)
+		return 0
+	}
+
+	return e.validate(object, selections, fragments)
+}
+
+func (e *executor) validateField(object *Object, field *Field, fragments map[string]bool) int {
+	e.fields++
+
+	if field.Name == "__typename" {
+		return 1
+	}
+
+	def, ok := object.Fields[field.Name]
+	if !ok {
+		e.fail(nil, "unknown field '%s' on '%s'", field.Name, object.Name)
+		return 0
+	}
+
+	for name := range field.Arguments {
+		if _, ok := def.Args[name]; !ok {
+			e.fail(nil, "unknown argument '%s' on '%s.%s'", name, object.Name, field.Name)
+		}
+	}
+
+	if def.Type == nil {
+		if len(field.Selections) != 0 {
+			e.fail(nil, "field '%s.%s' is a scalar and cannot have a selection", object.Name, field.Name)
+		}
+
+		return 1
+	}
+
+	if len(field.Selections) == 0 {
+		e.fail(nil, "field '%s.%s' of type '%s' requires a selection", object.Name, field.Name, def.Type.Name)
+		return 1
+	}
+
+	return 1 + e.validate(def.Type, field.Selections, fragments)
+}
+
+type pendingField struct {
+	node  *node
+	field *Field
+	def   *FieldDef
+	value any
+	err   error
+}
+
+func (e *executor) executeLevel(object *Object, nodes []*node, selections []Selection) {
+	fields := e.collectFields(object, selections, newOrderedFields())
+	pending := make([]*pendingField, 0, len(fields.keys)*len(nodes))
+
+	for _, key := range fields.keys {
+		field := fields.fields[key]
+
+		if field.Name == "__typename" {
+			for _, node := range nodes {
+				node.result.set(key, object.Name)
+			}
+
+			continue
+		}
+
+		def := object.Fields[field.Name]
+		args := e.arguments(def, field)
+
+		for _, node := range nodes {
+			value, err := def.Resolve(e.ctx, node.source, args)
+			pending = append(pending, &pendingField{node: node, field: field, def: def, value: value, err: err})
+		}
+	}
+
+	for _, pending := range pending {
+		if thunk, ok := pending.value.(Thunk); ok && pending.err == nil {
+			pending.value, pending.err = thunk()
+		}
+	}
+
+	children := make(map[string][]*node)
+
+	for _, pending := range pending {
+		key := pending.field.ResponseKey()
+		path := append(append([]any{}, pending.node.path...), key)
+
+		if pending.err != nil {
+			pending.node.result.set(key, nil)
+			e.fail(path, "%s", pending.err)
+
+			continue
+		}
+
+		if isNull(pending.value) {
+			pending.node.result.set(key, nil)
+			continue
+		}
+
+		if pending.def.Type == nil {
+			pending.node.result.set(key, pending.value)
+			continue
+		}
+
+		if !pending.def.List {
+			child := &node{source: pending.value, result: newOrderedMap(), path: path}
+
+			pending.node.result.set(key, child.result)
+			children[key] = append(children[key], child)
+
+			continue
+		}
+
+		items := reflect.ValueOf(pending.value)
+		list := make([]any, items.Len())
+
+		for index := range list {
+			item := items.Index(index).Interface()
+			if isNull(item) {
+				continue
+			}
+
+			child := &node{source: item, result: newOrderedMap(), path: append(append([]any{}, path...), index)}
+
+			list[index] = child.result
+			children[key] = append(children[key], child)
+		}
+
+		pending.node.result.set(key, list)
+	}
+
+	for _, key := range fields.keys {
+		field := fields.fields[key]
+
+		if len(children[key]) != 0 {
+			e.executeLevel(object.Fields[field.Name].Type, children[key], field.Selections)
+		}
+	}
+}
+
+func (e *executor) collectFields(object *Object, selections []Selection, fields *orderedFields) *orderedFields {
+	for _, selection := range selections {
+		if !e.included(selection.directives()) {
+			continue
+		}
+
+		switch selection := selection.(type) {
+		case *Field:
+			fields.add(selection)
+		case *InlineFragment:
+			e.collectFields(object, selection.Selections, fields)
+		case *FragmentSpread:
+			e.collectFields(object, e.doc.Fragments[selection.Name].Selections, fields)
+		}
+	}
+
+	return fields
+}
+
+func (e *executor) included(directives []*Directive) bool {
+	for _, directive := range directives {
+		condition, _ := e.value(directive.Arguments["if"]).(bool)
+
+		if directive.Name == "skip" && condition {
+			return false
+		}
+
+		if directive.Name == "include" && !condition {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *executor) arguments(def *FieldDef, field *Field) map[string]any {
+	args := make(map[string]any, len(def.Args))
+
+	for name, defaultValue := range def.Args {
+		args[name] = defaultValue
+
+		if value, ok := field.Arguments[name]; ok {
+			if value = e.value(value); value != nil {
+				args[name] = value
+			}
+		}
+	}
+
+	return args
+}
+
+func (e *executor) value(value Value) any {
+	switch value := value.(type) {
+	case Variable:
+		return e.variables[string(value)]
+	case EnumValue:
+		return string(value)
+	case []Value:
+		list := make([]any, len(value))
+		for index, item := range value {
+			list[index] = e.value(item)
+		}
+
+		return list
+	case map[string]Value:
+		object := make(map[string]any, len(value))
+		for name, item := range value {
+			object[name] = e.value(item)
+		}
+
+		return object
+	default:
+		return value
+	}
+}
+
+func (e *executor) fail(path []any, format string, args ...any) {
+	e.errors = append(e.errors, &Error{Message: fmt.Sprintf(format, args...), Path: path})
+}
+
+func isNull(value any) bool {
+	if value == nil {
+		return true
+	}
+
+	switch reflected := reflect.ValueOf(value); reflected.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return reflected.IsNil()
+	default:
+		return false
+	}
+}
+
+type orderedFields struct {
+	keys   []string
+	fields map[string]*Field
+}
+
+func newOrderedFields() *orderedFields {
+	return &orderedFields{fields: make(map[string]*Field)}
+}
+
+func (fields *orderedFields) add(field *Field) {
+	key := field.ResponseKey()
+
+	existing, ok := fields.fields[key]
+	if !ok {
+		fields.keys = append(fields.keys, key)
+		fields.fields[key] = field
+
+		return
+	}
+
+	merged := *existing
+	merged.Selections = append(append([]Selection{}, existing.Selections...), field.Selections...)
+
+	fields.fields[key] = &merged
+}
+
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: make(map[string]any)}
+}
+
+func (m *orderedMap) set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for index, key := range m.keys {
+		if index != 0 {
+			buf.WriteByte(',')
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		encodedValue, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(encodedValue)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,295 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type testUser struct {
+	ID      string
+	Name    string
+	Friends []string
+}
+
+var testUsers = map[string]*testUser{
+	"u1": {ID: "u1", Name: "Ann", Friends: []string{"u2", "u3"}},
+	"u2": {ID: "u2", Name: "Bob", Friends: []string{"u1"}},
+	"u3": {ID: "u3", Name: "Cid"},
+}
+
+func newTestSchema(maxDepth int, maxFields int) *Schema {
+	user := &Object{Name: "User"}
+	query := &Object{Name: "Query"}
+
+	user.Fields = map[string]*FieldDef{
+		"id": {Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return source.(*testUser).ID, nil
+		}},
+		"name": {Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return source.(*testUser).Name, nil
+		}},
+		"friends": {Type: user, List: true, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			friends := make([]*testUser, 0)
+			for _, id := range source.(*testUser).Friends {
+				friends = append(friends, testUsers[id])
+			}
+
+			return friends, nil
+		}},
+	}
+
+	query.Fields = map[string]*FieldDef{
+		"user": {Type: user, Args: map[string]any{"id": nil}, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			id, _ := args["id"].(string)
+
+			return testUsers[id], nil
+		}},
+		"greeting": {Args: map[string]any{"name": "world"}, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return "hello " + args["name"].(string), nil
+		}},
+		"broken": {Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return nil, errors.New("resolver failed")
+		}},
+	}
+
+	return &Schema{Query: query, MaxDepth: maxDepth, MaxFields: maxFields}
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]any
+		want          string
+	}{
+		{
+			"aliases and arguments",
+			`{ ann: user(id: "u1") { name } bob: user(id: "u2") { name } greeting }`,
+			"", nil,
+			`{"data":{"ann":{"name":"Ann"},"bob":{"name":"Bob"},"greeting":"hello world"}}`,
+		},
+		{
+			"lists and typename",
+			`{ user(id: "u1") { __typename friends { id name } } }`,
+			"", nil,
+			`{"data":{"user":{"__typename":"User","friends":[{"id":"u2","name":"Bob"},{"id":"u3","name":"Cid"}]}}}`,
+		},
+		{
+			"merged selections",
+			`{ user(id: "u1") { id } user(id: "u1") { name } }`,
+			"", nil,
+			`{"data":{"user":{"id":"u1","name":"Ann"}}}`,
+		},
+		{
+			"null object",
+			`{ user(id: "u9") { id } }`,
+			"", nil,
+			`{"data":{"user":null}}`,
+		},
+		{
+			"named and inline fragments",
+			`{ user(id: "u2") { ...Names ... on User { friends { ...Names } } } } fragment Names on User { id name }`,
+			"", nil,
+			`{"data":{"user":{"id":"u2","name":"Bob","friends":[{"id":"u1","name":"Ann"}]}}}`,
+		},
+		{
+			"variables",
+			`query ($id: ID, $name: String = "you") { user(id: $id) { name } greeting(name: $name) }`,
+			"", map[string]any{"id": "u3"},
+			`{"data":{"user":{"name":"Cid"},"greeting":"hello you"}}`,
+		},
+		{
+			"null variable keeps the argument default",
+			`query ($name: String) { greeting(name: $name) }`,
+			"", map[string]any{"name": nil},
+			`{"data":{"greeting":"hello world"}}`,
+		},
+		{
+			"skip and include",
+			`query ($yes: Boolean = true) { greeting @skip(if: $yes) user(id: "u1") @include(if: $yes) { id @include(if: false) name ... @skip(if: true) { friends { id } } } }`,
+			"", nil,
+			`{"data":{"user":{"name":"Ann"}}}`,
+		},
+		{
+			"operation name",
+			`query A { greeting } query B { user(id: "u1") { id } }`,
+			"B", nil,
+			`{"data":{"user":{"id":"u1"}}}`,
+		},
+		{
+			"resolver error",
+			`{ greeting broken }`,
+			"", nil,
+			`{"data":{"greeting":"hello world","broken":null},"errors":[{"message":"resolver failed","path":["broken"]}]}`,
+		},
+		{
+			"missing operation name",
+			`query A { greeting } query B { greeting }`,
+			"", nil,
+			`{"errors":[{"message":"operation name is required for documents with multiple operations"}]}`,
+		},
+		{
+			"unknown operation",
+			`query A { greeting }`,
+			"B", nil,
+			`{"errors":[{"message":"unknown operation 'B'"}]}`,
+		},
+		{
+			"mutation",
+			`mutation { greeting }`,
+			"", nil,
+			`{"errors":[{"message":"unsupported operation type 'mutation'"}]}`,
+		},
+		{
+			"unknown field and argument",
+			`{ users { id } greeting(to: "x") }`,
+			"", nil,
+			`{"errors":[{"message":"unknown field 'users' on 'Query'"},{"message":"unknown argument 'to' on 'Query.greeting'"}]}`,
+		},
+		{
+			"scalar with selection",
+			`{ greeting { id } }`,
+			"", nil,
+			`{"errors":[{"message":"field 'Query.greeting' is a scalar and cannot have a selection"}]}`,
+		},
+		{
+			"object without selection",
+			`{ user(id: "u1") }`,
+			"", nil,
+			`{"errors":[{"message":"field 'Query.user' of type 'User' requires a selection"}]}`,
+		},
+		{
+			"unknown fragment",
+			`{ ...Missing }`,
+			"", nil,
+			`{"errors":[{"message":"unknown fragment 'Missing'"}]}`,
+		},
+		{
+			"fragment spreading itself",
+			`{ user(id: "u1") { ...A } } fragment A on User { friends { ...B } } fragment B on User { ...A }`,
+			"", nil,
+			`{"errors":[{"message":"fragment 'A' spreads itself"}]}`,
+		},
+		{
+			"fragment on another type",
+			`{ ...F } fragment F on User { id }`,
+			"", nil,
+			`{"errors":[{"message":"fragment on 'User' cannot be spread on 'Query'"}]}`,
+		},
+		{
+			"maximum depth",
+			`{ user(id: "u1") { friends { friends { id } } } }`,
+			"", nil,
+			`{"data":{"user":{"friends":[{"friends":[{"id":"u1"}]},{"friends":[]}]}}}`,
+		},
+		{
+			"depth exceeded",
+			`{ user(id: "u1") { friends { friends { friends { id } } } } }`,
+			"", nil,
+			`{"errors":[{"message":"query depth 5 exceeds the maximum of 4"}]}`,
+		},
+		{
+			"depth exceeded through fragments",
+			`{ user(id: "u1") { ...A } } fragment A on User { friends { ...B } } fragment B on User { friends { friends { id } } }`,
+			"", nil,
+			`{"errors":[{"message":"query depth 5 exceeds the maximum of 4"}]}`,
+		},
+		{
+			"maximum fields",
+			`{ a: greeting b: greeting c: greeting d: greeting e: greeting f: greeting }`,
+			"", nil,
+			`{"data":{"a":"hello world","b":"hello world","c":"hello world","d":"hello world","e":"hello world","f":"hello world"}}`,
+		},
+		{
+			"aliased fields exceeded",
+			`{ a: greeting b: greeting c: greeting d: greeting e: greeting f: greeting g: greeting }`,
+			"", nil,
+			`{"errors":[{"message":"query selects more than the maximum of 6 fields"}]}`,
+		},
+		{
+			"fragment fields exceeded",
+			`{ user(id: "u1") { ...F ...F } } fragment F on User { id name friends { id } }`,
+			"", nil,
+			`{"errors":[{"message":"query selects more than the maximum of 6 fields"}]}`,
+		},
+	}
+
+	schema := newTestSchema(4, 6)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := Parse(test.query, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response, err := json.Marshal(schema.Execute(context.Background(), doc, test.operationName, test.variables))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(response) != test.want {
+				t.Errorf("Execute returned %s, want %s", response, test.want)
+			}
+		})
+	}
+}
+
+func TestExecuteBatchesAndCachesLoads(t *testing.T) {
+	batches := make([][]string, 0)
+
+	users := NewLoader(func(ids []string) (map[string]*testUser, error) {
+		batches = append(batches, ids)
+
+		loaded := make(map[string]*testUser)
+		for _, id := range ids {
+			loaded[id] = testUsers[id]
+		}
+
+		return loaded, nil
+	})
+
+	user := &Object{Name: "User"}
+	user.Fields = map[string]*FieldDef{
+		"name": {Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return source.(*testUser).Name, nil
+		}},
+		"best": {Type: user, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			friends := source.(*testUser).Friends
+			if len(friends) == 0 {
+				return nil, nil
+			}
+
+			return users.Load(friends[0]), nil
+		}},
+	}
+
+	query := &Object{Name: "Query", Fields: map[string]*FieldDef{
+		"user": {Type: user, Args: map[string]any{"id": nil}, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return users.Load(args["id"].(string)), nil
+		}},
+	}}
+
+	doc, err := Parse(`{ a: user(id: "u1") { name best { name best { name } } } b: user(id: "u2") { best { name } } c: user(id: "u3") { best { name } } }`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := json.Marshal((&Schema{Query: query}).Execute(context.Background(), doc, "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"data":{"a":{"name":"Ann","best":{"name":"Bob","best":{"name":"Ann"}}},"b":{"best":{"name":"Ann"}},"c":{"best":null}}}`
+	if string(response) != want {
+		t.Errorf("Execute returned %s, want %s", response, want)
+	}
+
+	if want := "[[u1 u2 u3]]"; fmt.Sprint(batches) != want {
+		t.Errorf("fetched %v, want %s", batches, want)
+	}
+}
@@ -0,0 +1,54 @@
+package graphql
+
+type Loader[K comparable, V any] struct {
+	fetch   func(keys []K) (map[K]V, error)
+	queue   []K
+	queued  map[K]bool
+	results map[K]V
+	errs    map[K]error
+}
+
+func NewLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:   fetch,
+		queued:  make(map[K]bool),
+		results: make(map[K]V),
+		errs:    make(map[K]error),
+	}
+}
+
+func (loader *Loader[K, V]) Load(key K) Thunk {
+	_, loaded := loader.results[key]
+
+	if !loaded && !loader.queued[key] {
+		loader.queue = append(loader.queue, key)
+		loader.queued[key] = true
+	}
+
+	return func() (any, error) {
+		loader.dispatch()
+
+		return loader.results[key], loader.errs[key]
+	}
+}
+
+func (loader *Loader[K, V]) dispatch() {
+	if len(loader.queue) == 0 {
+		return
+	}
+
+	keys := loader.queue
+
+	loader.queue = nil
+	loader.queued = make(map[K]bool)
+
+	results, err := loader.fetch(keys)
+
+	for _, key := range keys {
+		if err != nil {
+			loader.errs[key] = err
+		}
+
+		loader.results[key] = results[key]
+	}
+}
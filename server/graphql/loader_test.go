@@ -0,0 +1,75 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestLoaderBatches(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+
+	tests := []struct {
+		name    string
+		rounds  [][]string
+		missing []string
+		err     error
+		batches [][]string
+	}{
+		{"single key", [][]string{{"a"}}, nil, nil, [][]string{{"a"}}},
+		{"keys share a batch", [][]string{{"a", "b", "c"}}, nil, nil, [][]string{{"a", "b", "c"}}},
+		{"duplicate keys", [][]string{{"a", "b", "a"}}, nil, nil, [][]string{{"a", "b"}}},
+		{"loaded keys are cached", [][]string{{"a", "b"}, {"b", "c"}, {"a"}}, nil, nil, [][]string{{"a", "b"}, {"c"}}},
+		{"missing keys", [][]string{{"a", "b"}}, []string{"b"}, nil, [][]string{{"a", "b"}}},
+		{"failed fetch", [][]string{{"a", "b"}, {"a"}}, nil, errFetch, [][]string{{"a", "b"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			batches := make([][]string, 0)
+
+			loader := NewLoader(func(keys []string) (map[string]string, error) {
+				batches = append(batches, keys)
+
+				if test.err != nil {
+					return nil, test.err
+				}
+
+				values := make(map[string]string)
+				for _, key := range keys {
+					if !slices.Contains(test.missing, key) {
+						values[key] = "value " + key
+					}
+				}
+
+				return values, nil
+			})
+
+			for _, round := range test.rounds {
+				thunks := make([]Thunk, len(round))
+				for index, key := range round {
+					thunks[index] = loader.Load(key)
+				}
+
+				for index, thunk := range thunks {
+					key := round[index]
+
+					want := "value " + key
+					if test.err != nil || slices.Contains(test.missing, key) {
+						want = ""
+					}
+
+					value, err := thunk()
+					if value != want || !errors.Is(err, test.err) {
+						t.Errorf("Load(%q) returned %q, %v, want %q, %v", key, value, err, want, test.err)
+					}
+				}
+			}
+
+			if fmt.Sprint(batches) != fmt.Sprint(test.batches) {
+				t.Errorf("fetched %v, want %v", batches, test.batches)
+			}
+		})
+	}
+}
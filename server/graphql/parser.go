@@ -0,0 +1,584 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+type Operation struct {
+	Type       string
+	Name       string
+	Variables  map[string]Value
+	Selections []Selection
+}
+
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+}
+
+type Selection interface {
+	directives() []*Directive
+}
+
+type Field struct {
+	Alias      string
+	Name       string
+	Arguments  map[string]Value
+	Directives []*Directive
+	Selections []Selection
+}
+
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+}
+
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	Selections    []Selection
+}
+
+type Directive struct {
+	Name      string
+	Arguments map[string]Value
+}
+
+type Value interface{}
+
+type Variable string
+
+type EnumValue string
+
+func (field *Field) directives() []*Directive { return field.Directives }
+
+func (spread *FragmentSpread) directives() []*Directive { return spread.Directives }
+
+func (fragment *InlineFragment) directives() []*Directive { return fragment.Directives }
+
+func (field *Field) ResponseKey() string {
+	if len(field.Alias) != 0 {
+		return field.Alias
+	}
+
+	return field.Name
+}
+
+const (
+	tokenEOF = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  int
+	value string
+	pos   int
+}
+
+type parser struct {
+	source   string
+	pos      int
+	token    token
+	depth    int
+	maxDepth int
+}
+
+func Parse(source string, maxDepth int) (doc *Document, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			syntaxErr, ok := rec.(*Error)
+			if !ok {
+				panic(rec)
+			}
+
+			doc, err = nil, syntaxErr
+		}
+	}()
+
+	p := &parser{source: source, maxDepth: maxDepth}
+	p.next()
+
+	doc = &Document{Fragments: make(map[string]*Fragment)}
+
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunctuator, "{"):
+			doc.Operations = append(doc.Operations, &Operation{Type: "query", Selections: p.parseSelectionSet()})
+		case p.peek(tokenName, "fragment"):
+			fragment := p.parseFragment()
+			if _, ok := doc.Fragments[fragment.Name]; ok {
+				p.fail("duplicate fragment '%s'", fragment.Name)
+			}
+
+			doc.Fragments[fragment.Name] = fragment
+		case p.token.kind == tokenName:
+			doc.Operations = append(doc.Operations, p.parseOperation())
+		default:
+			p.fail("unexpected '%s'", p.token.value)
+		}
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, &Error{Message: "document does not contain an operation"}
+	}
+
+	return doc, nil
+}
+
+func (p *parser) parseOperation() *Operation {
+	operation := &Operation{Type: p.expect(tokenName, "").value}
+
+	if p.token.kind == tokenName {
+		operation.Name = p.expect(tokenName, "").value
+	}
+
+	if p.skip(tokenPunctuator, "(") {
+		operation.Variables = make(map[string]Value)
+
+		for !p.skip(tokenPunctuator, ")") {
+			p.expect(tokenPunctuator, "$")
+			name := p.expect(tokenName, "").value
+			p.expect(tokenPunctuator, ":")
+			p.parseType()
+
+			var defaultValue Value
+			if p.skip(tokenPunctuator, "=") {
+				defaultValue = p.parseValue(true)
+			}
+
+			operation.Variables[name] = defaultValue
+		}
+	}
+
+	p.parseDirectives()
+
+	operation.Selections = p.parseSelectionSet()
+
+	return operation
+}
+
+func (p *parser) parseFragment() *Fragment {
+	p.expect(tokenName, "fragment")
+
+	fragment := &Fragment{Name: p.expect(tokenName, "").value}
+	if fragment.Name == "on" {
+		p.fail("fragment cannot be named 'on'")
+	}
+
+	p.expect(tokenName, "on")
+	fragment.TypeCondition = p.expect(tokenName, "").value
+
+	p.parseDirectives()
+
+	fragment.Selections = p.parseSelectionSet()
+
+	return fragment
+}
+
+func (p *parser) parseType() {
+	if p.skip(tokenPunctuator, "[") {
+		p.enter()
+		p.parseType()
+		p.leave()
+		p.expect(tokenPunctuator, "]")
+	} else {
+		p.expect(tokenName, "")
+	}
+
+	p.skip(tokenPunctuator, "!")
+}
+
+func (p *parser) parseSelectionSet() []Selection {
+	p.expect(tokenPunctuator, "{")
+	p.enter()
+
+	selections := make([]Selection, 0)
+
+	for !p.skip(tokenPunctuator, "}") {
+		selections = append(selections, p.parseSelection())
+	}
+
+	p.leave()
+
+	if len(selections) == 0 {
+		p.fail("empty selection set")
+	}
+
+	return selections
+}
+
+func (p *parser) parseSelection() Selection {
+	if p.skip(tokenPunctuator, "...") {
+		if p.token.kind == tokenName && p.token.value != "on" {
+			return &FragmentSpread{Name: p.expect(tokenName, "").value, Directives: p.parseDirectives()}
+		}
+
+		fragment := &InlineFragment{}
+
+		if p.skip(tokenName, "on") {
+			fragment.TypeCondition = p.expect(tokenName, "").value
+		}
+
+		fragment.Directives = p.parseDirectives()
+		fragment.Selections = p.parseSelectionSet()
+
+		return fragment
+	}
+
+	field := &Field{Name: p.expect(tokenName, "").value}
+
+	if p.skip(tokenPunctuator, ":") {
+		field.Alias = field.Name
+		field.Name = p.expect(tokenName, "").value
+	}
+
+	field.Arguments = p.parseArguments(false)
+	field.Directives = p.parseDirectives()
+
+	if p.peek(tokenPunctuator, "{") {
+		field.Selections = p.parseSelectionSet()
+	}
+
+	return field
+}
+
+func (p *parser) parseArguments(constant bool) map[string]Value {
+	arguments := make(map[string]Value)
+
+	if !p.skip(tokenPunctuator, "(") {
+		return arguments
+	}
+
+	depth := p.depth
+	p.depth = 0
+
+	for !p.skip(tokenPunctuator, ")") {
+		name := p.expect(tokenName, "").value
+		p.expect(tokenPunctuator, ":")
+
+		if _, ok := arguments[name]; ok {
+			p.fail("duplicate argument '%s'", name)
+		}
+
+		arguments[name] = p.parseValue(constant)
+	}
+
+	p.depth = depth
+
+	return arguments
+}
+
+func (p *parser) parseDirectives() []*Directive {
+	directives := make([]*Directive, 0)
+
+	for p.skip(tokenPunctuator, "@") {
+		directive := &Directive{Name: p.expect(tokenName, "").value}
+		directive.Arguments = p.parseArguments(false)
+
+		directives = append(directives, directive)
+	}
+
+	return directives
+}
+
+func (p *parser) parseValue(constant bool) Value {
+	token := p.token
+
+	switch {
+	case token.kind == tokenPunctuator && token.value == "$" && !constant:
+		p.next()
+		return Variable(p.expect(tokenName, "").value)
+	case token.kind == tokenPunctuator && token.value == "[":
+		p.next()
+		p.enter()
+
+		list := make([]Value, 0)
+		for !p.skip(tokenPunctuator, "]") {
+			list = append(list, p.parseValue(constant))
+		}
+
+		p.leave()
+
+		return list
+	case token.kind == tokenPunctuator && token.value == "{":
+		p.next()
+		p.enter()
+
+		object := make(map[string]Value)
+		for !p.skip(tokenPunctuator, "}") {
+			name := p.expect(tokenName, "").value
+			p.expect(tokenPunctuator, ":")
+			object[name] = p.parseValue(constant)
+		}
+
+		p.leave()
+
+		return object
+	case token.kind == tokenInt:
+		p.next()
+
+		value, err := strconv.Atoi(token.value)
+		if err != nil {
+			p.fail("invalid integer '%s'", token.value)
+		}
+
+		return value
+	case token.kind == tokenFloat:
+		p.next()
+
+		value, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			p.fail("invalid float '%s'", token.value)
+		}
+
+		return value
+	case token.kind == tokenString:
+		p.next()
+		return token.value
+	case token.kind == tokenName:
+		p.next()
+
+		switch token.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		default:
+			return EnumValue(token.value)
+		}
+	}
+
+	p.fail("unexpected '%s'", token.value)
+
+	return nil
+}
+
+func (p *parser) enter() {
+	p.depth++
+
+	if p.maxDepth != 0 && p.depth > p.maxDepth {
+		p.fail("nesting exceeds the maximum depth of %d", p.maxDepth)
+	}
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) peek(kind int, value string) bool {
+	return p.token.kind == kind && (len(value) == 0 || p.token.value == value)
+}
+
+func (p *parser) skip(kind int, value string) bool {
+	if !p.peek(kind, value) {
+		return false
+	}
+
+	p.next()
+
+	return true
+}
+
+func (p *parser) expect(kind int, value string) token {
+	token := p.token
+
+	if !p.peek(kind, value) {
+		if len(value) != 0 {
+			p.fail("expected '%s', found '%s'", value, token.value)
+		}
+
+		p.fail("expected a name, found '%s'", token.value)
+	}
+
+	p.next()
+
+	return token
+}
+
+func (p *parser) fail(format string, args ...any) {
+	line := strings.Count(p.source[:p.token.pos], "\n") + 1
+
+	panic(&Error{Message: fmt.Sprintf("syntax error on line %d: %s", line, fmt.Sprintf(format, args...))})
+}
+
+func (p *parser) next() {
+	source := p.source
+
+	for p.pos < len(source) {
+		char := source[p.pos]
+
+		if char == '#' {
+			for p.pos < len(source) && source[p.pos] != '\n' {
+				p.pos++
+			}
+
+			continue
+		}
+
+		if char != ' ' && char != '\t' && char != '\n' && char != '\r' && char != ',' {
+			break
+		}
+
+		p.pos++
+	}
+
+	start := p.pos
+
+	if p.pos >= len(source) {
+		p.token = token{tokenEOF, "<EOF>", start}
+		return
+	}
+
+	char := source[p.pos]
+
+	switch {
+	case strings.HasPrefix(source[p.pos:], "..."):
+		p.pos += 3
+		p.token = token{tokenPunctuator, "...", start}
+	case strings.IndexByte("!$()[]{}:=@|&", char) >= 0:
+		p.pos++
+		p.token = token{tokenPunctuator, string(char), start}
+	case char == '_' || isLetter(char):
+		for p.pos < len(source) && (source[p.pos] == '_' || isLetter(source[p.pos]) || isDigit(source[p.pos])) {
+			p.pos++
+		}
+
+		p.token = token{tokenName, source[start:p.pos], start}
+	case char == '-' || isDigit(char):
+		p.token = p.lexNumber()
+	case char == '"':
+		p.token = p.lexString()
+	default:
+		p.token = token{tokenPunctuator, string(char), start}
+		p.fail("unexpected character '%c'", char)
+	}
+}
+
+func (p *parser) lexNumber() token {
+	source := p.source
+	start := p.pos
+	kind := tokenInt
+
+	if source[p.pos] == '-' {
+		p.pos++
+	}
+
+	for p.pos < len(source) && isDigit(source[p.pos]) {
+		p.pos++
+	}
+
+	if p.pos < len(source) && source[p.pos] == '.' {
+		kind = tokenFloat
+		p.pos++
+
+		for p.pos < len(source) && isDigit(source[p.pos]) {
+			p.pos++
+		}
+	}
+
+	if p.pos < len(source) && (source[p.pos] == 'e' || source[p.pos] == 'E') {
+		kind = tokenFloat
+		p.pos++
+
+		if p.pos < len(source) && (source[p.pos] == '+' || source[p.pos] == '-') {
+			p.pos++
+		}
+
+		for p.pos < len(source) && isDigit(source[p.pos]) {
+			p.pos++
+		}
+	}
+
+	return token{kind, source[start:p.pos], start}
+}
+
+func (p *parser) lexString() token {
+	source := p.source
+	start := p.pos
+
+	p.pos++
+
+	var value strings.Builder
+
+	for {
+		if p.pos >= len(source) || source[p.pos] == '\n' {
+			p.token = token{tokenString, "", start}
+			p.fail("unterminated string")
+		}
+
+		char := source[p.pos]
+
+		if char == '"' {
+			p.pos++
+			return token{tokenString, value.String(), start}
+		}
+
+		if char != '\\' {
+			r, size := utf8.DecodeRuneInString(source[p.pos:])
+			value.WriteRune(r)
+			p.pos += size
+
+			continue
+		}
+
+		p.pos++
+
+		if p.pos >= len(source) {
+			continue
+		}
+
+		switch escaped := source[p.pos]; escaped {
+		case '"', '\\', '/':
+			value.WriteByte(escaped)
+		case 'b':
+			value.WriteByte('\b')
+		case 'f':
+			value.WriteByte('\f')
+		case 'n':
+			value.WriteByte('\n')
+		case 'r':
+			value.WriteByte('\r')
+		case 't':
+			value.WriteByte('\t')
+		case 'u':
+			if p.pos+5 > len(source) {
+				p.fail("invalid unicode escape")
+			}
+
+			code, err := strconv.ParseUint(source[p.pos+1:p.pos+5], 16, 32)
+			if err != nil {
+				p.fail("invalid unicode escape")
+			}
+
+			value.WriteRune(rune(code))
+			p.pos += 4
+		default:
+			p.fail("invalid escape '\\%c'", escaped)
+		}
+
+		p.pos++
+	}
+}
+
+func isLetter(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
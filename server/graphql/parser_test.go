@@ -0,0 +1,115 @@
+package graphql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		err      string
+		maxDepth int
+	}{
+		{"shorthand query", "{ a }", "", 0},
+		{"named query with variables", "query Q($id: ID!, $ids: [ID!] = [\"a\", \"b\"]) { a(id: $id) }", "", 0},
+		{"fragments and directives", "query { ...F ... on Query @skip(if: true) { b } } fragment F on Query { a @include(if: false) }", "", 0},
+		{"comments and commas", "# comment\n{ a, b # trailing\n }", "", 0},
+		{"empty document", "", "document does not contain an operation", 0},
+		{"only fragments", "fragment F on Query { a }", "document does not contain an operation", 0},
+		{"empty selection", "{ }", "syntax error on line 1: empty selection set", 0},
+		{"unclosed selection", "{ a", "syntax error on line 1: expected a name, found '<EOF>'", 0},
+		{"missing field name", "{ a: }", "syntax error on line 1: expected a name, found '}'", 0},
+		{"duplicate argument", "{ a(x: 1, x: 2) }", "syntax error on line 1: duplicate argument 'x'", 0},
+		{"duplicate fragment", "{ a } fragment F on Q { a } fragment F on Q { b }", "syntax error on line 1: duplicate fragment 'F'", 0},
+		{"fragment named on", "{ a } fragment on on Q { a }", "syntax error on line 1: fragment cannot be named 'on'", 0},
+		{"variable default", "query ($a: Int = $b) { a }", "syntax error on line 1: unexpected '$'", 0},
+		{"unterminated string", "{ a(x: \"abc) }", "syntax error on line 1: unterminated string", 0},
+		{"invalid escape", "{ a(x: \"\\q\") }", "syntax error on line 1: invalid escape '\\q'", 0},
+		{"unexpected character", "{\n a ? }", "syntax error on line 2: unexpected character '?'", 0},
+		{"maximum depth", "query ($a: [[ID]]) { a { b(x: [[1]], y: {z: {w: 1}}) { c } } }", "", 3},
+		{"selections too deep", "{ a { b { c { d } } } }", "syntax error on line 1: nesting exceeds the maximum depth of 3", 3},
+		{"inline fragments too deep", "{ ... { ... { ... { a } } } }", "syntax error on line 1: nesting exceeds the maximum depth of 3", 3},
+		{"lists too deep", "{ a(x: [[[[1]]]]) }", "syntax error on line 1: nesting exceeds the maximum depth of 3", 3},
+		{"objects too deep", "{ a(x: {b: {c: {d: {e: 1}}}}) }", "syntax error on line 1: nesting exceeds the maximum depth of 3", 3},
+		{"list types too deep", "query ($a: [[[[ID]]]]) { a }", "syntax error on line 1: nesting exceeds the maximum depth of 3", 3},
+		{"unbounded nesting", strings.Repeat("{ a ", 100000) + "{ b }" + strings.Repeat(" }", 100000), "syntax error on line 1: nesting exceeds the maximum depth of 8", 8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := Parse(test.source, test.maxDepth)
+
+			if len(test.err) == 0 {
+				if err != nil || doc == nil {
+					t.Fatalf("Parse(%q) returned %v", test.source, err)
+				}
+
+				return
+			}
+
+			if err == nil || err.Error() != test.err || doc != nil {
+				t.Errorf("Parse(%q) returned %v, want %q", test.source, err, test.err)
+			}
+		})
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	doc, err := Parse(`
+		query Charts($country: String = "SK", $limit: Int) @cached {
+			top: chart(country: $country, limit: 10, ratio: -1.5e2, type: DAILY, tags: ["a", null], range: {from: 1, to: true}) {
+				...Entry @include(if: $limit)
+				... on ChartEntry { streams }
+				__typename
+			}
+		}
+
+		fragment Entry on ChartEntry { position, title: name(escaped: "\"\u00e9\"") }
+	`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Document{
+		Operations: []*Operation{{
+			Type:      "query",
+			Name:      "Charts",
+			Variables: map[string]Value{"country": "SK", "limit": nil},
+			Selections: []Selection{
+				&Field{
+					Alias: "top",
+					Name:  "chart",
+					Arguments: map[string]Value{
+						"country": Variable("country"),
+						"limit":   10,
+						"ratio":   -150.0,
+						"type":    EnumValue("DAILY"),
+						"tags":    []Value{"a", nil},
+						"range":   map[string]Value{"from": 1, "to": true},
+					},
+					Directives: []*Directive{},
+					Selections: []Selection{
+						&FragmentSpread{Name: "Entry", Directives: []*Directive{{Name: "include", Arguments: map[string]Value{"if": Variable("limit")}}}},
+						&InlineFragment{TypeCondition: "ChartEntry", Directives: []*Directive{}, Selections: []Selection{
+							&Field{Name: "streams", Arguments: map[string]Value{}, Directives: []*Directive{}},
+						}},
+						&Field{Name: "__typename", Arguments: map[string]Value{}, Directives: []*Directive{}},
+					},
+				},
+			},
+		}},
+		Fragments: map[string]*Fragment{
+			"Entry": {Name: "Entry", TypeCondition: "ChartEntry", Selections: []Selection{
+				&Field{Name: "position", Arguments: map[string]Value{}, Directives: []*Directive{}},
+				&Field{Alias: "title", Name: "name", Arguments: map[string]Value{"escaped": "\"é\""}, Directives: []*Directive{}},
+			}},
+		},
+	}
+
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("Parse returned %#v, want %#v", doc, want)
+	}
+}
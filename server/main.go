@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"fmt"
@@ -8,11 +9,16 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"spotify-charter/archive"
 	"spotify-charter/db"
 	"spotify-charter/model"
 	"spotify-charter/server"
 	"spotify-charter/spotify"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -23,61 +29,274 @@ func main() {
 	apiClientSecret := os.Getenv("SPOTIFY_CHARTER_API_CLIENT_SECRET")
 	dbFile := os.Getenv("SPOTIFY_CHARTER_DB_FILE")
 
+	args := os.Args[1:]
+
+	if len(args) != 0 && args[0] == "rebuild" {
+		rebuildDatabase(args[1:])
+		return
+	}
+
 	sqlDB := initDB(dbFile)
 
 	defer sqlDB.Close()
 
 	initCountries("countries.csv", sqlDB)
+	initChartSources("chart_sources.csv", sqlDB)
+
+	if len(args) != 0 && args[0] == "backfill" {
+		backfillCharts(sqlDB, args[1:])
+		return
+	}
+
+	if len(args) != 0 && args[0] == "aggregate" {
+		aggregateCommand(sqlDB, args[1:])
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	apiClient := spotify.NewAPIClient(apiClientID, apiClientSecret, initTransport())
+	apiClient.SetContext(ctx)
 
-	apiClient := spotify.NewAPIClient(apiClientID, apiClientSecret)
 	if err := apiClient.Authorize(); err != nil {
 		log.Panicln(err)
 	}
 
-	dateNow := model.TimeToDatestamp(time.Now())
+	defer apiClient.Close()
+
+	switch {
+	case len(args) == 0:
+		scrapeAndServe(ctx, stop, sqlDB, apiClient)
+	case len(args) >= 2 && args[0] == "countries" && args[1] == "discover":
+		discoverChartSources(sqlDB, apiClient, args[2:])
+	default:
+		log.Panicf("Unknown command '%s'\n", strings.Join(args, " "))
+	}
+}
 
+func scrapeAndServe(ctx context.Context, stop context.CancelFunc, sqlDB *sql.DB, apiClient *spotify.APICLient) {
 	reader := db.NewReader(sqlDB)
-	countriesWithPlaylist := reader.GetCountriesWithPlaylist()
+	defer reader.Close()
+
+	eventStore := db.NewEventStore(sqlDB)
+	defer eventStore.Close()
+
+	apiServer := &server.Server{
+		Reader:     reader,
+		Cache:      server.NewChartCache(initSize("SPOTIFY_CHARTER_CHART_CACHE_SIZE", 256)),
+		Events:     server.NewEventBus(eventStore, reader, initSize("SPOTIFY_CHARTER_EVENT_HISTORY_SIZE", 1024)),
+		AdminToken: os.Getenv("SPOTIFY_CHARTER_ADMIN_TOKEN"),
+	}
+
+	webhookStore := db.NewWebhookStore(sqlDB)
+	defer webhookStore.Close()
+
+	apiServer.Webhooks = server.NewWebhookDispatcher(webhookStore, reader)
+
+	db.AddCommitHook(apiServer.Cache.Invalidate)
+	db.AddCommitHook(apiServer.Events.PublishCommit)
+	db.AddCommitHook(apiServer.Webhooks.PublishCommit)
+
+	served := make(chan bool)
+	published := make(chan bool)
+	delivered := make(chan bool)
+
+	go func() {
+		defer close(served)
+
+		if err := serve(ctx, apiServer); err != nil {
+			log.Printf("Serving the API failed, cancelling the scrape: %s\n", err)
+			stop()
+		}
+	}()
+
+	go func() {
+		apiServer.Events.Run(ctx)
+		close(published)
+	}()
+
+	go func() {
+		apiServer.Webhooks.Run(ctx)
+		close(delivered)
+	}()
+
+	scrapeCharts(ctx, sqlDB, apiClient, reader, apiServer.Events)
+
+	<-served
+	<-published
+	<-delivered
+
+	apiServer.Events.Flush()
+	apiServer.Webhooks.Flush()
+}
+
+func scrapeCharts(ctx context.Context, sqlDB *sql.DB, apiClient *spotify.APICLient, reader *db.Reader, events *server.EventBus) {
+	dateNow := model.TimeToDatestamp(time.Now())
+
+	chartSources := reader.GetChartSources()
+
+	ingest := model.IngestEventExt{
+		Date:      dateNow,
+		StartedAt: time.Now().Unix(),
+		Sources:   len(chartSources),
+	}
+
+	events.Publish(&model.EventExt{Type: server.EventIngestStarted, Ingest: &ingest})
+
+	defer func() {
+		events.Flush()
+
+		finished := ingest
+		finished.FinishedAt = time.Now().Unix()
+		finished.Cancelled = ctx.Err() != nil
+
+		events.Publish(&model.EventExt{Type: server.EventIngestFinished, Ingest: &finished})
+	}()
 
 	wg := new(sync.WaitGroup)
 
-	writer := db.NewWriter(sqlDB)
+	scrape := &scrape{
+		ctx:        ctx,
+		apiClient:  apiClient,
+		writer:     db.NewWriter(sqlDB),
+		archive:    initArchive(),
+		date:       dateNow,
+		rediscover: os.Getenv("SPOTIFY_CHARTER_REDISCOVER") == "1",
+	}
+
+	if scrape.archive != nil {
+		apiClient.SetResponseHook(scrape.archiveMetadata)
+	}
+
+	for _, chartSource := range chartSources {
+		if chartSource.IsBroken() && time.Since(time.Unix(chartSource.BrokenAt, 0)) < brokenSourceReprobeInterval {
+			log.Printf("[%s/%s] Skipping broken playlist '%s' (%s)\n", chartSource.Country.Code,
+				chartSource.ChartType, chartSource.PlaylistID, chartSource.BrokenReason)
+
+			continue
+		}
 
-	for _, country := range countriesWithPlaylist {
 		wg.Add(1)
 
-		go getCountry(wg, apiClient, country, writer, dateNow, model.DailyTopTrack)
+		go scrape.getChart(wg, chartSource)
 	}
 
 	wg.Wait()
 
-	writer.Commit()
+	scrape.writer.Commit()
 
-	chartTracks := reader.GetChartTracksExt(dateNow)
-	for countryCode := range *chartTracks {
-		if countryCode != "SK" {
-			continue
-		}
+	if ctx.Err() != nil {
+		log.Println("Scrape cancelled, committed the completed charts and skipping enrichment")
+		return
+	}
 
-		fmt.Println((*chartTracks)[countryCode][0].Name)
+	enrichTracks(ctx, sqlDB, apiClient, reader)
+	enrichAlbums(ctx, sqlDB, apiClient, reader)
+	enrichArtists(ctx, sqlDB, apiClient, reader, dateNow)
+	enrichAudioFeatures(ctx, sqlDB, apiClient, reader, dateNow)
 
-		for _, artist := range (*chartTracks)[countryCode][0].Artists {
-			fmt.Println("\t", artist.Name)
-		}
+	if ctx.Err() != nil {
+		log.Println("Enrichment cancelled, skipping aggregation")
+		return
+	}
 
-		for _, image := range (*chartTracks)[countryCode][0].Album.Images {
-			fmt.Println("\t\t", image.URL)
-		}
+	for _, chartType := range model.AggregateChartTypes {
+		aggregateCharts(sqlDB, reader, chartType, dateNow, os.Getenv("SPOTIFY_CHARTER_AGGREGATE_SCHEME"))
+	}
+}
+
+func serve(ctx context.Context, apiServer *server.Server) error {
+	httpServer := &http.Server{
+		Addr:              ":8080",
+		Handler:           apiServer.Routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	httpServer.RegisterOnShutdown(apiServer.Events.Close)
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	fmt.Println("listening on: http://localhost:8080/v1")
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down the HTTP server, draining in-flight requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Draining in-flight requests failed: %s\n", err)
+	}
+
+	log.Println("Successfully shut down the HTTP server")
+
+	return nil
+}
+
+func initSize(envName string, fallback int) int {
+	sizeEnv := os.Getenv(envName)
+	if len(sizeEnv) == 0 {
+		return fallback
+	}
+
+	size, err := strconv.Atoi(sizeEnv)
+	if err != nil || size <= 0 {
+		log.Panicf("Invalid value '%s' for %s\n", sizeEnv, envName)
+	}
+
+	return size
+}
+
+func initTransport() http.RoundTripper {
+	modeEnv := os.Getenv("SPOTIFY_CHARTER_HTTP_MODE")
+	if len(modeEnv) == 0 {
+		return http.DefaultTransport
+	}
+
+	mode, ok := spotify.ParseCassetteMode(modeEnv)
+	if !ok {
+		log.Panicf("Unknown HTTP mode '%s'\n", modeEnv)
+	}
+
+	cassetteDir := os.Getenv("SPOTIFY_CHARTER_CASSETTE_DIR")
+
+	log.Printf("Using HTTP mode '%s' with cassette directory '%s'\n", mode, cassetteDir)
+
+	transport, err := spotify.NewCassetteTransport(http.DefaultTransport, mode, cassetteDir)
+	if err != nil {
+		log.Panicln(err)
 	}
 
-	server := server.Server{
-		Reader: reader,
+	return transport
+}
+
+func initArchive() *archive.Archive {
+	archiveDir := os.Getenv("SPOTIFY_CHARTER_ARCHIVE_DIR")
+	if len(archiveDir) == 0 {
+		return nil
 	}
 
-	fmt.Println("listening on: http://localhost:8080/test")
+	log.Printf("Archiving raw API responses to '%s'\n", archiveDir)
+
+	responseArchive, err := archive.Open(archiveDir)
+	if err != nil {
+		log.Panicln(err)
+	}
 
-	http.HandleFunc("/test", server.GetPlaylists)
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	return responseArchive
 }
 
 func initDB(dbPath string) *sql.DB {
@@ -119,9 +338,8 @@ func initCountries(csvPath string, sqlDB *sql.DB) {
 
 	for len(record) != 0 && err == nil {
 		country := model.Country{
-			Code:          record[0],
-			Name:          record[1],
-			TopPlaylistID: record[2],
+			Code: record[0],
+			Name: record[1],
 		}
 
 		log.Printf("Upserting country '%s' ('%s') to the DB\n", country.Name, country.Code)
@@ -140,26 +358,64 @@ func initCountries(csvPath string, sqlDB *sql.DB) {
 	writer.Commit()
 }
 
-func getCountry(wg *sync.WaitGroup, apiClient *spotify.APICLient, country *model.Country, writer *db.Writer, date int64, chartType model.ChartType) {
-	tracks, err := apiClient.GetPlaylist(country.TopPlaylistID)
+func initChartSources(csvPath string, sqlDB *sql.DB) {
+	log.Printf("Seeding missing and broken chart sources from the chart sources file '%s' to the DB\n", csvPath)
+
+	chartSources, err := os.Open(csvPath)
 	if err != nil {
-		fmt.Println(err)
-		return
+		log.Panicln(err)
+	}
+
+	defer chartSources.Close()
+
+	csvReader := csv.NewReader(chartSources)
+
+	if _, err = csvReader.Read(); err != nil {
+		log.Panicln(err)
+	}
+
+	reader := db.NewReader(sqlDB)
+	defer reader.Close()
+
+	existing := make(map[string]*model.ChartSource)
+
+	for _, chartSource := range reader.GetChartSources() {
+		existing[chartSource.Country.Code+"/"+string(chartSource.ChartType)] = chartSource
 	}
 
-	for index, track := range tracks {
-		chartTrack := &model.ChartTrack{
-			Country:   country,
-			Track:     track,
-			ChartType: chartType,
-			Date:      date,
-			Position:  index,
+	record, err := csvReader.Read()
+
+	writer := db.NewWriter(sqlDB)
+
+	for len(record) != 0 && err == nil {
+		chartType, ok := model.ParseChartType(record[1])
+		if !ok || chartType.IsAggregate() {
+			log.Panicf("Unknown chart type '%s' for country '%s'\n", record[1], record[0])
+		}
+
+		if current := existing[record[0]+"/"+string(chartType)]; current != nil && (!current.IsBroken() || current.PlaylistID == record[2]) {
+			record, err = csvReader.Read()
+			continue
+		}
+
+		chartSource := model.ChartSource{
+			Country:    &model.Country{Code: record[0]},
+			ChartType:  chartType,
+			PlaylistID: record[2],
 		}
 
-		writer.SaveChartTrack(chartTrack)
+		log.Printf("Inserting chart source '%s' for country '%s' to the DB\n", chartSource.ChartType, record[0])
 
-		log.Printf("[%s] %d: %s\n", country.Code, index, track.Name)
+		writer.SaveChartSource(&chartSource)
+
+		record, err = csvReader.Read()
+	}
+
+	if err != io.EOF {
+		log.Panicln(err)
 	}
 
-	wg.Done()
+	log.Println("Successfully finished writing chart sources from the chart sources file to the DB")
+
+	writer.Commit()
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"spotify-charter/db"
+	"spotify-charter/discovery"
+	"spotify-charter/model"
+	"testing"
+)
+
+func TestInitChartSourcesReplacesBrokenSources(t *testing.T) {
+	dir := t.TempDir()
+
+	sqlDB, err := sql.Open("sqlite3", filepath.Join(dir, "charter.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqlDB.Close()
+
+	db.CreateTables(sqlDB)
+
+	writer := db.NewWriter(sqlDB)
+	writer.SaveCountry(&model.Country{Code: "SK", Name: "Slovakia"})
+	writer.SaveCountry(&model.Country{Code: "CZ", Name: "Czechia"})
+	writer.SaveChartSource(&model.ChartSource{Country: &model.Country{Code: "SK"}, ChartType: model.DailyTopTrack, PlaylistID: "p1"})
+	writer.SaveChartSource(&model.ChartSource{Country: &model.Country{Code: "CZ"}, ChartType: model.DailyTopTrack, PlaylistID: "p2"})
+	writer.SaveChartSourceStatus(&model.ChartSource{Country: &model.Country{Code: "SK"}, ChartType: model.DailyTopTrack, PlaylistID: "p1", BrokenAt: 1, BrokenReason: "gone"})
+	writer.Commit()
+
+	reader := db.NewReader(sqlDB)
+
+	countries := reader.GetCountriesWithoutWorkingChartSource(model.DailyTopTrack)
+	if len(countries) != 1 || countries[0].Code != "SK" {
+		t.Errorf("got countries %v needing a source, want only SK", countries)
+	}
+
+	reader.Close()
+
+	csvPath := filepath.Join(dir, "chart_sources.csv")
+	csv := "Code,Chart Type,Playlist ID\nSK,DAILY_TOP_TRACK,p9\nCZ,DAILY_TOP_TRACK,p8\n"
+
+	if err := os.WriteFile(csvPath, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	initChartSources(csvPath, sqlDB)
+
+	reader = db.NewReader(sqlDB)
+	defer reader.Close()
+
+	playlists := make(map[string]string)
+
+	for _, chartSource := range reader.GetChartSources() {
+		if chartSource.IsBroken() {
+			t.Errorf("source for %s is still broken", chartSource.Country.Code)
+		}
+
+		playlists[chartSource.Country.Code] = chartSource.PlaylistID
+	}
+
+	if playlists["SK"] != "p9" || playlists["CZ"] != "p2" {
+		t.Errorf("got playlists %v, want the CSV to replace only the broken SK source", playlists)
+	}
+}
+
+func TestCountryPlaylistsOnlyAutoAcceptForTheirCountry(t *testing.T) {
+	file, err := os.Open("countries.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	countries := make([]*model.Country, 0, len(records))
+	for _, record := range records[1:] {
+		countries = append(countries, &model.Country{Code: record[0], Name: record[1]})
+	}
+
+	for _, country := range countries {
+		query, _ := discovery.SearchQuery(country, model.DailyTopTrack)
+		playlists := []*model.Playlist{{SpotifyID: country.Code, Name: query, OwnerID: "spotify"}}
+
+		for _, other := range countries {
+			candidates := discovery.Rank(other, model.DailyTopTrack, playlists)
+
+			accepted := len(candidates) != 0 && candidates[0].Score >= discovery.AutoAcceptScore
+			if accepted != (other == country) {
+				t.Errorf("the %q playlist auto-accepted %t for %s (%s)", query, accepted, other.Name, other.Code)
+			}
+		}
+	}
+}
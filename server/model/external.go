@@ -1,14 +1,32 @@
 package model
 
+type CountryExt struct {
+	Code       string      `json:"code"`
+	Name       string      `json:"name"`
+	ChartTypes []ChartType `json:"chart_types,omitempty"`
+}
+
 type ArtistExt struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Genres []string `json:"genres"`
 }
 
 type AlbumExt struct {
-	ID     string     `json:"id"`
-	Name   string     `json:"name"`
-	Images []ImageExt `json:"images"`
+	ID                   string         `json:"id"`
+	Name                 string         `json:"name"`
+	Images               []ImageExt     `json:"images"`
+	ReleaseDate          string         `json:"release_date,omitempty"`
+	ReleaseDatePrecision string         `json:"release_date_precision,omitempty"`
+	AlbumType            string         `json:"album_type,omitempty"`
+	TotalTracks          int            `json:"total_tracks,omitempty"`
+	Label                string         `json:"label,omitempty"`
+	Copyrights           []CopyrightExt `json:"copyrights"`
+}
+
+type CopyrightExt struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
 }
 
 type ImageExt struct {
@@ -17,10 +35,171 @@ type ImageExt struct {
 }
 
 type TrackExt struct {
-	ID      string      `json:"id"`
-	Name    string      `json:"name"`
-	Album   AlbumExt    `json:"album"`
-	Artists []ArtistExt `json:"artists"`
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Album            AlbumExt          `json:"album"`
+	Artists          []ArtistExt       `json:"artists"`
+	DurationMs       int               `json:"duration_ms"`
+	Explicit         bool              `json:"explicit"`
+	Popularity       int               `json:"popularity"`
+	DiscNumber       int               `json:"disc_number"`
+	TrackNumber      int               `json:"track_number"`
+	ISRC             string            `json:"isrc,omitempty"`
+	PreviewURL       string            `json:"preview_url,omitempty"`
+	DaysSinceRelease *int              `json:"days_since_release"`
+	AudioFeatures    *AudioFeaturesExt `json:"audio_features"`
+	MarketTrackID    string            `json:"market_track_id,omitempty"`
+	IsPlayable       *bool             `json:"is_playable,omitempty"`
+	Streams          *int64            `json:"streams,omitempty"`
+}
+
+type AudioFeaturesExt struct {
+	Tempo            float64 `json:"tempo"`
+	Key              int     `json:"key"`
+	Mode             int     `json:"mode"`
+	TimeSignature    int     `json:"time_signature"`
+	Energy           float64 `json:"energy"`
+	Danceability     float64 `json:"danceability"`
+	Valence          float64 `json:"valence"`
+	Acousticness     float64 `json:"acousticness"`
+	Instrumentalness float64 `json:"instrumentalness"`
+	Liveness         float64 `json:"liveness"`
+	Speechiness      float64 `json:"speechiness"`
+	Loudness         float64 `json:"loudness"`
+}
+
+type SoundProfileExt struct {
+	CountryCode      string  `json:"country_code"`
+	Date             int64   `json:"date"`
+	Tracks           int     `json:"tracks"`
+	Tempo            float64 `json:"tempo"`
+	Energy           float64 `json:"energy"`
+	Danceability     float64 `json:"danceability"`
+	Valence          float64 `json:"valence"`
+	Acousticness     float64 `json:"acousticness"`
+	Instrumentalness float64 `json:"instrumentalness"`
+	Liveness         float64 `json:"liveness"`
+	Speechiness      float64 `json:"speechiness"`
+	Loudness         float64 `json:"loudness"`
+	Mode             float64 `json:"mode"`
+}
+
+type ArtistStreamsExt struct {
+	ArtistID  string `json:"artist_id"`
+	Name      string `json:"name"`
+	WeekStart int64  `json:"week_start"`
+	Streams   int64  `json:"streams"`
+}
+
+type CountryStreamsExt struct {
+	CountryCode     string  `json:"country_code"`
+	Streams         int64   `json:"streams"`
+	TopTrackID      string  `json:"top_track_id"`
+	TopTrackStreams int64   `json:"top_track_streams"`
+	TopTrackShare   float64 `json:"top_track_share"`
 }
 
 type ChartTracksExt = map[string][]*TrackExt
+
+type ChartSourceExt struct {
+	CountryCode  string    `json:"country_code"`
+	ChartType    ChartType `json:"chart_type"`
+	PlaylistID   string    `json:"playlist_id"`
+	Broken       bool      `json:"broken"`
+	BrokenAt     int64     `json:"broken_at,omitempty"`
+	BrokenReason string    `json:"broken_reason,omitempty"`
+}
+
+type ChartMovementExt struct {
+	TrackID          string `json:"track_id"`
+	Name             string `json:"name"`
+	Position         *int   `json:"position"`
+	PreviousPosition *int   `json:"previous_position"`
+}
+
+type ChartMovementsExt struct {
+	PreviousDate int64             `json:"previous_date,omitempty"`
+	Entries      int               `json:"entries"`
+	New          int               `json:"new"`
+	Climbers     int               `json:"climbers"`
+	Fallers      int               `json:"fallers"`
+	Unchanged    int               `json:"unchanged"`
+	Exits        int               `json:"exits"`
+	NumberOne    *ChartMovementExt `json:"number_one"`
+	TopClimber   *ChartMovementExt `json:"top_climber"`
+}
+
+type ChartEventExt struct {
+	ChartType ChartType          `json:"chart_type"`
+	Date      int64              `json:"date"`
+	Movements *ChartMovementsExt `json:"movements"`
+}
+
+type IngestEventExt struct {
+	Date       int64 `json:"date"`
+	StartedAt  int64 `json:"started_at"`
+	FinishedAt int64 `json:"finished_at,omitempty"`
+	Sources    int   `json:"sources"`
+	Cancelled  bool  `json:"cancelled,omitempty"`
+}
+
+type EventExt struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	CountryCode string          `json:"country_code,omitempty"`
+	Time        int64           `json:"time"`
+	Chart       *ChartEventExt  `json:"chart,omitempty"`
+	Ingest      *IngestEventExt `json:"ingest,omitempty"`
+}
+
+type WebhookExt struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Event       string    `json:"event"`
+	CountryCode string    `json:"country_code,omitempty"`
+	ArtistID    string    `json:"artist_id,omitempty"`
+	ChartType   ChartType `json:"chart_type,omitempty"`
+	CreatedAt   int64     `json:"created_at"`
+}
+
+type WebhookRequestExt struct {
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Event       string    `json:"event"`
+	CountryCode string    `json:"country_code,omitempty"`
+	ArtistID    string    `json:"artist_id,omitempty"`
+	ChartType   ChartType `json:"chart_type,omitempty"`
+}
+
+type WebhookEventExt struct {
+	ID          string            `json:"id"`
+	Type        string            `json:"type"`
+	CountryCode string            `json:"country_code"`
+	ChartType   ChartType         `json:"chart_type"`
+	Date        int64             `json:"date"`
+	Movement    *ChartMovementExt `json:"movement"`
+	Artists     []*ArtistExt      `json:"artists"`
+	ArtistID    string            `json:"artist_id,omitempty"`
+}
+
+type WebhookAttemptExt struct {
+	AttemptedAt int64  `json:"attempted_at"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	Error       string `json:"error,omitempty"`
+	DurationMs  int64  `json:"duration_ms"`
+}
+
+type WebhookDeliveryExt struct {
+	ID             int64                `json:"id"`
+	WebhookID      int64                `json:"webhook_id"`
+	Event          *WebhookEventExt     `json:"event"`
+	Status         string               `json:"status"`
+	NextAttemptAt  int64                `json:"next_attempt_at,omitempty"`
+	LastStatusCode int                  `json:"last_status_code,omitempty"`
+	LastError      string               `json:"last_error,omitempty"`
+	ReplayOf       int64                `json:"replay_of,omitempty"`
+	CreatedAt      int64                `json:"created_at"`
+	DeliveredAt    int64                `json:"delivered_at,omitempty"`
+	Attempts       []*WebhookAttemptExt `json:"attempts"`
+}
@@ -3,20 +3,38 @@ package model
 import "time"
 
 type Country struct {
-	Code          string
-	Name          string
-	TopPlaylistID string
+	Code string
+	Name string
 }
 
 type Artist struct {
-	SpotifyID string
-	Name      string
+	SpotifyID  string
+	Name       string
+	Genres     []string
+	Popularity int
+	Followers  int
+}
+
+type ArtistSnapshot struct {
+	Artist *Artist
+	Date   int64
 }
 
 type Album struct {
-	SpotifyID string
-	Name      string
-	Images    []Image
+	SpotifyID            string
+	Name                 string
+	Images               []Image
+	ReleaseDate          string
+	ReleaseDatePrecision string
+	AlbumType            string
+	TotalTracks          int
+	Label                string
+	Copyrights           []Copyright
+}
+
+type Copyright struct {
+	Text string
+	Type string
 }
 
 type Image struct {
@@ -25,24 +43,214 @@ type Image struct {
 }
 
 type Track struct {
-	SpotifyID string
-	Name      string
-	Album     Album
-	Artists   []Artist
+	SpotifyID    string
+	Name         string
+	Album        Album
+	Artists      []Artist
+	DurationMs   int
+	Explicit     bool
+	Popularity   int
+	DiscNumber   int
+	TrackNumber  int
+	ISRC         string
+	PreviewURL   string
+	IsPlayable   bool
+	LinkedFromID string
+}
+
+type AudioFeatures struct {
+	TrackID          string
+	Tempo            float64
+	Key              int
+	Mode             int
+	TimeSignature    int
+	Energy           float64
+	Danceability     float64
+	Valence          float64
+	Acousticness     float64
+	Instrumentalness float64
+	Liveness         float64
+	Speechiness      float64
+	Loudness         float64
+}
+
+type AudioFeaturesMiss struct {
+	TrackID string
+	Date    int64
 }
 
 type ChartType string
 
 const (
-	DailyTopTrack ChartType = "DAILY_TOP_TRACK"
+	DailyTopTrack   ChartType = "DAILY_TOP_TRACK"
+	WeeklyTopTrack  ChartType = "WEEKLY_TOP_TRACK"
+	DailyViralTrack ChartType = "DAILY_VIRAL_TRACK"
+	NewReleases     ChartType = "NEW_RELEASES"
+)
+
+var ChartTypes = []ChartType{
+	DailyTopTrack,
+	WeeklyTopTrack,
+	DailyViralTrack,
+	NewReleases,
+}
+
+const (
+	WeeklyAggregate  ChartType = "WEEKLY_AGGREGATE"
+	MonthlyAggregate ChartType = "MONTHLY_AGGREGATE"
+	YearlyAggregate  ChartType = "YEARLY_AGGREGATE"
 )
 
+var AggregateChartTypes = []ChartType{
+	WeeklyAggregate,
+	MonthlyAggregate,
+	YearlyAggregate,
+}
+
+func ParseChartType(s string) (ChartType, bool) {
+	for _, chartType := range append(ChartTypes, AggregateChartTypes...) {
+		if string(chartType) == s {
+			return chartType, true
+		}
+	}
+
+	return "", false
+}
+
+func (chartType ChartType) IsAggregate() bool {
+	for _, aggregateChartType := range AggregateChartTypes {
+		if chartType == aggregateChartType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (chartType ChartType) PeriodBounds(date int64) (int64, int64) {
+	t := time.Unix(date, 0).UTC()
+
+	var start, end time.Time
+
+	switch chartType {
+	case WeeklyTopTrack:
+		start = t.AddDate(0, 0, -(int(t.Weekday())+2)%7)
+		end = start.AddDate(0, 0, 6)
+	case WeeklyAggregate:
+		start = t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+		end = start.AddDate(0, 0, 6)
+	case MonthlyAggregate:
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 1, -1)
+	case YearlyAggregate:
+		start = time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(1, 0, -1)
+	default:
+		return TimeToDatestamp(t), TimeToDatestamp(t)
+	}
+
+	return TimeToDatestamp(start), TimeToDatestamp(end)
+}
+
+type ChartSource struct {
+	Country      *Country
+	ChartType    ChartType
+	PlaylistID   string
+	BrokenAt     int64
+	BrokenReason string
+}
+
+func (chartSource *ChartSource) IsBroken() bool {
+	return chartSource.BrokenAt != 0
+}
+
+type ChartKey struct {
+	CountryCode string
+	ChartType   ChartType
+	Date        int64
+}
+
 type ChartTrack struct {
+	Country     *Country
+	Track       *Track
+	ChartType   ChartType
+	Date        int64
+	CaptureDate int64
+	Position    int
+	Streams     int64
+}
+
+const OffChart = -1
+
+type ChartMovement struct {
+	Track            *Track
+	Position         int
+	PreviousPosition int
+}
+
+func (movement *ChartMovement) IsNew() bool {
+	return movement.PreviousPosition == OffChart
+}
+
+func (movement *ChartMovement) IsExit() bool {
+	return movement.Position == OffChart
+}
+
+func (movement *ChartMovement) Change() int {
+	if movement.IsNew() || movement.IsExit() {
+		return 0
+	}
+
+	return movement.PreviousPosition - movement.Position
+}
+
+type ChartHighlight struct {
 	Country   *Country
-	Track     *Track
 	ChartType ChartType
 	Date      int64
-	Position  int
+	UpdatedAt int64
+	Movement  *ChartMovement
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+type Webhook struct {
+	ID          int64
+	URL         string
+	Secret      string
+	EventType   string
+	CountryCode string
+	ArtistID    string
+	ChartType   ChartType
+	CreatedAt   int64
+}
+
+type WebhookDelivery struct {
+	ID             int64
+	Webhook        *Webhook
+	EventID        string
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int
+	NextAttemptAt  int64
+	LastStatusCode int
+	LastError      string
+	ReplayOf       int64
+	CreatedAt      int64
+	DeliveredAt    int64
+}
+
+type WebhookAttempt struct {
+	DeliveryID  int64
+	AttemptedAt int64
+	StatusCode  int
+	Error       string
+	DurationMs  int64
 }
 
 func TimeToDatestamp(t time.Time) int64 {
@@ -50,3 +258,55 @@ func TimeToDatestamp(t time.Time) int64 {
 
 	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Unix()
 }
+
+var releaseDateLayouts = map[string]string{
+	"year":  "2006",
+	"month": "2006-01",
+	"day":   "2006-01-02",
+}
+
+func ReleaseDateToDatestamp(releaseDate string, precision string) (int64, bool) {
+	layout, ok := releaseDateLayouts[precision]
+	if !ok {
+		return 0, false
+	}
+
+	t, err := time.Parse(layout, releaseDate)
+	if err != nil {
+		return 0, false
+	}
+
+	return TimeToDatestamp(t), true
+}
+
+func DaysBetween(from int64, to int64) int {
+	return int((to - from) / int64(24*time.Hour/time.Second))
+}
+
+const GlobalCountryCode = "AA"
+
+func (country *Country) Market() string {
+	if country.Code == GlobalCountryCode {
+		return ""
+	}
+
+	return country.Code
+}
+
+type Playlist struct {
+	SpotifyID   string
+	Name        string
+	Description string
+	OwnerID     string
+}
+
+type ResponseKind string
+
+const (
+	PlaylistResponse      ResponseKind = "PLAYLIST"
+	TracksResponse        ResponseKind = "TRACKS"
+	AlbumsResponse        ResponseKind = "ALBUMS"
+	ArtistsResponse       ResponseKind = "ARTISTS"
+	AudioFeaturesResponse ResponseKind = "AUDIO_FEATURES"
+	ChartsCSVResponse     ResponseKind = "CHARTS_CSV"
+)
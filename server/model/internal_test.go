@@ -0,0 +1,41 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodBounds(t *testing.T) {
+	tests := []struct {
+		chartType ChartType
+		date      string
+		from      string
+		to        string
+	}{
+		{DailyTopTrack, "2026-10-19", "2026-10-19", "2026-10-19"},
+		{WeeklyTopTrack, "2026-10-16", "2026-10-16", "2026-10-22"},
+		{WeeklyTopTrack, "2026-10-19", "2026-10-16", "2026-10-22"},
+		{WeeklyTopTrack, "2026-10-22", "2026-10-16", "2026-10-22"},
+		{WeeklyTopTrack, "2026-10-23", "2026-10-23", "2026-10-29"},
+		{DailyViralTrack, "2026-10-19", "2026-10-19", "2026-10-19"},
+		{WeeklyAggregate, "2026-10-19", "2026-10-19", "2026-10-25"},
+		{WeeklyAggregate, "2026-10-25", "2026-10-19", "2026-10-25"},
+		{WeeklyAggregate, "2027-01-01", "2026-12-28", "2027-01-03"},
+		{MonthlyAggregate, "2026-10-19", "2026-10-01", "2026-10-31"},
+		{MonthlyAggregate, "2028-02-10", "2028-02-01", "2028-02-29"},
+		{YearlyAggregate, "2026-10-19", "2026-01-01", "2026-12-31"},
+	}
+
+	for _, test := range tests {
+		date, _ := time.Parse("2006-01-02", test.date)
+
+		from, to := test.chartType.PeriodBounds(TimeToDatestamp(date))
+
+		gotFrom := time.Unix(from, 0).UTC().Format("2006-01-02")
+		gotTo := time.Unix(to, 0).UTC().Format("2006-01-02")
+
+		if gotFrom != test.from || gotTo != test.to {
+			t.Errorf("%s.PeriodBounds(%s) = %s..%s, want %s..%s", test.chartType, test.date, gotFrom, gotTo, test.from, test.to)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"spotify-charter/archive"
+	"spotify-charter/backfill"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"spotify-charter/spotify"
+)
+
+func rebuildDatabase(args []string) {
+	if len(args) != 1 {
+		log.Panicln("Usage: rebuild <new DB file>")
+	}
+
+	dbFile := args[0]
+
+	if _, err := os.Stat(dbFile); err == nil {
+		log.Panicf("Refusing to rebuild into the existing DB file '%s'\n", dbFile)
+	}
+
+	responseArchive := initArchive()
+	if responseArchive == nil {
+		log.Panicln("SPOTIFY_CHARTER_ARCHIVE_DIR must be set to rebuild the DB")
+	}
+
+	entries, err := responseArchive.Entries()
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	sqlDB := initDB(dbFile)
+
+	defer sqlDB.Close()
+
+	initCountries("countries.csv", sqlDB)
+	initChartSources("chart_sources.csv", sqlDB)
+
+	log.Printf("Rebuilding the DB from %d archived responses\n", len(entries))
+
+	writer := db.NewWriter(sqlDB)
+
+	for _, entry := range entries {
+		body, err := responseArchive.Load(entry.Hash)
+		if err != nil {
+			log.Printf("Loading archived response '%s' failed: %s\n", entry.Hash, err)
+			continue
+		}
+
+		if err := replayEntry(writer, entry, body); err != nil {
+			log.Printf("Replaying archived '%s' response '%s' failed: %s\n", entry.Kind, entry.Hash, err)
+		}
+	}
+
+	writer.Commit()
+
+	log.Println("Successfully rebuilt the DB from the archive")
+}
+
+func replayEntry(writer *db.Writer, entry *archive.Entry, body []byte) error {
+	switch entry.Kind {
+	case model.PlaylistResponse:
+		tracks, err := spotify.ParsePlaylist(body)
+		if err != nil {
+			return err
+		}
+
+		country := &model.Country{Code: entry.CountryCode}
+		date, _ := entry.ChartType.PeriodBounds(entry.Date)
+
+		for index, track := range tracks {
+			if track == nil {
+				continue
+			}
+
+			chartTrack := &model.ChartTrack{
+				Country:     country,
+				Track:       track,
+				ChartType:   entry.ChartType,
+				Date:        date,
+				CaptureDate: entry.Date,
+				Position:    index,
+			}
+
+			if err := writer.SaveChartTrack(chartTrack); err != nil {
+				log.Printf("[%s/%s] %d: Rejecting track: %s\n", entry.CountryCode, entry.ChartType, index, err)
+			}
+		}
+	case model.TracksResponse:
+		tracks, err := spotify.ParseTracks(body)
+		if err != nil {
+			return err
+		}
+
+		for _, track := range tracks {
+			if err := writer.SaveTrack(track); err != nil {
+				log.Printf("Rejecting track: %s\n", err)
+			}
+		}
+	case model.AlbumsResponse:
+		albums, err := spotify.ParseAlbums(body)
+		if err != nil {
+			return err
+		}
+
+		for _, album := range albums {
+			if err := writer.SaveAlbumDetails(album); err != nil {
+				log.Printf("Rejecting album: %s\n", err)
+			}
+		}
+	case model.ArtistsResponse:
+		artists, err := spotify.ParseArtists(body)
+		if err != nil {
+			return err
+		}
+
+		for _, artist := range artists {
+			artistSnapshot := &model.ArtistSnapshot{
+				Artist: artist,
+				Date:   entry.Date,
+			}
+
+			if err := writer.SaveArtistDetails(artistSnapshot); err != nil {
+				log.Printf("Rejecting artist: %s\n", err)
+			}
+		}
+	case model.AudioFeaturesResponse:
+		audioFeatures, err := spotify.ParseAudioFeatures(body)
+		if err != nil {
+			return err
+		}
+
+		for _, features := range audioFeatures {
+			writer.SaveAudioFeatures(features)
+		}
+	case model.ChartsCSVResponse:
+		entries, err := backfill.ReadEntries(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		chart := &backfill.Chart{
+			CountryCode: entry.CountryCode,
+			ChartType:   entry.ChartType,
+			Date:        entry.Date,
+		}
+
+		return saveBackfillEntries(writer, chart, entries)
+	default:
+		log.Printf("Skipping archived '%s' response '%s'\n", entry.Kind, entry.Hash)
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"spotify-charter/model"
+	"spotify-charter/spotify"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+const (
+	playlistBody = `{"items":[{"is_local":false,"track":{"type":"track","id":"t1","name":"One",` +
+		`"album":{"id":"a1","name":"Album"},"artists":[{"id":"r1","name":"Artist"}]}}]}`
+	albumsBody = `{"albums":[{"id":"a1","name":"Album","label":"Label","copyrights":[{"text":"(C) Label","type":"C"}]}]}`
+)
+
+func TestRebuildReplaysArchivedMetadata(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("SPOTIFY_CHARTER_ARCHIVE_DIR", filepath.Join(dir, "archive"))
+
+	apiClient := spotify.NewAPIClient("id", "secret", roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := playlistBody
+		if strings.HasPrefix(req.URL.Path, "/v1/albums") {
+			body = albumsBody
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}))
+	defer apiClient.Close()
+
+	scrape := &scrape{
+		ctx:       context.Background(),
+		apiClient: apiClient,
+		archive:   initArchive(),
+		date:      1792368000,
+	}
+
+	apiClient.SetResponseHook(scrape.archiveMetadata)
+
+	chartSource := &model.ChartSource{
+		Country:    &model.Country{Code: "SK"},
+		ChartType:  model.DailyTopTrack,
+		PlaylistID: "p1",
+	}
+
+	if _, err := scrape.fetchPlaylist(chartSource); err != nil {
+		t.Fatalf("fetching the playlist failed: %s", err)
+	}
+
+	if _, err := apiClient.GetAlbum("a1"); err != nil {
+		t.Fatalf("fetching the album failed: %s", err)
+	}
+
+	entries, err := scrape.archive.Entries()
+	if err != nil {
+		t.Fatalf("reading the archive failed: %s", err)
+	}
+
+	kinds := make([]model.ResponseKind, 0)
+	for _, entry := range entries {
+		kinds = append(kinds, entry.Kind)
+	}
+
+	if len(kinds) != 2 || kinds[1] != model.AlbumsResponse {
+		t.Fatalf("archived %v, want a playlist and an albums response", kinds)
+	}
+
+	dbFile := filepath.Join(dir, "rebuilt.db")
+
+	rebuildDatabase([]string{dbFile})
+
+	sqlDB, err := sql.Open("sqlite3", dbFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqlDB.Close()
+
+	var label string
+	if err := sqlDB.QueryRow("SELECT COALESCE(label, '') FROM albums WHERE spotify_id = 'a1'").Scan(&label); err != nil {
+		t.Fatalf("reading the rebuilt album failed: %s", err)
+	}
+
+	if label != "Label" {
+		t.Errorf("rebuilt album has label %q, want %q", label, "Label")
+	}
+
+	var chartTracks int
+	if err := sqlDB.QueryRow("SELECT COUNT(*) FROM chart_tracks WHERE country_code = 'SK' AND track_id = 't1'").Scan(&chartTracks); err != nil {
+		t.Fatal(err)
+	}
+
+	if chartTracks != 1 {
+		t.Errorf("rebuilt DB has %d SK chart rows for 't1', want 1", chartTracks)
+	}
+}
+
+func TestRebuildReplaysArchivedBackfill(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("SPOTIFY_CHARTER_ARCHIVE_DIR", filepath.Join(dir, "archive"))
+
+	csvDir := filepath.Join(dir, "charts")
+	if err := os.Mkdir(csvDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	csvBody := "Position,Track Name,Artist,Streams,URL\n" +
+		"1,One,Art One,\"12,345\",https://open.spotify.com/track/t1\n" +
+		"2,Two,Art Two,678,https://open.spotify.com/track/t2\n"
+
+	if err := os.WriteFile(filepath.Join(csvDir, "regional-sk-daily-2026-10-19.csv"), []byte(csvBody), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sourceDB := initDB(filepath.Join(dir, "source.db"))
+	defer sourceDB.Close()
+
+	initCountries("countries.csv", sourceDB)
+	backfillCharts(sourceDB, []string{csvDir})
+
+	dbFile := filepath.Join(dir, "rebuilt.db")
+
+	rebuildDatabase([]string{dbFile})
+
+	sqlDB, err := sql.Open("sqlite3", dbFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqlDB.Close()
+
+	rows, err := sqlDB.Query("SELECT track_id, position, streams FROM chart_tracks WHERE country_code = 'SK' ORDER BY position")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var trackID string
+		var position, streams int
+
+		if err := rows.Scan(&trackID, &position, &streams); err != nil {
+			t.Fatal(err)
+		}
+
+		got = append(got, fmt.Sprintf("%d:%s:%d", position, trackID, streams))
+	}
+
+	if want := []string{"0:t1:12345", "1:t2:678"}; !slices.Equal(got, want) {
+		t.Errorf("rebuilt DB has SK chart rows %v, want %v", got, want)
+	}
+}
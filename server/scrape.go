@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"spotify-charter/archive"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"spotify-charter/spotify"
+	"sync"
+	"time"
+)
+
+const brokenSourceReprobeInterval = 7 * 24 * time.Hour
+
+type scrape struct {
+	ctx        context.Context
+	apiClient  *spotify.APICLient
+	writer     *db.Writer
+	archive    *archive.Archive
+	date       int64
+	rediscover bool
+}
+
+func (s *scrape) getChart(wg *sync.WaitGroup, chartSource *model.ChartSource) {
+	defer wg.Done()
+
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	tracks, err := s.fetchPlaylist(chartSource)
+	if spotify.IsUnavailable(err) {
+		log.Printf("[%s/%s] Playlist '%s' is unavailable: %s\n", chartSource.Country.Code, chartSource.ChartType, chartSource.PlaylistID, err)
+
+		chartSource.BrokenAt = time.Now().Unix()
+		chartSource.BrokenReason = err.Error()
+
+		s.writer.SaveChartSourceStatus(chartSource)
+
+		if !s.rediscover {
+			return
+		}
+
+		replacement := rediscoverChartSource(s.apiClient, chartSource)
+		if replacement == nil {
+			return
+		}
+
+		s.writer.SaveChartSource(replacement)
+
+		chartSource = replacement
+		tracks, err = s.fetchPlaylist(chartSource)
+	}
+
+	if s.ctx.Err() != nil {
+		log.Printf("[%s/%s] Scrape cancelled, dropping playlist '%s'\n", chartSource.Country.Code, chartSource.ChartType, chartSource.PlaylistID)
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if chartSource.IsBroken() {
+		log.Printf("[%s/%s] Playlist '%s' is available again\n", chartSource.Country.Code, chartSource.ChartType, chartSource.PlaylistID)
+
+		chartSource.BrokenAt = 0
+		chartSource.BrokenReason = ""
+
+		s.writer.SaveChartSourceStatus(chartSource)
+	}
+
+	date, _ := chartSource.ChartType.PeriodBounds(s.date)
+
+	for index, track := range tracks {
+		if track == nil {
+			log.Printf("[%s/%s] %d: Skipping unavailable, local or non-track item\n", chartSource.Country.Code, chartSource.ChartType, index)
+			continue
+		}
+
+		chartTrack := &model.ChartTrack{
+			Country:     chartSource.Country,
+			Track:       track,
+			ChartType:   chartSource.ChartType,
+			Date:        date,
+			CaptureDate: s.date,
+			Position:    index,
+		}
+
+		if err := s.writer.SaveChartTrack(chartTrack); err != nil {
+			log.Printf("[%s/%s] %d: Rejecting track: %s\n", chartSource.Country.Code, chartSource.ChartType, index, err)
+			continue
+		}
+
+		log.Printf("[%s/%s] %d: %s\n", chartSource.Country.Code, chartSource.ChartType, index, track.Name)
+	}
+}
+
+func (s *scrape) fetchPlaylist(chartSource *model.ChartSource) ([]*model.Track, error) {
+	body, err := s.apiClient.FetchPlaylist(chartSource.PlaylistID, chartSource.Country.Market())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.archive != nil {
+		entry := &archive.Entry{
+			Kind:        model.PlaylistResponse,
+			CountryCode: chartSource.Country.Code,
+			ChartType:   chartSource.ChartType,
+			Date:        s.date,
+			CapturedAt:  time.Now().Unix(),
+		}
+
+		if err := s.archive.Store(entry, body); err != nil {
+			log.Printf("[%s/%s] Archiving playlist response failed: %s\n", chartSource.Country.Code, chartSource.ChartType, err)
+		}
+	}
+
+	return spotify.ParsePlaylist(body)
+}
+
+func (s *scrape) archiveMetadata(kind model.ResponseKind, body []byte) {
+	entry := &archive.Entry{
+		Kind:       kind,
+		Date:       s.date,
+		CapturedAt: time.Now().Unix(),
+	}
+
+	if err := s.archive.Store(entry, body); err != nil {
+		log.Printf("Archiving '%s' response failed: %s\n", kind, err)
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"container/list"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"sync"
+)
+
+type chartCacheKey struct {
+	chartType    model.ChartType
+	date         int64
+	countryCode  string
+	lastModified int64
+}
+
+type chartCacheEntry struct {
+	key         chartCacheKey
+	chartTracks *model.ChartTracksExt
+}
+
+type chartCacheLoad struct {
+	done        chan struct{}
+	chartTracks *model.ChartTracksExt
+}
+
+type ChartCacheStats struct {
+	Entries       int     `json:"entries"`
+	MaxEntries    int     `json:"max_entries"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRate       float64 `json:"hit_rate"`
+	Evictions     int64   `json:"evictions"`
+	Invalidations int64   `json:"invalidations"`
+}
+
+type ChartCache struct {
+	lock          sync.Mutex
+	maxEntries    int
+	entries       map[chartCacheKey]*list.Element
+	recency       *list.List
+	loads         map[chartCacheKey]*chartCacheLoad
+	generation    int64
+	hits          int64
+	misses        int64
+	evictions     int64
+	invalidations int64
+}
+
+func NewChartCache(maxEntries int) *ChartCache {
+	return &ChartCache{
+		maxEntries: maxEntries,
+		entries:    make(map[chartCacheKey]*list.Element),
+		recency:    list.New(),
+		loads:      make(map[chartCacheKey]*chartCacheLoad),
+	}
+}
+
+func (cache *ChartCache) get(key chartCacheKey, load func() *model.ChartTracksExt) *model.ChartTracksExt {
+	cache.lock.Lock()
+
+	if element, ok := cache.entries[key]; ok {
+		cache.hits++
+		cache.recency.MoveToFront(element)
+		cache.lock.Unlock()
+
+		return element.Value.(*chartCacheEntry).chartTracks
+	}
+
+	cache.misses++
+
+	if pending, ok := cache.loads[key]; ok {
+		cache.lock.Unlock()
+		<-pending.done
+
+		if pending.chartTracks != nil {
+			return pending.chartTracks
+		}
+
+		return load()
+	}
+
+	pending := &chartCacheLoad{done: make(chan struct{})}
+	cache.loads[key] = pending
+	generation := cache.generation
+	cache.lock.Unlock()
+
+	defer close(pending.done)
+
+	defer func() {
+		cache.lock.Lock()
+		delete(cache.loads, key)
+		cache.lock.Unlock()
+	}()
+
+	chartTracks := load()
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	pending.chartTracks = chartTracks
+
+	if generation == cache.generation {
+		cache.put(key, chartTracks)
+	}
+
+	return chartTracks
+}
+
+func (cache *ChartCache) put(key chartCacheKey, chartTracks *model.ChartTracksExt) {
+	cache.entries[key] = cache.recency.PushFront(&chartCacheEntry{key: key, chartTracks: chartTracks})
+
+	for cache.recency.Len() > cache.maxEntries {
+		oldest := cache.recency.Back()
+
+		cache.recency.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*chartCacheEntry).key)
+
+		cache.evictions++
+	}
+}
+
+func (cache *ChartCache) Invalidate(commit *db.Commit) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.generation++
+
+	if commit.MetadataUpdated {
+		cache.invalidations += int64(len(cache.entries))
+		cache.entries = make(map[chartCacheKey]*list.Element)
+		cache.recency.Init()
+
+		return
+	}
+
+	committed := make(map[model.ChartKey]bool)
+	for _, chartKey := range commit.Charts {
+		committed[chartKey] = true
+		committed[model.ChartKey{ChartType: chartKey.ChartType, Date: chartKey.Date}] = true
+	}
+
+	for key, element := range cache.entries {
+		if committed[model.ChartKey{CountryCode: key.countryCode, ChartType: key.chartType, Date: key.date}] {
+			cache.recency.Remove(element)
+			delete(cache.entries, key)
+
+			cache.invalidations++
+		}
+	}
+}
+
+func (cache *ChartCache) Stats() *ChartCacheStats {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	stats := &ChartCacheStats{
+		Entries:       len(cache.entries),
+		MaxEntries:    cache.maxEntries,
+		Hits:          cache.hits,
+		Misses:        cache.misses,
+		Evictions:     cache.evictions,
+		Invalidations: cache.invalidations,
+	}
+
+	if requests := cache.hits + cache.misses; requests != 0 {
+		stats.HitRate = float64(cache.hits) / float64(requests)
+	}
+
+	return stats
+}
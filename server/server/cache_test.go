@@ -0,0 +1,288 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func backdateUpdates(t *testing.T, sqlDB *sql.DB) {
+	for _, query := range []string{"UPDATE chart_updates SET updated_at = 1", "UPDATE ingests SET updated_at = 1"} {
+		if _, err := sqlDB.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func metadataUpdatedAt(t *testing.T, sqlDB *sql.DB) int64 {
+	var updatedAt int64
+	if err := sqlDB.QueryRow("SELECT updated_at FROM ingests WHERE kind = 'metadata'").Scan(&updatedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	return updatedAt
+}
+
+func getCountryChart(t *testing.T, routes http.Handler) []string {
+	res := httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/v1/countries/SK/charts/DAILY_TOP_TRACK/2026-10-19", nil))
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("chart responded with %d: %s", res.Code, res.Body)
+	}
+
+	var body struct {
+		Data []*model.TrackExt `json:"data"`
+	}
+
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	trackIDs := make([]string, 0, len(body.Data))
+	for _, track := range body.Data {
+		if track != nil {
+			trackIDs = append(trackIDs, track.ID)
+		}
+	}
+
+	return trackIDs
+}
+
+func TestChartCacheSeesCommitsFromOtherProcesses(t *testing.T) {
+	sqlDB := newTestDB(t)
+
+	reader := db.NewReader(sqlDB)
+	t.Cleanup(reader.Close)
+
+	backdateUpdates(t, sqlDB)
+
+	s := &Server{Reader: reader, Cache: NewChartCache(4)}
+	routes := s.Routes()
+
+	if trackIDs := getCountryChart(t, routes); len(trackIDs) != 2 || trackIDs[0] != "t0" {
+		t.Fatalf("got chart %v, want [t0 t1]", trackIDs)
+	}
+
+	writer := db.NewWriter(sqlDB)
+	writer.ReplaceChart([]*model.ChartTrack{{
+		Country:   &model.Country{Code: "SK"},
+		Track:     &model.Track{SpotifyID: "t2"},
+		ChartType: model.DailyTopTrack,
+		Date:      testDate,
+	}})
+	writer.Commit()
+
+	if trackIDs := getCountryChart(t, routes); len(trackIDs) != 1 || trackIDs[0] != "t2" {
+		t.Errorf("got chart %v after a commit without hooks, want [t2]", trackIDs)
+	}
+}
+
+func TestUnchangedTracksDoNotUpdateMetadata(t *testing.T) {
+	sqlDB := newTestDB(t)
+
+	backdateUpdates(t, sqlDB)
+
+	saveTrack := func(name string) {
+		writer := db.NewWriter(sqlDB)
+
+		err := writer.SaveChartTrack(&model.ChartTrack{
+			Country:   &model.Country{Code: "SK"},
+			Track:     &model.Track{SpotifyID: "t0", Name: name, Album: model.Album{SpotifyID: "a0", Name: "Album Zero"}, Artists: []model.Artist{{SpotifyID: "r1", Name: "Art One"}}},
+			ChartType: model.DailyTopTrack,
+			Date:      testDate,
+			Streams:   2000,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writer.Commit()
+	}
+
+	saveTrack("Zero")
+
+	if updatedAt := metadataUpdatedAt(t, sqlDB); updatedAt != 1 {
+		t.Errorf("rewriting an unchanged track moved the metadata ingest to %d", updatedAt)
+	}
+
+	saveTrack("Zero (Remastered)")
+
+	if updatedAt := metadataUpdatedAt(t, sqlDB); updatedAt == 1 {
+		t.Errorf("renaming a track did not update the metadata ingest")
+	}
+
+	backdateUpdates(t, sqlDB)
+
+	saveArtist := func(genres []string, popularity int) {
+		writer := db.NewWriter(sqlDB)
+
+		err := writer.SaveArtistDetails(&model.ArtistSnapshot{
+			Artist: &model.Artist{SpotifyID: "r1", Name: "Art One", Genres: genres, Popularity: popularity, Followers: 1000 + popularity},
+			Date:   testDate + 86400,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writer.SaveAudioFeatures(&model.AudioFeatures{TrackID: "t0", Tempo: 120, Energy: 0.5, Danceability: 0.7})
+		writer.Commit()
+	}
+
+	saveArtist([]string{"pop"}, 60)
+
+	if updatedAt := metadataUpdatedAt(t, sqlDB); updatedAt != 1 {
+		t.Errorf("re-enriching an artist with unchanged genres moved the metadata ingest to %d", updatedAt)
+	}
+
+	saveArtist([]string{"pop", "dance pop"}, 60)
+
+	if updatedAt := metadataUpdatedAt(t, sqlDB); updatedAt == 1 {
+		t.Errorf("adding an artist genre did not update the metadata ingest")
+	}
+
+	backdateUpdates(t, sqlDB)
+
+	saveArtist([]string{"dance pop"}, 60)
+
+	if updatedAt := metadataUpdatedAt(t, sqlDB); updatedAt == 1 {
+		t.Errorf("removing an artist genre did not update the metadata ingest")
+	}
+}
+
+func TestChartCacheControl(t *testing.T) {
+	sqlDB := newTestDB(t)
+
+	reader := db.NewReader(sqlDB)
+	t.Cleanup(reader.Close)
+
+	routes := (&Server{Reader: reader}).Routes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return res
+	}
+
+	finished := get("/v1/countries/SK/charts/DAILY_TOP_TRACK/2026-10-18")
+	if cacheControl := finished.Header().Get("Cache-Control"); cacheControl != finishedCacheControl {
+		t.Errorf("a finished chart has Cache-Control %q, want %q", cacheControl, finishedCacheControl)
+	}
+
+	for _, path := range []string{"/v1/countries/SK/charts/DAILY_TOP_TRACK/2020-01-01", "/v1/countries/CZ/charts/WEEKLY_TOP_TRACK/2026-10-15"} {
+		if cacheControl := get(path).Header().Get("Cache-Control"); cacheControl != revalidateCacheControl {
+			t.Errorf("%s has Cache-Control %q, want %q for a chart that was never written", path, cacheControl, revalidateCacheControl)
+		}
+	}
+
+	revalidate := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/countries/SK/charts/DAILY_TOP_TRACK/2026-10-18", nil)
+		req.Header.Set("If-None-Match", finished.Header().Get("ETag"))
+
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+
+		return res
+	}
+
+	if _, err := sqlDB.Exec("UPDATE ingests SET updated_at = updated_at + 86400"); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := revalidate(); res.Code != http.StatusNotModified || res.Header().Get("Cache-Control") != finishedCacheControl {
+		t.Errorf("revalidating a finished chart after a metadata ingest that left it unchanged responded with %d, want 304", res.Code)
+	}
+
+	writer := db.NewWriter(sqlDB)
+
+	err := writer.SaveArtistDetails(&model.ArtistSnapshot{
+		Artist: &model.Artist{SpotifyID: "r2", Name: "Art Two", Genres: []string{"indie"}, Popularity: 40, Followers: 500},
+		Date:   testDate + 86400,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer.SaveAudioFeatures(&model.AudioFeatures{TrackID: "t2", Tempo: 96, Energy: 0.4, Danceability: 0.6})
+	writer.Commit()
+
+	res := revalidate()
+	if res.Code != http.StatusOK || res.Header().Get("ETag") == finished.Header().Get("ETag") {
+		t.Errorf("revalidating a re-enriched finished chart responded with %d and ETag %s, want the new chart", res.Code, res.Header().Get("ETag"))
+	}
+
+	if cacheControl := res.Header().Get("Cache-Control"); cacheControl != finishedCacheControl {
+		t.Errorf("a re-enriched finished chart has Cache-Control %q, want %q", cacheControl, finishedCacheControl)
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"gzip;q=0.000", ""},
+		{"*", "br"},
+		{"br;q=0, *", "gzip"},
+		{"GZIP; q=0.8", "gzip"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+
+		if encoding := negotiateEncoding(req); encoding != test.want {
+			t.Errorf("negotiateEncoding(%q) returned %q, want %q", test.acceptEncoding, encoding, test.want)
+		}
+	}
+}
+
+func TestCompressedResponses(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"name":"Zero"}`), compressionMinBodyBytes)
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}
+
+	for encoding, decode := range decoders {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+
+		res := httptest.NewRecorder()
+		writeBody(res, req, http.StatusOK, jsonContentType, payload)
+
+		if contentEncoding := res.Header().Get("Content-Encoding"); contentEncoding != encoding {
+			t.Fatalf("responded with Content-Encoding %q, want %q", contentEncoding, encoding)
+		}
+
+		reader, err := decode(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := io.ReadAll(reader)
+		if err != nil || !bytes.Equal(body, payload) {
+			t.Errorf("%s body did not decode to the payload: %v", encoding, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"spotify-charter/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	finishedCacheControl    = "public, max-age=86400"
+	revalidateCacheControl  = "public, no-cache"
+	compressionMinBodyBytes = 1024
+)
+
+func (s *Server) notModified(w http.ResponseWriter, r *http.Request, chartType model.ChartType, date int64, countryCode string) (lastModified int64, finished bool, unchanged bool) {
+	lastModified = s.Reader.GetChartLastModified(chartType, date, countryCode)
+
+	if chartFinished(chartType, date) && s.Reader.GetChartUpdatedAt(chartType, date, countryCode) != 0 {
+		return lastModified, true, false
+	}
+
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d/%d", countryCode, chartType, date, lastModified)))
+	etag := `W/"` + hex.EncodeToString(hash[:8]) + `"`
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", revalidateCacheControl)
+
+	return lastModified, false, conditionalNotModified(w, r, etag, lastModified)
+}
+
+func contentNotModified(w http.ResponseWriter, r *http.Request, payload []byte, lastModified int64, cacheControl string) bool {
+	hash := sha256.Sum256(payload)
+	etag := `"` + hex.EncodeToString(hash[:8]) + `"`
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", cacheControl)
+
+	return conditionalNotModified(w, r, etag, lastModified)
+}
+
+func conditionalNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified int64) bool {
+	header := w.Header()
+
+	if lastModified != 0 {
+		header.Set("Last-Modified", time.Unix(lastModified, 0).UTC().Format(http.TimeFormat))
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); len(ifNoneMatch) > 0 {
+		if !etagMatches(ifNoneMatch, etag) {
+			return false
+		}
+	} else {
+		ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || lastModified == 0 || lastModified > ifModifiedSince.Unix() {
+			return false
+		}
+	}
+
+	header.Add("Vary", "Accept-Encoding")
+	w.WriteHeader(http.StatusNotModified)
+
+	return true
+}
+
+func chartFinished(chartType model.ChartType, date int64) bool {
+	_, to := chartType.PeriodBounds(date)
+
+	return to < model.TimeToDatestamp(time.Now())
+}
+
+func etagMatches(ifNoneMatch string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func negotiateEncoding(r *http.Request) string {
+	qualities := make(map[string]float64)
+
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+		if len(name) == 0 {
+			continue
+		}
+
+		quality := 1.0
+
+		if value, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+
+			quality = parsed
+		}
+
+		qualities[strings.ToLower(name)] = quality
+	}
+
+	encoding, best := "", 0.0
+
+	for _, candidate := range []string{"br", "gzip"} {
+		quality, ok := qualities[candidate]
+		if !ok {
+			quality = qualities["*"]
+		}
+
+		if quality > best {
+			encoding, best = candidate, quality
+		}
+	}
+
+	return encoding
+}
@@ -0,0 +1,394 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventChartCommitted = "chart.committed"
+	EventIngestStarted  = "ingest.started"
+	EventIngestFinished = "ingest.finished"
+	EventHistoryGap     = "history.gap"
+)
+
+const (
+	eventSubscriberBuffer  = 64
+	eventKeepAliveInterval = 15 * time.Second
+	eventRetryMs           = 5000
+	lastEventIDHeader      = "Last-Event-ID"
+	eventStreamContentType = "text/event-stream"
+)
+
+type eventSubscriber struct {
+	events    chan *model.EventExt
+	countries map[string]bool
+}
+
+type EventBus struct {
+	lock        sync.Mutex
+	store       *db.EventStore
+	reader      *db.Reader
+	lastID      int64
+	droppedID   int64
+	history     []*model.EventExt
+	historySize int
+	subscribers map[*eventSubscriber]bool
+	closed      bool
+	commits     []*db.Commit
+	wake        chan bool
+	publishing  sync.Mutex
+}
+
+func NewEventBus(store *db.EventStore, reader *db.Reader, historySize int) *EventBus {
+	bus := &EventBus{
+		store:       store,
+		reader:      reader,
+		history:     make([]*model.EventExt, 0),
+		historySize: historySize,
+		subscribers: make(map[*eventSubscriber]bool),
+		wake:        make(chan bool, 1),
+	}
+
+	if store != nil {
+		bus.history = store.GetRecentEvents(historySize + 1)
+		bus.trimHistory()
+	}
+
+	if len(bus.history) != 0 {
+		bus.lastID = bus.history[len(bus.history)-1].ID
+	}
+
+	return bus
+}
+
+func (bus *EventBus) Publish(event *model.EventExt) {
+	bus.broadcast(event)
+
+	if bus.store != nil {
+		if err := bus.store.SaveEvent(event, bus.historySize+1); err != nil {
+			log.Printf("Persisting event %d failed, it will not be replayed after a restart: %s\n", event.ID, err)
+		}
+	}
+}
+
+func (bus *EventBus) broadcast(event *model.EventExt) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	now := time.Now()
+
+	bus.lastID = max(bus.lastID+1, now.UnixMicro())
+
+	event.ID = bus.lastID
+	event.Time = now.Unix()
+
+	bus.history = append(bus.history, event)
+	bus.trimHistory()
+
+	for subscriber := range bus.subscribers {
+		if !subscriber.wants(event) {
+			continue
+		}
+
+		select {
+		case subscriber.events <- event:
+		default:
+			delete(bus.subscribers, subscriber)
+			close(subscriber.events)
+		}
+	}
+}
+
+func (bus *EventBus) trimHistory() {
+	if len(bus.history) <= bus.historySize {
+		return
+	}
+
+	dropped := len(bus.history) - bus.historySize
+
+	bus.droppedID = bus.history[dropped-1].ID
+	bus.history = slices.Delete(bus.history, 0, dropped)
+}
+
+func (bus *EventBus) PublishCommit(commit *db.Commit) {
+	if len(commit.Charts) == 0 {
+		return
+	}
+
+	bus.lock.Lock()
+	bus.commits = append(bus.commits, commit)
+	bus.lock.Unlock()
+
+	select {
+	case bus.wake <- true:
+	default:
+	}
+}
+
+func (bus *EventBus) Run(ctx context.Context) {
+	for {
+		bus.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-bus.wake:
+		}
+	}
+}
+
+func (bus *EventBus) Flush() {
+	bus.publishing.Lock()
+	defer bus.publishing.Unlock()
+
+	bus.lock.Lock()
+	commits := bus.commits
+	bus.commits = nil
+	bus.lock.Unlock()
+
+	for _, commit := range commits {
+		bus.publishCharts(commit)
+	}
+}
+
+func (bus *EventBus) publishCharts(commit *db.Commit) {
+	charts := slices.Clone(commit.Charts)
+
+	slices.SortFunc(charts, func(a, b model.ChartKey) int {
+		return strings.Compare(a.CountryCode+string(a.ChartType), b.CountryCode+string(b.ChartType))
+	})
+
+	for _, chart := range charts {
+		bus.publishChart(chart)
+	}
+}
+
+func (bus *EventBus) publishChart(chart model.ChartKey) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("[%s/%s] Skipping chart event: %v\n", chart.CountryCode, chart.ChartType, err)
+		}
+	}()
+
+	previousDate, movements := bus.reader.GetChartMovements(chart.ChartType, chart.Date, chart.CountryCode)
+
+	bus.Publish(&model.EventExt{
+		Type:        EventChartCommitted,
+		CountryCode: chart.CountryCode,
+		Chart: &model.ChartEventExt{
+			ChartType: chart.ChartType,
+			Date:      chart.Date,
+			Movements: summarizeMovements(previousDate, movements),
+		},
+	})
+}
+
+func (bus *EventBus) Close() {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	bus.closed = true
+
+	for subscriber := range bus.subscribers {
+		delete(bus.subscribers, subscriber)
+		close(subscriber.events)
+	}
+}
+
+func (bus *EventBus) subscribe(lastEventID int64, countries map[string]bool) (*eventSubscriber, []*model.EventExt) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	subscriber := &eventSubscriber{
+		events:    make(chan *model.EventExt, eventSubscriberBuffer),
+		countries: countries,
+	}
+
+	if bus.closed {
+		close(subscriber.events)
+		return subscriber, nil
+	}
+
+	missed := make([]*model.EventExt, 0)
+
+	if lastEventID != 0 && lastEventID < bus.droppedID {
+		missed = append(missed, &model.EventExt{ID: bus.lastID, Type: EventHistoryGap, Time: time.Now().Unix()})
+	} else if lastEventID != 0 {
+		for _, event := range bus.history {
+			if event.ID > lastEventID && subscriber.wants(event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	bus.subscribers[subscriber] = true
+
+	return subscriber, missed
+}
+
+func (bus *EventBus) unsubscribe(subscriber *eventSubscriber) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	if bus.subscribers[subscriber] {
+		delete(bus.subscribers, subscriber)
+		close(subscriber.events)
+	}
+}
+
+func (subscriber *eventSubscriber) wants(event *model.EventExt) bool {
+	return len(subscriber.countries) == 0 || len(event.CountryCode) == 0 || subscriber.countries[event.CountryCode]
+}
+
+func summarizeMovements(previousDate int64, movements []*model.ChartMovement) *model.ChartMovementsExt {
+	summary := &model.ChartMovementsExt{PreviousDate: previousDate}
+
+	var topClimber *model.ChartMovement
+
+	for _, movement := range movements {
+		switch {
+		case movement.IsExit():
+			summary.Exits++
+			continue
+		case movement.IsNew():
+			summary.New++
+		case movement.Change() > 0:
+			summary.Climbers++
+		case movement.Change() < 0:
+			summary.Fallers++
+		default:
+			summary.Unchanged++
+		}
+
+		summary.Entries++
+
+		if movement.Position == 0 {
+			summary.NumberOne = movementExt(movement)
+		}
+
+		if movement.Change() > 0 && (topClimber == nil || movement.Change() > topClimber.Change()) {
+			topClimber = movement
+		}
+	}
+
+	if topClimber != nil {
+		summary.TopClimber = movementExt(topClimber)
+	}
+
+	return summary
+}
+
+func movementExt(movement *model.ChartMovement) *model.ChartMovementExt {
+	movementExt := &model.ChartMovementExt{
+		TrackID: movement.Track.SpotifyID,
+		Name:    movement.Track.Name,
+	}
+
+	if !movement.IsExit() {
+		position := movement.Position + 1
+		movementExt.Position = &position
+	}
+
+	if !movement.IsNew() {
+		previousPosition := movement.PreviousPosition + 1
+		movementExt.PreviousPosition = &previousPosition
+	}
+
+	return movementExt
+}
+
+func (s *Server) GetEvents(w http.ResponseWriter, r *http.Request) {
+	lastEventIDParam := r.Header.Get(lastEventIDHeader)
+	if len(lastEventIDParam) == 0 {
+		lastEventIDParam = r.URL.Query().Get("last_event_id")
+	}
+
+	var lastEventID int64
+
+	if len(lastEventIDParam) != 0 {
+		var err error
+
+		lastEventID, err = strconv.ParseInt(lastEventIDParam, 10, 64)
+		if err != nil || lastEventID < 0 {
+			writeError(w, r, badRequest("invalid last event ID '%s'", lastEventIDParam))
+			return
+		}
+	}
+
+	countries := make(map[string]bool)
+
+	for _, param := range r.URL.Query()["country"] {
+		for _, code := range strings.Split(param, ",") {
+			if code = strings.ToUpper(strings.TrimSpace(code)); len(code) != 0 {
+				countries[code] = true
+			}
+		}
+	}
+
+	controller := http.NewResponseController(w)
+
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		writeError(w, r, internalError())
+		return
+	}
+
+	subscriber, missed := s.Events.subscribe(lastEventID, countries)
+	defer s.Events.unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", eventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventRetryMs)
+
+	for _, event := range missed {
+		writeEvent(w, event)
+	}
+
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-subscriber.events:
+			if !ok {
+				return
+			}
+
+			writeEvent(w, event)
+		}
+
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event *model.EventExt) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
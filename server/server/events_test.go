@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"testing"
+	"time"
+)
+
+func newTestEventBus(t *testing.T, historySize int) (*EventBus, func(historySize int) *EventBus) {
+	sqlDB := newTestDB(t)
+
+	reader := db.NewReader(sqlDB)
+	t.Cleanup(reader.Close)
+
+	store := db.NewEventStore(sqlDB)
+	t.Cleanup(store.Close)
+
+	restart := func(historySize int) *EventBus {
+		return NewEventBus(store, reader, historySize)
+	}
+
+	return restart(historySize), restart
+}
+
+func publishTestEvents(bus *EventBus, count int) []*model.EventExt {
+	events := make([]*model.EventExt, 0, count)
+
+	for range count {
+		event := &model.EventExt{Type: EventIngestFinished, Ingest: &model.IngestEventExt{Date: testDate}}
+		bus.Publish(event)
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func eventIDs(events []*model.EventExt) []int64 {
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+
+	return ids
+}
+
+func TestEventHistorySurvivesRestart(t *testing.T) {
+	bus, restart := newTestEventBus(t, 16)
+
+	published := publishTestEvents(bus, 3)
+
+	restarted := restart(16)
+
+	_, missed := restarted.subscribe(published[0].ID, nil)
+	if len(missed) != 2 || missed[0].ID != published[1].ID || missed[1].ID != published[2].ID {
+		t.Fatalf("replayed %v after a restart, want %v", eventIDs(missed), eventIDs(published[1:]))
+	}
+
+	next := publishTestEvents(restarted, 1)[0]
+	if next.ID <= published[2].ID {
+		t.Errorf("event ID %d after a restart does not follow %d", next.ID, published[2].ID)
+	}
+}
+
+func TestEventHistoryReportsGaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		restart bool
+	}{
+		{"evicted", false},
+		{"restarted", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bus, restart := newTestEventBus(t, 2)
+
+			published := publishTestEvents(bus, 3)
+
+			if test.restart {
+				bus = restart(2)
+			}
+
+			_, missed := bus.subscribe(published[0].ID-1, nil)
+			if len(missed) != 1 || missed[0].Type != EventHistoryGap || missed[0].ID != published[2].ID {
+				t.Errorf("resuming before the kept history sent %v, want a single %s event", eventIDs(missed), EventHistoryGap)
+			}
+
+			_, missed = bus.subscribe(published[0].ID, nil)
+			if len(missed) != 2 || missed[0].ID != published[1].ID {
+				t.Errorf("resuming from the newest dropped event replayed %v, want %v", eventIDs(missed), eventIDs(published[1:]))
+			}
+		})
+	}
+}
+
+func TestEventBusSurvivesDBErrors(t *testing.T) {
+	sqlDB := newTestDB(t)
+
+	store := db.NewEventStore(sqlDB)
+	t.Cleanup(store.Close)
+
+	reader := db.NewReader(sqlDB)
+
+	bus := NewEventBus(store, reader, 16)
+
+	subscriber, _ := bus.subscribe(0, nil)
+
+	if _, err := sqlDB.Exec("DROP TABLE events"); err != nil {
+		t.Fatal(err)
+	}
+
+	published := publishTestEvents(bus, 1)[0]
+
+	if event := <-subscriber.events; event.ID != published.ID {
+		t.Errorf("subscriber got event %d, want %d even though it could not be persisted", event.ID, published.ID)
+	}
+
+	reader.Close()
+
+	bus.PublishCommit(testCommit)
+	bus.Flush()
+
+	if len(bus.history) != 1 {
+		t.Errorf("got %d events after a failing commit hook, want the chart event skipped", len(bus.history))
+	}
+}
+
+func TestChartEventsArePublishedOffTheCommitPath(t *testing.T) {
+	bus, _ := newTestEventBus(t, 16)
+
+	subscriber, _ := bus.subscribe(0, nil)
+
+	bus.PublishCommit(&db.Commit{CommittedAt: testDate})
+	bus.PublishCommit(testCommit)
+
+	if len(bus.history) != 0 {
+		t.Fatalf("got %d events while committing, want the chart events queued", len(bus.history))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan bool)
+
+	go func() {
+		bus.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case event := <-subscriber.events:
+		if event.Type != EventChartCommitted || event.CountryCode != "SK" || event.Chart == nil || event.Chart.Movements.Entries == 0 {
+			t.Errorf("got event %+v, want the committed SK chart with its movements", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the queued commit was not published")
+	}
+
+	cancel()
+	<-stopped
+}
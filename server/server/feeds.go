@@ -0,0 +1,248 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"spotify-charter/model"
+	"strings"
+	"time"
+)
+
+const (
+	atomContentType     = "application/atom+xml; charset=utf-8"
+	feedExtension       = ".atom"
+	feedWindowDays      = 30
+	feedMaxEntries      = 100
+	feedClimberMinClimb = 2
+	feedClimberPercent  = 20
+	feedIDPrefix        = "urn:spotify-charter:"
+)
+
+const (
+	highlightNumberOne = "number-one"
+	highlightNewEntry  = "new-entry"
+	highlightClimber   = "climber"
+)
+
+type atomFeed struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string       `xml:"id"`
+	Title   string       `xml:"title"`
+	Updated string       `xml:"updated"`
+	Author  atomPerson   `xml:"author"`
+	Links   []atomLink   `xml:"link"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Links      []atomLink     `xml:"link"`
+	Summary    string         `xml:"summary"`
+	Categories []atomCategory `xml:"category"`
+}
+
+func (s *Server) GetCountryFeed(w http.ResponseWriter, r *http.Request) {
+	code, ok := strings.CutSuffix(r.PathValue("feed"), feedExtension)
+	if !ok {
+		writeError(w, r, notFound("no feed at '%s', expected a country code followed by '%s'", r.URL.Path, feedExtension))
+		return
+	}
+
+	country := s.Reader.GetCountry(strings.ToUpper(code))
+	if country == nil {
+		writeError(w, r, notFound("country '%s' not found", code))
+		return
+	}
+
+	chartType, apiErr := parseChartType(r.URL.Query().Get("type"))
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	highlights := s.Reader.GetChartHighlights(chartType, feedSince(), country.Code, "", feedClimberMinClimb, feedClimberPercent, feedMaxEntries)
+
+	feed := &atomFeed{
+		ID:    feedIDPrefix + strings.Join([]string{"feeds", "countries", country.Code, string(chartType)}, ":"),
+		Title: fmt.Sprintf("%s chart highlights in %s", chartType, country.Name),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: feedURL(r, "/feeds/countries/"+country.Code)},
+			{Rel: "alternate", Type: jsonContentType, Href: baseURL(r) + "/v1/countries/" + country.Code + "/charts/" + string(chartType) + "/latest"},
+		},
+	}
+
+	s.writeFeed(w, r, feed, highlights, s.Reader.GetFeedUpdatedAt(chartType, country.Code, ""), false)
+}
+
+func (s *Server) GetArtistFeed(w http.ResponseWriter, r *http.Request) {
+	id, ok := strings.CutSuffix(r.PathValue("feed"), feedExtension)
+	if !ok {
+		writeError(w, r, notFound("no feed at '%s', expected an artist ID followed by '%s'", r.URL.Path, feedExtension))
+		return
+	}
+
+	artist := s.Reader.GetArtistExt(id)
+	if artist == nil {
+		writeError(w, r, notFound("artist '%s' not found", id))
+		return
+	}
+
+	chartType, apiErr := parseChartType(r.URL.Query().Get("type"))
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	highlights := s.Reader.GetChartHighlights(chartType, feedSince(), "", artist.ID, feedClimberMinClimb, feedClimberPercent, feedMaxEntries)
+
+	feed := &atomFeed{
+		ID:    feedIDPrefix + strings.Join([]string{"feeds", "artists", artist.ID, string(chartType)}, ":"),
+		Title: fmt.Sprintf("%s chart highlights of %s", chartType, artist.Name),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: feedURL(r, "/feeds/artists/"+url.PathEscape(artist.ID))},
+			{Rel: "alternate", Type: "text/html", Href: "https://open.spotify.com/artist/" + url.PathEscape(artist.ID)},
+		},
+	}
+
+	s.writeFeed(w, r, feed, highlights, s.Reader.GetFeedUpdatedAt(chartType, "", artist.ID), true)
+}
+
+func (s *Server) writeFeed(w http.ResponseWriter, r *http.Request, feed *atomFeed, highlights []*model.ChartHighlight, updated int64, withCountry bool) {
+	trackIDs := make([]string, 0, len(highlights))
+	for _, highlight := range highlights {
+		trackIDs = append(trackIDs, highlight.Movement.Track.SpotifyID)
+	}
+
+	artistIDs := s.Reader.GetArtistIDsByTrackIDs(trackIDs)
+
+	allArtistIDs := make([]string, 0)
+	for _, trackArtistIDs := range artistIDs {
+		allArtistIDs = append(allArtistIDs, trackArtistIDs...)
+	}
+
+	artists := s.Reader.GetArtistsExtByIDs(allArtistIDs)
+
+	feed.Author = atomPerson{Name: "spotify-charter"}
+	feed.Entries = make([]*atomEntry, 0, len(highlights))
+
+	for _, highlight := range highlights {
+		artistNames := make([]string, 0)
+		for _, artistID := range artistIDs[highlight.Movement.Track.SpotifyID] {
+			if artist, ok := artists[artistID]; ok {
+				artistNames = append(artistNames, artist.Name)
+			}
+		}
+
+		feed.Entries = append(feed.Entries, highlightEntry(highlight, artistNames, withCountry))
+	}
+
+	feed.Updated = atomTime(updated)
+
+	payload, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	payload = append([]byte(xml.Header), payload...)
+
+	if contentNotModified(w, r, payload, updated, revalidateCacheControl) {
+		return
+	}
+
+	writeBody(w, r, http.StatusOK, atomContentType, payload)
+}
+
+func highlightEntry(highlight *model.ChartHighlight, artistNames []string, withCountry bool) *atomEntry {
+	movement := highlight.Movement
+	date := time.Unix(highlight.Date, 0).UTC().Format(dateLayout)
+
+	kind := highlightClimber
+	if movement.Position == 0 {
+		kind = highlightNumberOne
+	} else if movement.IsNew() {
+		kind = highlightNewEntry
+	}
+
+	track := movement.Track.Name
+	if len(artistNames) != 0 {
+		track += " by " + strings.Join(artistNames, ", ")
+	}
+
+	var title string
+
+	switch kind {
+	case highlightNumberOne:
+		title = "New #1"
+	case highlightNewEntry:
+		title = fmt.Sprintf("New entry at #%d", movement.Position+1)
+	default:
+		title = fmt.Sprintf("Up %d to #%d", movement.Change(), movement.Position+1)
+	}
+
+	if withCountry {
+		title += " in " + highlight.Country.Name
+	}
+
+	previously := "a new entry"
+	if !movement.IsNew() {
+		previously = fmt.Sprintf("up from #%d", movement.PreviousPosition+1)
+	}
+
+	return &atomEntry{
+		ID:        feedIDPrefix + strings.Join([]string{kind, highlight.Country.Code, string(highlight.ChartType), date, movement.Track.SpotifyID}, ":"),
+		Title:     title + ": " + track,
+		Updated:   atomTime(highlight.UpdatedAt),
+		Published: atomTime(highlight.Date),
+		Links: []atomLink{
+			{Rel: "alternate", Type: "text/html", Href: "https://open.spotify.com/track/" + url.PathEscape(movement.Track.SpotifyID)},
+		},
+		Summary: fmt.Sprintf("%s is #%d on the %s chart of %s on %s, %s.",
+			track, movement.Position+1, highlight.ChartType, highlight.Country.Name, date, previously),
+		Categories: []atomCategory{{Term: kind}, {Term: highlight.Country.Code}},
+	}
+}
+
+func feedSince() int64 {
+	return model.TimeToDatestamp(time.Now().AddDate(0, 0, -feedWindowDays))
+}
+
+func atomTime(timestamp int64) string {
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
+
+func feedURL(r *http.Request, path string) string {
+	feedURL := baseURL(r) + path + feedExtension
+	if len(r.URL.RawQuery) != 0 {
+		feedURL += "?" + r.URL.RawQuery
+	}
+
+	return feedURL
+}
+
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
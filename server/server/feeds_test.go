@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedLastModifiedFollowsChartUpdates(t *testing.T) {
+	sqlDB := newTestDB(t)
+
+	reader := db.NewReader(sqlDB)
+	t.Cleanup(reader.Close)
+
+	routes := (&Server{Reader: reader}).Routes()
+
+	updates := []string{
+		"UPDATE chart_updates SET updated_at = 1792400000 WHERE country_code = 'SK'",
+		"UPDATE chart_updates SET updated_at = 1792500000 WHERE country_code = 'CZ'",
+	}
+
+	for _, query := range updates {
+		if _, err := sqlDB.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want int64
+	}{
+		{"/feeds/countries/SK.atom", 1792400000},
+		{"/feeds/countries/CZ.atom", 1792500000},
+		{"/feeds/artists/r1.atom", 1792500000},
+		{"/feeds/countries/SK.atom?type=WEEKLY_TOP_TRACK", 0},
+	}
+
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s responded with %d: %s", test.path, res.Code, res.Body)
+		}
+
+		want := ""
+		if test.want != 0 {
+			want = time.Unix(test.want, 0).UTC().Format(http.TimeFormat)
+		}
+
+		if lastModified := res.Header().Get("Last-Modified"); lastModified != want {
+			t.Errorf("%s has Last-Modified %q, want %q", test.path, lastModified, want)
+		}
+	}
+}
+
+func TestFeedHighlightsClimbersOfShortCharts(t *testing.T) {
+	sqlDB := newTestDB(t)
+
+	reader := db.NewReader(sqlDB)
+	t.Cleanup(reader.Close)
+
+	previousDate := model.TimeToDatestamp(time.Now().AddDate(0, 0, -2))
+	date := model.TimeToDatestamp(time.Now().AddDate(0, 0, -1))
+
+	charts := map[int64][]string{
+		previousDate: {"c0", "c1", "c2", "c3", "c4"},
+		date:         {"c0", "c4", "c3", "c1", "c2"},
+	}
+
+	writer := db.NewWriter(sqlDB)
+	writer.SaveCountry(&model.Country{Code: "AT", Name: "Austria"})
+
+	for date, trackIDs := range charts {
+		for position, trackID := range trackIDs {
+			err := writer.SaveChartTrack(&model.ChartTrack{
+				Country:   &model.Country{Code: "AT"},
+				Track:     &model.Track{SpotifyID: trackID, Name: strings.ToUpper(trackID), Album: model.Album{SpotifyID: "a0", Name: "Album Zero"}},
+				ChartType: model.DailyTopTrack,
+				Date:      date,
+				Position:  position,
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	writer.Commit()
+
+	res := httptest.NewRecorder()
+	(&Server{Reader: reader}).Routes().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/feeds/countries/AT.atom", nil))
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("feed responded with %d: %s", res.Code, res.Body)
+	}
+
+	feed := &atomFeed{}
+	if err := xml.Unmarshal(res.Body.Bytes(), feed); err != nil {
+		t.Fatal(err)
+	}
+
+	climbers := make([]string, 0)
+	for _, entry := range feed.Entries {
+		if entry.Categories[0].Term == highlightClimber {
+			climbers = append(climbers, entry.Title)
+		}
+	}
+
+	want := "Up 3 to #2: C4"
+	if len(climbers) != 1 || climbers[0] != want {
+		t.Errorf("got climbers %q, want only %q", climbers, want)
+	}
+}
@@ -0,0 +1,565 @@
+package server
+
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"spotify-charter/graphql"
+	"spotify-charter/model"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	graphQLMaxDepth         = 8
+	graphQLMaxFields        = 500
+	graphQLMaxBodyBytes     = 1 << 20
+	graphQLMaxQueryBytes    = 16 << 10
+	maxPersistedQueries     = 1024
+	persistedQueryNotFound  = "PERSISTED_QUERY_NOT_FOUND"
+	persistedQueryMismatch  = "PERSISTED_QUERY_HASH_MISMATCH"
+	graphQLDefaultChartType = string(model.DailyTopTrack)
+)
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+	Extensions    struct {
+		PersistedQuery *struct {
+			Version    int    `json:"version"`
+			SHA256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+type persistedQueries struct {
+	lock    sync.Mutex
+	queries map[string]*list.Element
+	recency *list.List
+}
+
+type persistedQuery struct {
+	hash  string
+	query string
+}
+
+type chartLoadKey struct {
+	chartType model.ChartType
+	date      int64
+	id        string
+	latest    bool
+}
+
+type graphQLLoaders struct {
+	countries     *graphql.Loader[string, *model.CountryExt]
+	tracks        *graphql.Loader[string, *model.TrackExt]
+	albums        *graphql.Loader[string, *model.AlbumExt]
+	artists       *graphql.Loader[string, *model.ArtistExt]
+	trackArtists  *graphql.Loader[string, []*model.ArtistExt]
+	audioFeatures *graphql.Loader[string, *model.AudioFeaturesExt]
+	countryCharts *graphql.Loader[chartLoadKey, []*model.ChartTrack]
+	artistCharts  *graphql.Loader[chartLoadKey, []*model.ChartTrack]
+}
+
+func (s *Server) ServeGraphQL(w http.ResponseWriter, r *http.Request) {
+	request := &graphQLRequest{}
+
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+
+		request.Query = query.Get("query")
+		request.OperationName = query.Get("operationName")
+
+		for name, target := range map[string]any{"variables": &request.Variables, "extensions": &request.Extensions} {
+			if param := query.Get(name); len(param) != 0 {
+				if err := json.Unmarshal([]byte(param), target); err != nil {
+					writeGraphQLError(w, r, http.StatusBadRequest, "invalid '"+name+"' parameter: "+err.Error(), "")
+					return
+				}
+			}
+		}
+	} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, graphQLMaxBodyBytes)).Decode(request); err != nil {
+		writeGraphQLError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error(), "")
+		return
+	}
+
+	if len(request.Query) > graphQLMaxQueryBytes {
+		writeGraphQLError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("query exceeds the maximum length of %d bytes", graphQLMaxQueryBytes), "")
+		return
+	}
+
+	if persisted := request.Extensions.PersistedQuery; persisted != nil {
+		if len(request.Query) == 0 {
+			query, ok := s.persisted.get(persisted.SHA256Hash)
+			if !ok {
+				writeGraphQLError(w, r, http.StatusOK, "PersistedQueryNotFound", persistedQueryNotFound)
+				return
+			}
+
+			request.Query = query
+		} else if !s.persisted.put(persisted.SHA256Hash, request.Query) {
+			writeGraphQLError(w, r, http.StatusBadRequest, "provided sha256Hash does not match the query", persistedQueryMismatch)
+			return
+		}
+	}
+
+	if len(request.Query) == 0 {
+		writeGraphQLError(w, r, http.StatusBadRequest, "missing query", "")
+		return
+	}
+
+	doc, err := graphql.Parse(request.Query, graphQLMaxDepth)
+	if err != nil {
+		writeGraphQLError(w, r, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), graphQLLoadersKey, s.newGraphQLLoaders())
+
+	writeJSON(w, r, http.StatusOK, s.graphQL.Execute(ctx, doc, request.OperationName, request.Variables))
+}
+
+func writeGraphQLError(w http.ResponseWriter, r *http.Request, status int, message string, code string) {
+	graphQLErr := &graphql.Error{Message: message}
+
+	if len(code) != 0 {
+		graphQLErr.Extensions = map[string]any{"code": code}
+	}
+
+	writeJSON(w, r, status, &graphql.Response{Errors: []*graphql.Error{graphQLErr}})
+}
+
+func newPersistedQueries() *persistedQueries {
+	return &persistedQueries{queries: make(map[string]*list.Element), recency: list.New()}
+}
+
+func (queries *persistedQueries) get(hash string) (string, bool) {
+	queries.lock.Lock()
+	defer queries.lock.Unlock()
+
+	element, ok := queries.queries[strings.ToLower(hash)]
+	if !ok {
+		return "", false
+	}
+
+	queries.recency.MoveToFront(element)
+
+	return element.Value.(*persistedQuery).query, true
+}
+
+func (queries *persistedQueries) put(hash string, query string) bool {
+	sum := sha256.Sum256([]byte(query))
+	if hex.EncodeToString(sum[:]) != strings.ToLower(hash) {
+		return false
+	}
+
+	hash = strings.ToLower(hash)
+
+	queries.lock.Lock()
+	defer queries.lock.Unlock()
+
+	if element, ok := queries.queries[hash]; ok {
+		queries.recency.MoveToFront(element)
+		return true
+	}
+
+	queries.queries[hash] = queries.recency.PushFront(&persistedQuery{hash: hash, query: query})
+
+	for queries.recency.Len() > maxPersistedQueries {
+		oldest := queries.recency.Back()
+		queries.recency.Remove(oldest)
+		delete(queries.queries, oldest.Value.(*persistedQuery).hash)
+	}
+
+	return true
+}
+
+func (s *Server) newGraphQLLoaders() *graphQLLoaders {
+	return &graphQLLoaders{
+		countries: graphql.NewLoader(func(codes []string) (map[string]*model.CountryExt, error) {
+			countries := make(map[string]*model.CountryExt)
+
+			for _, country := range s.Reader.GetCountries() {
+				countries[country.Code] = country
+			}
+
+			return countries, nil
+		}),
+		tracks: graphql.NewLoader(func(ids []string) (map[string]*model.TrackExt, error) {
+			return s.Reader.GetTracksExtByIDs(ids), nil
+		}),
+		albums: graphql.NewLoader(func(ids []string) (map[string]*model.AlbumExt, error) {
+			return s.Reader.GetAlbumsExtByIDs(ids), nil
+		}),
+		artists: graphql.NewLoader(func(ids []string) (map[string]*model.ArtistExt, error) {
+			return s.Reader.GetArtistsExtByIDs(ids), nil
+		}),
+		trackArtists: graphql.NewLoader(func(ids []string) (map[string][]*model.ArtistExt, error) {
+			artistIDs := s.Reader.GetArtistIDsByTrackIDs(ids)
+
+			allArtistIDs := make([]string, 0)
+			for _, trackArtistIDs := range artistIDs {
+				allArtistIDs = append(allArtistIDs, trackArtistIDs...)
+			}
+
+			artists := s.Reader.GetArtistsExtByIDs(allArtistIDs)
+
+			trackArtists := make(map[string][]*model.ArtistExt)
+			for trackID, trackArtistIDs := range artistIDs {
+				for _, artistID := range trackArtistIDs {
+					if artist, ok := artists[artistID]; ok {
+						trackArtists[trackID] = append(trackArtists[trackID], artist)
+					}
+				}
+			}
+
+			return trackArtists, nil
+		}),
+		audioFeatures: graphql.NewLoader(func(ids []string) (map[string]*model.AudioFeaturesExt, error) {
+			return s.Reader.GetAudioFeaturesByTrackIDs(ids), nil
+		}),
+		countryCharts: graphql.NewLoader(func(keys []chartLoadKey) (map[chartLoadKey][]*model.ChartTrack, error) {
+			return loadCharts(keys, s.Reader.GetLatestChartDates, s.Reader.GetChartEntriesByCountries), nil
+		}),
+		artistCharts: graphql.NewLoader(func(keys []chartLoadKey) (map[chartLoadKey][]*model.ChartTrack, error) {
+			return loadCharts(keys, s.latestGlobalChartDates, s.Reader.GetChartEntriesByArtists), nil
+		}),
+	}
+}
+
+func loadCharts(keys []chartLoadKey, latest func(model.ChartType, []string) map[string]int64,
+	load func(model.ChartType, int64, []string) map[string][]*model.ChartTrack) map[chartLoadKey][]*model.ChartTrack {
+	type period struct {
+		chartType model.ChartType
+		date      int64
+	}
+
+	latestIDs := make(map[model.ChartType][]string)
+
+	for _, key := range keys {
+		if key.latest {
+			latestIDs[key.chartType] = append(latestIDs[key.chartType], key.id)
+		}
+	}
+
+	latestDates := make(map[model.ChartType]map[string]int64)
+
+	for chartType, typeIDs := range latestIDs {
+		latestDates[chartType] = latest(chartType, typeIDs)
+	}
+
+	ids := make(map[period][]string)
+	periods := make(map[chartLoadKey]period)
+
+	for _, key := range keys {
+		keyPeriod := period{key.chartType, key.date}
+
+		if key.latest {
+			date, ok := latestDates[key.chartType][key.id]
+			if !ok {
+				continue
+			}
+
+			keyPeriod.date = date
+		}
+
+		ids[keyPeriod] = append(ids[keyPeriod], key.id)
+		periods[key] = keyPeriod
+	}
+
+	loaded := make(map[period]map[string][]*model.ChartTrack)
+
+	for period, periodIDs := range ids {
+		loaded[period] = load(period.chartType, period.date, periodIDs)
+	}
+
+	charts := make(map[chartLoadKey][]*model.ChartTrack)
+
+	for key, keyPeriod := range periods {
+		if chartTracks, ok := loaded[keyPeriod][key.id]; ok {
+			charts[key] = chartTracks
+		}
+	}
+
+	return charts
+}
+
+func loaders(ctx context.Context) *graphQLLoaders {
+	return ctx.Value(graphQLLoadersKey).(*graphQLLoaders)
+}
+
+func (s *Server) latestGlobalChartDates(chartType model.ChartType, ids []string) map[string]int64 {
+	dates := make(map[string]int64)
+
+	if date := s.Reader.GetLatestChartDate(chartType, ""); date != 0 {
+		for _, id := range ids {
+			dates[id] = date
+		}
+	}
+
+	return dates
+}
+
+func (s *Server) chartArgs(args map[string]any, id string) (chartLoadKey, error) {
+	chartTypeArg, _ := args["type"].(string)
+	dateArg, _ := args["date"].(string)
+
+	chartType, apiErr := parseChartType(strings.ToUpper(chartTypeArg))
+	if apiErr != nil {
+		return chartLoadKey{}, apiErr
+	}
+
+	if len(dateArg) == 0 || dateArg == "latest" {
+		return chartLoadKey{chartType: chartType, id: id, latest: true}, nil
+	}
+
+	date, apiErr := s.chartDate(dateArg, chartType, "")
+	if apiErr != nil {
+		return chartLoadKey{}, apiErr
+	}
+
+	return chartLoadKey{chartType: chartType, date: date, id: id}, nil
+}
+
+func marketTrackID(track *model.TrackExt) string {
+	return cmp.Or(track.MarketTrackID, track.ID)
+}
+
+func scalar[T any](resolve func(source T) any) *graphql.FieldDef {
+	return &graphql.FieldDef{
+		Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return resolve(source.(T)), nil
+		},
+	}
+}
+
+func (s *Server) newGraphQLSchema() *graphql.Schema {
+	query := &graphql.Object{Name: "Query"}
+	country := &graphql.Object{Name: "Country"}
+	chartEntry := &graphql.Object{Name: "ChartEntry"}
+	track := &graphql.Object{Name: "Track"}
+	album := &graphql.Object{Name: "Album"}
+	image := &graphql.Object{Name: "Image"}
+	artist := &graphql.Object{Name: "Artist"}
+	audioFeatures := &graphql.Object{Name: "AudioFeatures"}
+
+	chartFieldArgs := map[string]any{"type": graphQLDefaultChartType, "date": "latest"}
+
+	query.Fields = map[string]*graphql.FieldDef{
+		"countries": {
+			Type: country, List: true,
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return s.Reader.GetCountries(), nil
+			},
+		},
+		"country": {
+			Type: country, Args: map[string]any{"code": nil},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				code, _ := args["code"].(string)
+
+				return loaders(ctx).countries.Load(strings.ToUpper(code)), nil
+			},
+		},
+		"chart": {
+			Type: chartEntry, List: true, Args: map[string]any{"type": graphQLDefaultChartType, "date": "latest", "country": model.GlobalCountryCode},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				code, _ := args["country"].(string)
+				code = strings.ToUpper(code)
+
+				key, err := s.chartArgs(args, code)
+				if err != nil {
+					return nil, err
+				}
+
+				return loaders(ctx).countryCharts.Load(key), nil
+			},
+		},
+		"track": {
+			Type: track, Args: map[string]any{"id": nil},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				id, _ := args["id"].(string)
+
+				return loaders(ctx).tracks.Load(id), nil
+			},
+		},
+		"album": {
+			Type: album, Args: map[string]any{"id": nil},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				id, _ := args["id"].(string)
+
+				return loaders(ctx).albums.Load(id), nil
+			},
+		},
+		"artist": {
+			Type: artist, Args: map[string]any{"id": nil},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				id, _ := args["id"].(string)
+
+				return loaders(ctx).artists.Load(id), nil
+			},
+		},
+	}
+
+	country.Fields = map[string]*graphql.FieldDef{
+		"code": scalar(func(country *model.CountryExt) any { return country.Code }),
+		"name": scalar(func(country *model.CountryExt) any { return country.Name }),
+		"chart": {
+			Type: chartEntry, List: true, Args: chartFieldArgs,
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				code := source.(*model.CountryExt).Code
+
+				key, err := s.chartArgs(args, code)
+				if err != nil {
+					return nil, err
+				}
+
+				return loaders(ctx).countryCharts.Load(key), nil
+			},
+		},
+	}
+
+	chartEntry.Fields = map[string]*graphql.FieldDef{
+		"chartType": scalar(func(chartTrack *model.ChartTrack) any { return chartTrack.ChartType }),
+		"date":      scalar(func(chartTrack *model.ChartTrack) any { return time.Unix(chartTrack.Date, 0).UTC().Format(dateLayout) }),
+		"position":  scalar(func(chartTrack *model.ChartTrack) any { return chartTrack.Position + 1 }),
+		"streams": scalar(func(chartTrack *model.ChartTrack) any {
+			if chartTrack.Streams == 0 {
+				return nil
+			}
+
+			return chartTrack.Streams
+		}),
+		"country": {
+			Type: country,
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return loaders(ctx).countries.Load(source.(*model.ChartTrack).Country.Code), nil
+			},
+		},
+		"track": {
+			Type: track,
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				chartTrack := source.(*model.ChartTrack)
+
+				load := loaders(ctx).tracks.Load(chartTrack.Track.SpotifyID)
+				if chartTrack.Track.LinkedFromID == chartTrack.Track.SpotifyID {
+					return load, nil
+				}
+
+				return graphql.Thunk(func() (any, error) {
+					value, err := load()
+
+					track, _ := value.(*model.TrackExt)
+					if err != nil || track == nil {
+						return value, err
+					}
+
+					canonical := *track
+					canonical.MarketTrackID, canonical.ID = track.ID, chartTrack.Track.LinkedFromID
+
+					return &canonical, nil
+				}), nil
+			},
+		},
+	}
+
+	track.Fields = map[string]*graphql.FieldDef{
+		"id":            scalar(func(track *model.TrackExt) any { return track.ID }),
+		"name":          scalar(func(track *model.TrackExt) any { return track.Name }),
+		"durationMs":    scalar(func(track *model.TrackExt) any { return track.DurationMs }),
+		"explicit":      scalar(func(track *model.TrackExt) any { return track.Explicit }),
+		"popularity":    scalar(func(track *model.TrackExt) any { return track.Popularity }),
+		"discNumber":    scalar(func(track *model.TrackExt) any { return track.DiscNumber }),
+		"trackNumber":   scalar(func(track *model.TrackExt) any { return track.TrackNumber }),
+		"isrc":          scalar(func(track *model.TrackExt) any { return nullString(track.ISRC) }),
+		"previewUrl":    scalar(func(track *model.TrackExt) any { return nullString(track.PreviewURL) }),
+		"marketTrackId": scalar(func(track *model.TrackExt) any { return nullString(track.MarketTrackID) }),
+		"album": {
+			Type: album,
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return loaders(ctx).albums.Load(source.(*model.TrackExt).Album.ID), nil
+			},
+		},
+		"artists": {
+			Type: artist, List: true,
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return loaders(ctx).trackArtists.Load(marketTrackID(source.(*model.TrackExt))), nil
+			},
+		},
+		"audioFeatures": {
+			Type: audioFeatures,
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return loaders(ctx).audioFeatures.Load(marketTrackID(source.(*model.TrackExt))), nil
+			},
+		},
+	}
+
+	album.Fields = map[string]*graphql.FieldDef{
+		"id":                   scalar(func(album *model.AlbumExt) any { return album.ID }),
+		"name":                 scalar(func(album *model.AlbumExt) any { return album.Name }),
+		"releaseDate":          scalar(func(album *model.AlbumExt) any { return nullString(album.ReleaseDate) }),
+		"releaseDatePrecision": scalar(func(album *model.AlbumExt) any { return nullString(album.ReleaseDatePrecision) }),
+		"albumType":            scalar(func(album *model.AlbumExt) any { return nullString(album.AlbumType) }),
+		"totalTracks":          scalar(func(album *model.AlbumExt) any { return album.TotalTracks }),
+		"label":                scalar(func(album *model.AlbumExt) any { return nullString(album.Label) }),
+		"images": {
+			Type: image, List: true,
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return source.(*model.AlbumExt).Images, nil
+			},
+		},
+	}
+
+	image.Fields = map[string]*graphql.FieldDef{
+		"url":   scalar(func(image model.ImageExt) any { return image.URL }),
+		"width": scalar(func(image model.ImageExt) any { return image.Width }),
+	}
+
+	artist.Fields = map[string]*graphql.FieldDef{
+		"id":     scalar(func(artist *model.ArtistExt) any { return artist.ID }),
+		"name":   scalar(func(artist *model.ArtistExt) any { return artist.Name }),
+		"genres": scalar(func(artist *model.ArtistExt) any { return artist.Genres }),
+		"charts": {
+			Type: chartEntry, List: true, Args: chartFieldArgs,
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				key, err := s.chartArgs(args, source.(*model.ArtistExt).ID)
+				if err != nil {
+					return nil, err
+				}
+
+				return loaders(ctx).artistCharts.Load(key), nil
+			},
+		},
+	}
+
+	audioFeatures.Fields = map[string]*graphql.FieldDef{
+		"tempo":            scalar(func(features *model.AudioFeaturesExt) any { return features.Tempo }),
+		"key":              scalar(func(features *model.AudioFeaturesExt) any { return features.Key }),
+		"mode":             scalar(func(features *model.AudioFeaturesExt) any { return features.Mode }),
+		"timeSignature":    scalar(func(features *model.AudioFeaturesExt) any { return features.TimeSignature }),
+		"energy":           scalar(func(features *model.AudioFeaturesExt) any { return features.Energy }),
+		"danceability":     scalar(func(features *model.AudioFeaturesExt) any { return features.Danceability }),
+		"valence":          scalar(func(features *model.AudioFeaturesExt) any { return features.Valence }),
+		"acousticness":     scalar(func(features *model.AudioFeaturesExt) any { return features.Acousticness }),
+		"instrumentalness": scalar(func(features *model.AudioFeaturesExt) any { return features.Instrumentalness }),
+		"liveness":         scalar(func(features *model.AudioFeaturesExt) any { return features.Liveness }),
+		"speechiness":      scalar(func(features *model.AudioFeaturesExt) any { return features.Speechiness }),
+		"loudness":         scalar(func(features *model.AudioFeaturesExt) any { return features.Loudness }),
+	}
+
+	return &graphql.Schema{Query: query, MaxDepth: graphQLMaxDepth, MaxFields: graphQLMaxFields}
+}
+
+func nullString(value string) any {
+	if len(value) == 0 {
+		return nil
+	}
+
+	return value
+}
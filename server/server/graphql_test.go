@@ -0,0 +1,187 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"strings"
+	"testing"
+)
+
+func persistedQueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPersistedQueriesEvictOldest(t *testing.T) {
+	queries := newPersistedQueries()
+
+	for index := 0; index < maxPersistedQueries; index++ {
+		query := fmt.Sprintf("{ q%d: countries { code } }", index)
+
+		if !queries.put(persistedQueryHash(query), query) {
+			t.Fatalf("put(%q) rejected a matching hash", query)
+		}
+	}
+
+	if queries.put(persistedQueryHash("{ countries { code } }"), "{ countries { name } }") {
+		t.Errorf("put accepted a hash that does not match the query")
+	}
+
+	if _, ok := queries.get(strings.ToUpper(persistedQueryHash("{ q0: countries { code } }"))); !ok {
+		t.Fatalf("get did not find the first query")
+	}
+
+	for index := 0; index < 2; index++ {
+		query := fmt.Sprintf("{ extra%d: countries { code } }", index)
+		queries.put(persistedQueryHash(query), query)
+	}
+
+	tests := []struct {
+		query string
+		found bool
+	}{
+		{"{ q0: countries { code } }", true},
+		{"{ q1: countries { code } }", false},
+		{"{ q2: countries { code } }", false},
+		{"{ q3: countries { code } }", true},
+		{fmt.Sprintf("{ q%d: countries { code } }", maxPersistedQueries-1), true},
+		{"{ extra0: countries { code } }", true},
+		{"{ extra1: countries { code } }", true},
+	}
+
+	for _, test := range tests {
+		query, ok := queries.get(persistedQueryHash(test.query))
+		if ok != test.found || (ok && query != test.query) {
+			t.Errorf("get(%q) returned %q, %t, want found %t", test.query, query, ok, test.found)
+		}
+	}
+
+	if queries.recency.Len() != maxPersistedQueries || len(queries.queries) != maxPersistedQueries {
+		t.Errorf("kept %d queries, want %d", len(queries.queries), maxPersistedQueries)
+	}
+}
+
+func TestGraphQLLimitsAliasedFields(t *testing.T) {
+	sqlDB := newTestDB(t)
+
+	reader := db.NewReader(sqlDB)
+	t.Cleanup(reader.Close)
+
+	routes := (&Server{Reader: reader}).Routes()
+
+	aliased := func(count int) string {
+		var query strings.Builder
+
+		query.WriteString("{")
+		for index := 0; index < count; index++ {
+			fmt.Fprintf(&query, " c%d: countries { chart { track { artists { charts { position } } } } }", index)
+		}
+		query.WriteString(" }")
+
+		return query.String()
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{"few aliases", aliased(2), ""},
+		{"many aliases", aliased(100), fmt.Sprintf("query selects more than the maximum of %d fields", graphQLMaxFields)},
+		{"fragment fan-out", "fragment f on Country { code name } { countries { " + strings.Repeat("...f ", graphQLMaxFields) + "} }", fmt.Sprintf("query selects more than the maximum of %d fields", graphQLMaxFields)},
+		{"deep nesting", strings.Repeat("{ a ", 1000) + strings.Repeat("}", 1000), fmt.Sprintf("syntax error on line 1: nesting exceeds the maximum depth of %d", graphQLMaxDepth)},
+		{"deep list argument", "{ countries(x: " + strings.Repeat("[", 1000) + strings.Repeat("]", 1000) + ") { code } }", fmt.Sprintf("syntax error on line 1: nesting exceeds the maximum depth of %d", graphQLMaxDepth)},
+		{"long query", "{ countries { code " + strings.Repeat("name ", graphQLMaxQueryBytes/5) + "} }", fmt.Sprintf("query exceeds the maximum length of %d bytes", graphQLMaxQueryBytes)},
+	}
+
+	for _, test := range tests {
+		body, _ := json.Marshal(map[string]any{"query": test.query})
+
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+		var response struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+
+		if err := json.Unmarshal(res.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		if len(test.err) == 0 {
+			if len(response.Errors) != 0 || response.Data == nil {
+				t.Errorf("%s: got %s, want data without errors", test.name, res.Body)
+			}
+
+			continue
+		}
+
+		if response.Data != nil || len(response.Errors) != 1 || response.Errors[0].Message != test.err {
+			t.Errorf("%s: got %s, want only the error %q", test.name, res.Body, test.err)
+		}
+	}
+}
+
+func TestLoadChartsResolvesLatestDatesOncePerType(t *testing.T) {
+	keys := []chartLoadKey{
+		{chartType: model.DailyTopTrack, id: "SK", latest: true},
+		{chartType: model.DailyTopTrack, id: "CZ", latest: true},
+		{chartType: model.DailyTopTrack, id: "AT", latest: true},
+		{chartType: model.DailyTopTrack, date: 100, id: "SK"},
+		{chartType: model.WeeklyTopTrack, id: "SK", latest: true},
+	}
+
+	latestCalls := make([]string, 0)
+	loadCalls := make([]string, 0)
+
+	latest := func(chartType model.ChartType, ids []string) map[string]int64 {
+		latestCalls = append(latestCalls, fmt.Sprintf("%s:%d", chartType, len(ids)))
+
+		if chartType == model.WeeklyTopTrack {
+			return map[string]int64{"SK": 50}
+		}
+
+		return map[string]int64{"SK": 200, "CZ": 200}
+	}
+
+	load := func(chartType model.ChartType, date int64, ids []string) map[string][]*model.ChartTrack {
+		loadCalls = append(loadCalls, fmt.Sprintf("%s:%d:%d", chartType, date, len(ids)))
+
+		charts := make(map[string][]*model.ChartTrack)
+		for _, id := range ids {
+			charts[id] = []*model.ChartTrack{{ChartType: chartType, Date: date}}
+		}
+
+		return charts
+	}
+
+	charts := loadCharts(keys, latest, load)
+
+	if len(latestCalls) != 2 {
+		t.Errorf("resolved latest dates with %v, want one call per chart type", latestCalls)
+	}
+
+	if len(loadCalls) != 3 {
+		t.Errorf("loaded charts with %v, want one call per chart type and date", loadCalls)
+	}
+
+	wantDates := map[chartLoadKey]int64{keys[0]: 200, keys[1]: 200, keys[3]: 100, keys[4]: 50}
+
+	for _, key := range keys {
+		chart, ok := charts[key]
+		if want, loaded := wantDates[key]; loaded != ok || (ok && chart[0].Date != want) {
+			t.Errorf("chart for %+v is %v, want date %d (loaded %t)", key, chart, want, loaded)
+		}
+	}
+}
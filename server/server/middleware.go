@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	graphQLLoadersKey
+)
+
+const requestIDHeader = "X-Request-ID"
+
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if len(id) == 0 || len(id) > 64 {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func withRecovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recovery := &recoveryWriter{ResponseWriter: w}
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			log.Printf("[%s] panic serving %s %s: %v\n%s", requestID(r), r.Method, r.URL.Path, rec, debug.Stack())
+
+			if recovery.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+
+			writeError(w, r, internalError())
+		}()
+
+		next.ServeHTTP(recovery, r)
+	})
+}
+
+func (w *recoveryWriter) WriteHeader(status int) {
+	if status >= http.StatusOK || status == http.StatusSwitchingProtocols {
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recoveryWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recoveryWriter) FlushError() error {
+	w.wroteHeader = true
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *recoveryWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func withAdminToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(token) == 0 {
+			writeError(w, r, forbidden("administration is disabled, no admin token is configured"))
+			return
+		}
+
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="spotify-charter"`)
+			writeError(w, r, unauthorized("a valid admin bearer token is required"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func withMethod(r *http.Request, method string) *http.Request {
+	clone := r.Clone(r.Context())
+	clone.Method = method
+
+	return clone
+}
+
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey).(string)
+
+	return id
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoveryWritesErrorEnvelope(t *testing.T) {
+	handler := withRequestID(withRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"stale"`)
+		panic("boom")
+	})))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/v1/charts", nil))
+
+	if res.Code != http.StatusInternalServerError {
+		t.Fatalf("a panicking handler responded with %d, want 500", res.Code)
+	}
+
+	var body envelope
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not a JSON envelope: %s", res.Body)
+	}
+
+	if body.Error == nil || body.Error.Code != "internal_error" || len(body.Error.RequestID) == 0 {
+		t.Fatalf("got %s, want an internal_error envelope with a request ID", res.Body)
+	}
+
+	if body.Error.RequestID != res.Header().Get(requestIDHeader) {
+		t.Errorf("envelope has request ID %q, the %s header is %q", body.Error.RequestID, requestIDHeader, res.Header().Get(requestIDHeader))
+	}
+
+	if etag := res.Header().Get("ETag"); len(etag) != 0 {
+		t.Errorf("error response kept the ETag %s", etag)
+	}
+}
+
+func TestRecoveryAbortsStartedResponses(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w http.ResponseWriter)
+	}{
+		{"header written", func(w http.ResponseWriter) { w.WriteHeader(http.StatusOK) }},
+		{"body written", func(w http.ResponseWriter) { w.Write([]byte("data: {}\n\n")) }},
+		{"flushed", func(w http.ResponseWriter) { http.NewResponseController(w).Flush() }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := withRequestID(withRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				test.write(w)
+				panic("boom")
+			})))
+
+			res := httptest.NewRecorder()
+
+			func() {
+				defer func() {
+					if rec := recover(); rec != http.ErrAbortHandler {
+						t.Errorf("recovered %v, want the handler to be aborted", rec)
+					}
+				}()
+
+				handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/v1/events", nil))
+			}()
+
+			if res.Code != http.StatusOK || strings.Contains(res.Body.String(), "internal_error") {
+				t.Errorf("started response ended with %d and body %q, want it left as written", res.Code, res.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,452 @@
+package server
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"spotify-charter/model"
+	"strconv"
+	"strings"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+type route struct {
+	method      string
+	path        string
+	handler     http.HandlerFunc
+	summary     string
+	params      []param
+	request     any
+	response    any
+	status      int
+	raw         bool
+	contentType string
+	conditional bool
+	deprecated  bool
+	admin       bool
+}
+
+type param struct {
+	name        string
+	in          string
+	kind        string
+	description string
+}
+
+var (
+	codeParam      = param{"code", "path", "string", "ISO 3166-1 alpha-2 country code, AA for global charts"}
+	typeParam      = param{"type", "path", "chart_type", "Chart type"}
+	dateParam      = param{"date", "path", "date", "Chart date as YYYY-MM-DD or 'latest' for the newest stored chart, normalized to the start of the chart period"}
+	idParam        = param{"id", "path", "string", "Spotify ID"}
+	typeQuery      = param{"type", "query", "chart_type", "Chart type, defaults to DAILY_TOP_TRACK"}
+	dateQuery      = param{"date", "query", "date", "Chart date as YYYY-MM-DD, defaults to today"}
+	fromQuery      = param{"from", "query", "date", "Start of the range as YYYY-MM-DD"}
+	toQuery        = param{"to", "query", "date", "End of the range as YYYY-MM-DD, defaults to today"}
+	countryQuery   = param{"country", "query", "string", "Country code, defaults to AA"}
+	countriesQuery = param{"country", "query", "string", "Comma-separated country codes to filter chart events by, defaults to every country"}
+	webhookParam   = param{"id", "path", "integer", "Webhook ID"}
+	deliveryParam  = param{"delivery", "path", "integer", "Delivery ID"}
+	eventIDHeader  = param{"Last-Event-ID", "header", "integer", "ID of the last received event, missed events are replayed first or a history.gap event is sent when they are no longer kept"}
+	countryFeed    = param{"feed", "path", "string", "Country code followed by .atom, e.g. SK.atom"}
+	artistFeed     = param{"feed", "path", "string", "Spotify artist ID followed by .atom"}
+	pathParamRegex = regexp.MustCompile(`{([^}]+)}`)
+)
+
+func (s *Server) routes() []route {
+	return []route{
+		{
+			method: "GET", path: "/test", handler: s.GetPlaylists,
+			summary:  "Charts of every country for a date",
+			params:   []param{typeQuery, dateQuery},
+			response: model.ChartTracksExt{}, raw: true, conditional: true, deprecated: true,
+		},
+		{
+			method: "GET", path: "/v1/countries", handler: s.GetCountries,
+			summary:  "List countries",
+			response: []*model.CountryExt{},
+		},
+		{
+			method: "GET", path: "/v1/countries/{code}", handler: s.GetCountry,
+			summary:  "Get a country and its chart types",
+			params:   []param{codeParam},
+			response: &model.CountryExt{},
+		},
+		{
+			method: "GET", path: "/v1/countries/{code}/charts/{type}/{date}", handler: s.GetCountryChart,
+			summary:  "Get the chart of a country",
+			params:   []param{codeParam, typeParam, dateParam},
+			response: []*model.TrackExt{}, conditional: true,
+		},
+		{
+			method: "GET", path: "/v1/charts/{type}/{date}", handler: s.GetChart,
+			summary:  "Get the charts of every country",
+			params:   []param{typeParam, dateParam},
+			response: model.ChartTracksExt{}, conditional: true,
+		},
+		{
+			method: "GET", path: "/v1/tracks/{id}", handler: s.GetTrack,
+			summary:  "Get a track",
+			params:   []param{idParam},
+			response: &model.TrackExt{},
+		},
+		{
+			method: "GET", path: "/v1/albums/{id}", handler: s.GetAlbum,
+			summary:  "Get an album",
+			params:   []param{idParam},
+			response: &model.AlbumExt{},
+		},
+		{
+			method: "GET", path: "/v1/artists/{id}", handler: s.GetArtist,
+			summary:  "Get an artist",
+			params:   []param{idParam},
+			response: &model.ArtistExt{},
+		},
+		{
+			method: "GET", path: "/v1/status", handler: s.GetStatus,
+			summary:  "List chart sources and their health",
+			response: []*model.ChartSourceExt{},
+		},
+		{
+			method: "GET", path: "/v1/sound-profiles", handler: s.GetSoundProfiles,
+			summary:  "Average audio features per country and day",
+			params:   []param{typeQuery, fromQuery, toQuery},
+			response: []*model.SoundProfileExt{},
+		},
+		{
+			method: "GET", path: "/v1/streams/artists", handler: s.GetArtistStreams,
+			summary:  "Weekly streams per artist",
+			params:   []param{typeQuery, fromQuery, toQuery, countryQuery},
+			response: []*model.ArtistStreamsExt{},
+		},
+		{
+			method: "GET", path: "/v1/streams/countries", handler: s.GetCountryStreams,
+			summary:  "Streams per country",
+			params:   []param{typeQuery, fromQuery, toQuery},
+			response: []*model.CountryStreamsExt{},
+		},
+		{
+			method: "GET", path: "/v1/metrics", handler: s.GetMetrics,
+			summary:  "Server metrics",
+			response: &Metrics{},
+		},
+		{
+			method: "GET", path: "/v1/events", handler: s.GetEvents,
+			summary:  "Stream chart and ingestion events as Server-Sent Events",
+			params:   []param{countriesQuery, eventIDHeader},
+			response: &model.EventExt{}, contentType: eventStreamContentType,
+		},
+		{
+			method: "GET", path: "/v1/webhooks", handler: s.GetWebhooks,
+			summary:  "List webhook subscriptions",
+			response: []*model.WebhookExt{}, admin: true,
+		},
+		{
+			method: "POST", path: "/v1/webhooks", handler: s.CreateWebhook,
+			summary: "Subscribe to track.entered, country.number_one or artist.number_one events, the secret is only returned here",
+			request: &model.WebhookRequestExt{}, response: &model.WebhookExt{}, status: http.StatusCreated, admin: true,
+		},
+		{
+			method: "GET", path: "/v1/webhooks/{id}", handler: s.GetWebhook,
+			summary:  "Get a webhook subscription",
+			params:   []param{webhookParam},
+			response: &model.WebhookExt{}, admin: true,
+		},
+		{
+			method: "DELETE", path: "/v1/webhooks/{id}", handler: s.DeleteWebhook,
+			summary:  "Delete a webhook subscription",
+			params:   []param{webhookParam},
+			response: &model.WebhookExt{}, admin: true,
+		},
+		{
+			method: "GET", path: "/v1/webhooks/{id}/deliveries", handler: s.GetWebhookDeliveries,
+			summary:  "Recent deliveries of a webhook and their attempts",
+			params:   []param{webhookParam},
+			response: []*model.WebhookDeliveryExt{}, admin: true,
+		},
+		{
+			method: "POST", path: "/v1/webhooks/{id}/deliveries/{delivery}/replay", handler: s.ReplayWebhookDelivery,
+			summary:  "Deliver the payload of a past delivery again",
+			params:   []param{webhookParam, deliveryParam},
+			response: &model.WebhookDeliveryExt{}, status: http.StatusAccepted, admin: true,
+		},
+		{
+			method: "GET", path: "/feeds/countries/{feed}", handler: s.GetCountryFeed,
+			summary:  "Atom feed of new #1s, new entries and big climbers of a country over the last 30 days",
+			params:   []param{countryFeed, typeQuery},
+			response: "", contentType: atomContentType, conditional: true,
+		},
+		{
+			method: "GET", path: "/feeds/artists/{feed}", handler: s.GetArtistFeed,
+			summary:  "Atom feed of new #1s, new entries and big climbers of an artist's tracks over the last 30 days",
+			params:   []param{artistFeed, typeQuery},
+			response: "", contentType: atomContentType, conditional: true,
+		},
+	}
+}
+
+func (s *Server) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.openAPI)
+}
+
+func (s *Server) GetDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	w.Write(docsPage)
+}
+
+func buildOpenAPI(routes []route) map[string]any {
+	schemas := make(map[string]any)
+	paths := make(map[string]any)
+
+	schemas["Error"] = object(map[string]any{"error": schemaFor(reflect.TypeOf(errorResponse{}), schemas)}, []string{"error"})
+
+	errorResp := map[string]any{
+		"description": "Error",
+		"content":     map[string]any{jsonContentType: map[string]any{"schema": ref("Error")}},
+	}
+
+	for _, route := range routes {
+		validateRoute(route)
+
+		schema := schemaFor(reflect.TypeOf(route.response), schemas)
+		contentType := route.contentType
+		if len(contentType) == 0 {
+			contentType = jsonContentType
+		}
+
+		if !route.raw && contentType == jsonContentType {
+			schema = object(map[string]any{"data": schema}, []string{"data"})
+		}
+
+		status := http.StatusOK
+		if route.status != 0 {
+			status = route.status
+		}
+
+		responses := map[string]any{
+			strconv.Itoa(status): map[string]any{
+				"description": http.StatusText(status),
+				"content":     map[string]any{contentType: map[string]any{"schema": schema}},
+			},
+			"400": errorResp,
+			"404": errorResp,
+			"500": errorResp,
+		}
+
+		if route.conditional {
+			responses["304"] = map[string]any{"description": "Not modified since the ETag or date sent by the client"}
+		}
+
+		parameters := make([]any, 0, len(route.params))
+
+		for _, param := range route.params {
+			parameters = append(parameters, map[string]any{
+				"name":        param.name,
+				"in":          param.in,
+				"required":    param.in == "path",
+				"description": param.description,
+				"schema":      paramSchema(param.kind),
+			})
+		}
+
+		operation := map[string]any{
+			"summary":    route.summary,
+			"parameters": parameters,
+			"responses":  responses,
+		}
+
+		if route.request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					jsonContentType: map[string]any{"schema": schemaFor(reflect.TypeOf(route.request), schemas)},
+				},
+			}
+		}
+
+		if route.deprecated {
+			operation["deprecated"] = true
+		}
+
+		if route.admin {
+			operation["security"] = []any{map[string]any{"adminToken": []any{}}}
+			responses["401"] = errorResp
+			responses["403"] = errorResp
+			responses["503"] = errorResp
+		}
+
+		pathItem, ok := paths[route.path].(map[string]any)
+		if !ok {
+			pathItem = make(map[string]any)
+			paths[route.path] = pathItem
+		}
+
+		pathItem[strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "spotify-charter",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"adminToken": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func validateRoute(route route) {
+	declared := make(map[string]bool)
+
+	for _, param := range route.params {
+		if param.in == "path" {
+			declared[param.name] = true
+		}
+	}
+
+	for _, match := range pathParamRegex.FindAllStringSubmatch(route.path, -1) {
+		if !declared[match[1]] {
+			panic(fmt.Sprintf("route '%s' does not declare path parameter '%s'", route.path, match[1]))
+		}
+
+		delete(declared, match[1])
+	}
+
+	for name := range declared {
+		panic(fmt.Sprintf("route '%s' declares unknown path parameter '%s'", route.path, name))
+	}
+
+	if route.response == nil {
+		panic(fmt.Sprintf("route '%s' does not declare a response", route.path))
+	}
+}
+
+func paramSchema(kind string) map[string]any {
+	switch kind {
+	case "date":
+		return map[string]any{"type": "string", "pattern": `^(\d{4}-\d{2}-\d{2}|latest)$`}
+	case "chart_type":
+		return chartTypeSchema()
+	case "integer":
+		return map[string]any{"type": "integer", "format": "int64"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+func chartTypeSchema() map[string]any {
+	chartTypes := make([]string, 0)
+
+	for _, chartType := range append(model.ChartTypes, model.AggregateChartTypes...) {
+		chartTypes = append(chartTypes, string(chartType))
+	}
+
+	return map[string]any{"type": "string", "enum": chartTypes}
+}
+
+func schemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	if t == reflect.TypeOf(model.ChartType("")) {
+		return chartTypeSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := schemaFor(t.Elem(), schemas)
+		if _, ok := schema["$ref"]; ok {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+
+		schema["nullable"] = true
+
+		return schema
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Uint, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Struct:
+		return structSchema(t, schemas)
+	default:
+		panic(fmt.Sprintf("no OpenAPI schema for type '%s'", t))
+	}
+}
+
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	name := strings.TrimSuffix(t.Name(), "Ext")
+
+	if len(name) != 0 {
+		name = strings.ToUpper(name[:1]) + name[1:]
+
+		if _, ok := schemas[name]; ok {
+			return ref(name)
+		}
+
+		schemas[name] = nil
+	}
+
+	properties := make(map[string]any)
+	required := make([]string, 0)
+
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		fieldName, options, _ := strings.Cut(tag, ",")
+		if len(fieldName) == 0 {
+			fieldName = field.Name
+		}
+
+		properties[fieldName] = schemaFor(field.Type, schemas)
+
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, fieldName)
+		}
+	}
+
+	schema := object(properties, required)
+
+	if len(name) == 0 {
+		return schema
+	}
+
+	schemas[name] = schema
+
+	return ref(name)
+}
+
+func object(properties map[string]any, required []string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+
+	if len(required) != 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
@@ -0,0 +1,360 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var openAPIPaths = map[string]string{
+	"GET /v1/tracks/{id}":                                 "/v1/tracks/t1",
+	"GET /v1/albums/{id}":                                 "/v1/albums/a1",
+	"GET /v1/artists/{id}":                                "/v1/artists/r1",
+	"GET /v1/webhooks/{id}":                               "/v1/webhooks/1",
+	"DELETE /v1/webhooks/{id}":                            "/v1/webhooks/2",
+	"GET /v1/webhooks/{id}/deliveries":                    "/v1/webhooks/1/deliveries",
+	"POST /v1/webhooks/{id}/deliveries/{delivery}/replay": "/v1/webhooks/1/deliveries/1/replay",
+	"GET /feeds/countries/{feed}":                         "/feeds/countries/SK.atom",
+	"GET /feeds/artists/{feed}":                           "/feeds/artists/r1.atom",
+	"GET /v1/countries/{code}/charts/{type}/{date}":       "/v1/countries/SK/charts/DAILY_TOP_TRACK/2026-10-19",
+	"GET /v1/charts/{type}/{date}":                        "/v1/charts/DAILY_TOP_TRACK/2026-10-19",
+	"GET /v1/countries/{code}":                            "/v1/countries/SK",
+	"GET /test":                                           "/test?date=2026-10-19",
+	"GET /v1/sound-profiles":                              "/v1/sound-profiles?from=2026-10-18&to=2026-10-19",
+	"GET /v1/streams/artists":                             "/v1/streams/artists?from=2026-10-18&to=2026-10-19&country=SK",
+	"GET /v1/streams/countries":                           "/v1/streams/countries?from=2026-10-18&to=2026-10-19",
+	"POST /v1/webhooks":                                   "/v1/webhooks",
+	"GET /v1/events":                                      "/v1/events",
+	"GET /v1/webhooks":                                    "/v1/webhooks",
+	"GET /v1/countries":                                   "/v1/countries",
+	"GET /v1/status":                                      "/v1/status",
+	"GET /v1/metrics":                                     "/v1/metrics",
+}
+
+var openAPIBodies = map[string]string{
+	"POST /v1/webhooks": `{"url":"https://example.com/hook","event":"country.number_one","country_code":"SK"}`,
+}
+
+func TestHandlersMatchOpenAPI(t *testing.T) {
+	sqlDB := newTestDB(t)
+
+	reader := db.NewReader(sqlDB)
+	t.Cleanup(reader.Close)
+
+	store := db.NewWebhookStore(sqlDB)
+	t.Cleanup(store.Close)
+
+	eventStore := db.NewEventStore(sqlDB)
+	t.Cleanup(eventStore.Close)
+
+	s := &Server{
+		Reader:     reader,
+		Cache:      NewChartCache(4),
+		Events:     NewEventBus(eventStore, reader, 16),
+		Webhooks:   NewWebhookDispatcher(store, reader),
+		AdminToken: "token",
+	}
+
+	createTestWebhook(t, store, "https://example.com/one", WebhookTrackEntered)
+	createTestWebhook(t, store, "https://example.com/two", WebhookCountryNumberOne)
+
+	publishNow(t, s.Webhooks, testCommit)
+	s.Events.PublishCommit(testCommit)
+	s.Events.Flush()
+	s.Events.Publish(&model.EventExt{Type: EventIngestFinished, Ingest: &model.IngestEventExt{Date: testDate}})
+
+	server := httptest.NewServer(s.Routes())
+	defer server.Close()
+	defer s.Events.Close()
+
+	spec := roundTripJSON(t, s.openAPI)
+
+	for _, route := range s.routes() {
+		key := route.method + " " + route.path
+
+		t.Run(key, func(t *testing.T) {
+			path, ok := openAPIPaths[key]
+			if !ok {
+				t.Fatalf("no sample request for route '%s', add one to openAPIPaths", key)
+			}
+
+			operation := lookup(spec, "paths", route.path, strings.ToLower(route.method))
+			if operation == nil {
+				t.Fatalf("route is missing from the OpenAPI document")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, route.method, server.URL+path, strings.NewReader(openAPIBodies[key]))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set(lastEventIDHeader, "1")
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer res.Body.Close()
+
+			var status string
+			for code := range lookup(operation, "responses").(map[string]any) {
+				if strings.HasPrefix(code, "2") {
+					status = code
+				}
+			}
+
+			if strconv.Itoa(res.StatusCode) != status {
+				body, _ := io.ReadAll(res.Body)
+				t.Fatalf("responded with %d, the spec declares %s: %s", res.StatusCode, status, body)
+			}
+
+			content := lookup(operation, "responses", status, "content").(map[string]any)
+			if len(content) != 1 {
+				t.Fatalf("spec declares %d content types, want 1", len(content))
+			}
+
+			for contentType, media := range content {
+				if res.Header.Get("Content-Type") != contentType {
+					t.Fatalf("responded with content type %q, the spec declares %q", res.Header.Get("Content-Type"), contentType)
+				}
+
+				schema := lookup(media, "schema").(map[string]any)
+
+				for _, problem := range validateSchema(spec, schema, responseValue(t, contentType, res.Body), "$") {
+					t.Error(problem)
+				}
+			}
+		})
+	}
+}
+
+func responseValue(t *testing.T, contentType string, body io.Reader) any {
+	switch contentType {
+	case jsonContentType:
+		var value any
+		if err := json.NewDecoder(body).Decode(&value); err != nil {
+			t.Fatalf("invalid JSON body: %s", err)
+		}
+
+		return value
+	case eventStreamContentType:
+		scanner := bufio.NewScanner(body)
+
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				var value any
+				if err := json.Unmarshal([]byte(data), &value); err != nil {
+					t.Fatalf("invalid JSON event: %s", err)
+				}
+
+				return value
+			}
+		}
+
+		t.Fatalf("the event stream ended without an event: %v", scanner.Err())
+	default:
+		payload, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := xml.Unmarshal(payload, new(struct{})); err != nil {
+			t.Fatalf("invalid XML body: %s", err)
+		}
+
+		return string(payload)
+	}
+
+	return nil
+}
+
+func validateSchema(spec map[string]any, schema map[string]any, value any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		return validateSchema(spec, lookup(spec, "components", "schemas", name).(map[string]any), value, path)
+	}
+
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+
+		return []string{fmt.Sprintf("%s: null is not nullable", path)}
+	}
+
+	if allOf, ok := schema["allOf"].([]any); ok {
+		problems := make([]string, 0)
+
+		for _, part := range allOf {
+			problems = append(problems, validateSchema(spec, part.(map[string]any), value, path)...)
+		}
+
+		return problems
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: got %T, want an object", path, value)}
+		}
+
+		return validateObject(spec, schema, object, path)
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: got %T, want an array", path, value)}
+		}
+
+		problems := make([]string, 0)
+
+		for index, item := range array {
+			problems = append(problems, validateSchema(spec, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", path, index))...)
+		}
+
+		return problems
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: got %T, want a string", path, value)}
+		}
+
+		if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, any(str)) {
+			return []string{fmt.Sprintf("%s: %q is not one of %v", path, str, enum)}
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return []string{fmt.Sprintf("%s: got %v, want an integer", path, value)}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s: got %T, want a number", path, value)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: got %T, want a boolean", path, value)}
+		}
+	default:
+		return []string{fmt.Sprintf("%s: unsupported schema %v", path, schema)}
+	}
+
+	return nil
+}
+
+func validateObject(spec map[string]any, schema map[string]any, object map[string]any, path string) []string {
+	problems := make([]string, 0)
+
+	properties, _ := schema["properties"].(map[string]any)
+	additional, _ := schema["additionalProperties"].(map[string]any)
+
+	required, _ := schema["required"].([]any)
+	for _, name := range required {
+		if _, ok := object[name.(string)]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: required property %q is missing", path, name))
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertySchema, ok := properties[name].(map[string]any)
+		if !ok {
+			propertySchema = additional
+		}
+
+		if propertySchema == nil {
+			problems = append(problems, fmt.Sprintf("%s: property %q is not in the spec", path, name))
+			continue
+		}
+
+		problems = append(problems, validateSchema(spec, propertySchema, object[name], path+"."+name)...)
+	}
+
+	return problems
+}
+
+func roundTripJSON(t *testing.T, value any) map[string]any {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := make(map[string]any)
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	return decoded
+}
+
+func lookup(value any, keys ...string) any {
+	for _, key := range keys {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		value = object[key]
+	}
+
+	return value
+}
+
+func TestValidateSchemaReportsDrift(t *testing.T) {
+	spec := roundTripJSON(t, buildOpenAPI(nil))
+	spec["components"] = map[string]any{"schemas": map[string]any{
+		"Track": map[string]any{
+			"type":       "object",
+			"required":   []any{"id"},
+			"properties": map[string]any{"id": map[string]any{"type": "string"}, "streams": map[string]any{"type": "integer"}},
+		},
+	}}
+
+	track := map[string]any{"$ref": "#/components/schemas/Track"}
+
+	tests := []struct {
+		value    string
+		problems int
+	}{
+		{`{"id":"t1","streams":10}`, 0},
+		{`{"streams":1.5}`, 2},
+		{`{"id":"t1","name":"One"}`, 1},
+		{`null`, 1},
+	}
+
+	for _, test := range tests {
+		var value any
+		if err := json.Unmarshal([]byte(test.value), &value); err != nil {
+			t.Fatal(err)
+		}
+
+		if problems := validateSchema(spec, track, value, "$"); len(problems) != test.problems {
+			t.Errorf("%s: got problems %v, want %d", test.value, problems, test.problems)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	jsonContentType   = "application/json"
+	retryAfterSeconds = 30
+)
+
+type envelope struct {
+	Data  any            `json:"data,omitempty"`
+	Error *errorResponse `json:"error,omitempty"`
+}
+
+type errorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+}
+
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...any) *apiError {
+	return &apiError{http.StatusBadRequest, "bad_request", fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...any) *apiError {
+	return &apiError{http.StatusNotFound, "not_found", fmt.Sprintf(format, args...)}
+}
+
+func unauthorized(format string, args ...any) *apiError {
+	return &apiError{http.StatusUnauthorized, "unauthorized", fmt.Sprintf(format, args...)}
+}
+
+func forbidden(format string, args ...any) *apiError {
+	return &apiError{http.StatusForbidden, "forbidden", fmt.Sprintf(format, args...)}
+}
+
+func methodNotAllowed(method string) *apiError {
+	return &apiError{http.StatusMethodNotAllowed, "method_not_allowed", fmt.Sprintf("method '%s' is not allowed", method)}
+}
+
+func unavailable(format string, args ...any) *apiError {
+	return &apiError{http.StatusServiceUnavailable, "unavailable", fmt.Sprintf(format, args...)}
+}
+
+func internalError() *apiError {
+	return &apiError{http.StatusInternalServerError, "internal_error", "internal server error"}
+}
+
+func writeData(w http.ResponseWriter, r *http.Request, data any) {
+	writeDataStatus(w, r, http.StatusOK, data)
+}
+
+func writeDataStatus(w http.ResponseWriter, r *http.Request, status int, data any) {
+	writeJSON(w, r, status, envelope{Data: data})
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err *apiError) {
+	w.Header().Del("ETag")
+	w.Header().Del("Last-Modified")
+	w.Header().Del("Cache-Control")
+
+	if err.status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+	}
+
+	writeJSON(w, r, err.status, envelope{Error: &errorResponse{Code: err.code, Message: err.message, RequestID: requestID(r)}})
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	var buf bytes.Buffer
+
+	err := json.NewEncoder(&buf).Encode(body)
+	if err != nil {
+		log.Printf("[%s] failed to encode response: %s\n", requestID(r), err)
+
+		if status == http.StatusInternalServerError {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeError(w, r, internalError())
+		return
+	}
+
+	writeBody(w, r, status, jsonContentType, buf.Bytes())
+}
+
+func writeChart(w http.ResponseWriter, r *http.Request, body any, lastModified int64, finished bool) {
+	if !finished {
+		writeJSON(w, r, http.StatusOK, body)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		log.Printf("[%s] failed to encode response: %s\n", requestID(r), err)
+
+		writeError(w, r, internalError())
+		return
+	}
+
+	if contentNotModified(w, r, buf.Bytes(), lastModified, finishedCacheControl) {
+		return
+	}
+
+	writeBody(w, r, http.StatusOK, jsonContentType, buf.Bytes())
+}
+
+func writeBody(w http.ResponseWriter, r *http.Request, status int, contentType string, payload []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if len(payload) >= compressionMinBodyBytes {
+		switch negotiateEncoding(r) {
+		case "br":
+			payload = compressBytes(payload, brotli.NewWriter)
+			w.Header().Set("Content-Encoding", "br")
+		case "gzip":
+			payload = compressBytes(payload, gzip.NewWriter)
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+	w.WriteHeader(status)
+
+	w.Write(payload)
+}
+
+func compressBytes[W io.WriteCloser](body []byte, newWriter func(io.Writer) W) []byte {
+	var buf bytes.Buffer
+
+	writer := newWriter(&buf)
+
+	if _, err := writer.Write(body); err != nil {
+		panic(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		panic(err)
+	}
+
+	return buf.Bytes()
+}
@@ -1,27 +1,352 @@
 package server
 
 import (
-	"encoding/json"
 	"net/http"
 	"spotify-charter/db"
+	"spotify-charter/graphql"
 	"spotify-charter/model"
+	"strings"
 	"time"
 )
 
 type Server struct {
-	Reader *db.Reader
+	Reader     *db.Reader
+	Cache      *ChartCache
+	Events     *EventBus
+	Webhooks   *WebhookDispatcher
+	AdminToken string
+	openAPI    map[string]any
+	graphQL    *graphql.Schema
+	persisted  *persistedQueries
+}
+
+type Metrics struct {
+	ChartCache *ChartCacheStats `json:"chart_cache,omitempty"`
+}
+
+const dateLayout = "2006-01-02"
+
+func (s *Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+
+	routes := s.routes()
+
+	for _, route := range routes {
+		var handler http.Handler = route.handler
+		if route.admin {
+			handler = withAdminToken(s.AdminToken, handler)
+		}
+
+		mux.Handle(route.method+" "+route.path, handler)
+	}
+
+	s.openAPI = buildOpenAPI(routes)
+
+	mux.HandleFunc("GET /openapi.json", s.GetOpenAPI)
+	mux.HandleFunc("GET /docs", s.GetDocs)
+
+	s.graphQL = s.newGraphQLSchema()
+	s.persisted = newPersistedQueries()
+
+	mux.HandleFunc("GET /graphql", s.ServeGraphQL)
+	mux.HandleFunc("POST /graphql", s.ServeGraphQL)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		allowed := make([]string, 0)
+
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+			if _, pattern := mux.Handler(withMethod(r, method)); len(pattern) > 0 && pattern != "/" {
+				allowed = append(allowed, method)
+
+				if method == http.MethodGet {
+					allowed = append(allowed, http.MethodHead)
+				}
+			}
+		}
+
+		if len(allowed) != 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, r, methodNotAllowed(r.Method))
+			return
+		}
+
+		writeError(w, r, notFound("no resource at '%s'", r.URL.Path))
+	})
+
+	return withRequestID(withRecovery(mux))
 }
 
 func (s *Server) GetPlaylists(w http.ResponseWriter, r *http.Request) {
-	dateNow := model.TimeToDatestamp(time.Now())
+	chartType, apiErr := parseChartType(r.URL.Query().Get("type"))
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	date, apiErr := s.chartDate(r.URL.Query().Get("date"), chartType, "")
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	lastModified, finished, unchanged := s.notModified(w, r, chartType, date, "")
+	if unchanged {
+		return
+	}
+
+	chartTracks := s.chartTracks(chartType, date, "", lastModified)
+
+	writeChart(w, r, chartTracks, lastModified, finished)
+}
+
+func (s *Server) GetCountries(w http.ResponseWriter, r *http.Request) {
+	writeData(w, r, s.Reader.GetCountries())
+}
+
+func (s *Server) GetCountry(w http.ResponseWriter, r *http.Request) {
+	country, err := s.country(r.PathValue("code"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeData(w, r, country)
+}
+
+func (s *Server) GetCountryChart(w http.ResponseWriter, r *http.Request) {
+	country, apiErr := s.country(r.PathValue("code"))
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	chartType, date, apiErr := s.chartPathParams(r, country.Code)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	lastModified, finished, unchanged := s.notModified(w, r, chartType, date, country.Code)
+	if unchanged {
+		return
+	}
+
+	chartTracks := s.chartTracks(chartType, date, country.Code, lastModified)
+
+	tracks := (*chartTracks)[country.Code]
+	if tracks == nil {
+		tracks = make([]*model.TrackExt, 0)
+	}
+
+	writeChart(w, r, envelope{Data: tracks}, lastModified, finished)
+}
+
+func (s *Server) GetChart(w http.ResponseWriter, r *http.Request) {
+	chartType, date, apiErr := s.chartPathParams(r, "")
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	lastModified, finished, unchanged := s.notModified(w, r, chartType, date, "")
+	if unchanged {
+		return
+	}
+
+	writeChart(w, r, envelope{Data: s.chartTracks(chartType, date, "", lastModified)}, lastModified, finished)
+}
+
+func (s *Server) GetTrack(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	track := s.Reader.GetTrackExt(id)
+	if track == nil {
+		writeError(w, r, notFound("unknown track '%s'", id))
+		return
+	}
+
+	writeData(w, r, track)
+}
+
+func (s *Server) GetAlbum(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	album := s.Reader.GetAlbumExt(id)
+	if album == nil {
+		writeError(w, r, notFound("unknown album '%s'", id))
+		return
+	}
+
+	writeData(w, r, album)
+}
+
+func (s *Server) GetArtist(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	artist := s.Reader.GetArtistExt(id)
+	if artist == nil {
+		writeError(w, r, notFound("unknown artist '%s'", id))
+		return
+	}
+
+	writeData(w, r, artist)
+}
+
+func (s *Server) GetStatus(w http.ResponseWriter, r *http.Request) {
+	writeData(w, r, s.Reader.GetChartSourcesExt())
+}
 
-	chartTracks := s.Reader.GetChartTracksExt(dateNow)
+func (s *Server) GetSoundProfiles(w http.ResponseWriter, r *http.Request) {
+	chartType, from, to, apiErr := rangeQueryParams(r, 30)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	writeData(w, r, s.Reader.GetSoundProfiles(chartType, from, to))
+}
+
+func (s *Server) GetArtistStreams(w http.ResponseWriter, r *http.Request) {
+	chartType, from, to, apiErr := rangeQueryParams(r, 28)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	countryCode := model.GlobalCountryCode
+
+	if param := r.URL.Query().Get("country"); len(param) > 0 {
+		country, apiErr := s.country(param)
+		if apiErr != nil {
+			writeError(w, r, apiErr)
+			return
+		}
+
+		countryCode = country.Code
+	}
+
+	writeData(w, r, s.Reader.GetArtistWeeklyStreams(chartType, countryCode, from, to))
+}
+
+func (s *Server) GetCountryStreams(w http.ResponseWriter, r *http.Request) {
+	chartType, from, to, apiErr := rangeQueryParams(r, 7)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	writeData(w, r, s.Reader.GetCountryStreams(chartType, from, to))
+}
+
+func (s *Server) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics := &Metrics{}
+
+	if s.Cache != nil {
+		metrics.ChartCache = s.Cache.Stats()
+	}
+
+	writeData(w, r, metrics)
+}
+
+func (s *Server) chartTracks(chartType model.ChartType, date int64, countryCode string, lastModified int64) *model.ChartTracksExt {
+	load := func() *model.ChartTracksExt {
+		return s.Reader.GetChartTracksExt(chartType, date, countryCode)
+	}
+
+	if s.Cache == nil {
+		return load()
+	}
+
+	return s.Cache.get(chartCacheKey{chartType, date, countryCode, lastModified}, load)
+}
+
+func (s *Server) country(code string) (*model.CountryExt, *apiError) {
+	country := s.Reader.GetCountry(strings.ToUpper(code))
+	if country == nil {
+		return nil, notFound("unknown country '%s'", code)
+	}
+
+	return country, nil
+}
+
+func (s *Server) chartPathParams(r *http.Request, countryCode string) (model.ChartType, int64, *apiError) {
+	chartType, err := parseChartType(strings.ToUpper(r.PathValue("type")))
+	if err != nil {
+		return "", 0, err
+	}
+
+	date, err := s.chartDate(r.PathValue("date"), chartType, countryCode)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return chartType, date, nil
+}
+
+func (s *Server) chartDate(param string, chartType model.ChartType, countryCode string) (int64, *apiError) {
+	if len(param) == 0 || param == "latest" {
+		if latest := s.Reader.GetLatestChartDate(chartType, countryCode); latest != 0 {
+			return latest, nil
+		}
+	}
+
+	date, err := parseDate(param, "date", time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	date, _ = chartType.PeriodBounds(date)
+
+	return date, nil
+}
+
+func rangeQueryParams(r *http.Request, days int) (model.ChartType, int64, int64, *apiError) {
+	query := r.URL.Query()
+
+	chartType, err := parseChartType(query.Get("type"))
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	to, err := parseDate(query.Get("to"), "to", time.Now())
+	if err != nil {
+		return "", 0, 0, err
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	from, err := parseDate(query.Get("from"), "from", time.Unix(to, 0).AddDate(0, 0, -days))
+	if err != nil {
+		return "", 0, 0, err
+	}
 
-	err := json.NewEncoder(w).Encode(chartTracks)
+	if from > to {
+		return "", 0, 0, badRequest("'from' must not be after 'to'")
+	}
+
+	return chartType, from, to, nil
+}
+
+func parseChartType(param string) (model.ChartType, *apiError) {
+	if len(param) == 0 {
+		return model.DailyTopTrack, nil
+	}
+
+	chartType, ok := model.ParseChartType(param)
+	if !ok {
+		return "", badRequest("unknown chart type '%s'", param)
+	}
+
+	return chartType, nil
+}
+
+func parseDate(param string, name string, fallback time.Time) (int64, *apiError) {
+	if len(param) == 0 || param == "latest" {
+		return model.TimeToDatestamp(fallback), nil
+	}
+
+	date, err := time.Parse(dateLayout, param)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return 0, badRequest("invalid date '%s' for '%s', expected YYYY-MM-DD", param, name)
 	}
+
+	return model.TimeToDatestamp(date), nil
 }
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var (
+	testPreviousDate = model.TimeToDatestamp(time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC))
+	testDate         = model.TimeToDatestamp(time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC))
+)
+
+var testCharts = map[int64]map[string][]string{
+	testPreviousDate: {"SK": {"t1", "t2"}, "CZ": {"t2"}},
+	testDate:         {"SK": {"t0", "t1"}, "CZ": {"t1", "t2"}},
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	sqlDB, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "charter.db")+"?_busy_timeout=50")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db.CreateTables(sqlDB)
+
+	artists := map[string]model.Artist{
+		"r1": {SpotifyID: "r1", Name: "Art One"},
+		"r2": {SpotifyID: "r2", Name: "Art Two"},
+	}
+
+	tracks := map[string]*model.Track{
+		"t0": {SpotifyID: "t0", Name: "Zero", Album: model.Album{SpotifyID: "a0", Name: "Album Zero"}, Artists: []model.Artist{artists["r1"]}},
+		"t1": {SpotifyID: "t1", Name: "One", Album: model.Album{SpotifyID: "a1", Name: "Album One"}, Artists: []model.Artist{artists["r1"], artists["r2"]}},
+		"t2": {SpotifyID: "t2", Name: "Two", Album: model.Album{SpotifyID: "a1", Name: "Album One"}, Artists: []model.Artist{artists["r2"]}},
+	}
+
+	writer := db.NewWriter(sqlDB)
+
+	writer.SaveCountry(&model.Country{Code: "SK", Name: "Slovakia"})
+	writer.SaveCountry(&model.Country{Code: "CZ", Name: "Czechia"})
+	writer.SaveChartSource(&model.ChartSource{Country: &model.Country{Code: "SK"}, ChartType: model.DailyTopTrack, PlaylistID: "p1"})
+
+	for date, charts := range testCharts {
+		for countryCode, trackIDs := range charts {
+			for position, trackID := range trackIDs {
+				err := writer.SaveChartTrack(&model.ChartTrack{
+					Country:   &model.Country{Code: countryCode},
+					Track:     tracks[trackID],
+					ChartType: model.DailyTopTrack,
+					Date:      date,
+					Position:  position,
+					Streams:   int64(1000 * (len(trackIDs) - position)),
+				})
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	for _, track := range tracks {
+		writer.SaveAudioFeatures(&model.AudioFeatures{TrackID: track.SpotifyID, Tempo: 120, Energy: 0.5, Danceability: 0.7})
+	}
+
+	writer.SaveAlbumDetails(&model.Album{SpotifyID: "a1", Name: "Album One", Label: "Label", Copyrights: []model.Copyright{{Text: "(C) Label", Type: "C"}}})
+	writer.SaveArtistDetails(&model.ArtistSnapshot{Artist: &model.Artist{SpotifyID: "r1", Name: "Art One", Genres: []string{"pop"}, Popularity: 50, Followers: 1000}, Date: testDate})
+
+	writer.Commit()
+
+	return sqlDB
+}
+
+func TestV1Envelopes(t *testing.T) {
+	sqlDB := newTestDB(t)
+
+	reader := db.NewReader(sqlDB)
+	t.Cleanup(reader.Close)
+
+	routes := (&Server{Reader: reader}).Routes()
+
+	tests := []struct {
+		path   string
+		status int
+		code   string
+		error  string
+	}{
+		{"/v1/countries/SK", http.StatusOK, "SK", ""},
+		{"/v1/countries/sk", http.StatusOK, "SK", ""},
+		{"/v1/countries/XX", http.StatusNotFound, "", "not_found"},
+		{"/v1/countries/SK/charts/DAILY_TOP_TRACK/2026-10-19", http.StatusOK, "", ""},
+		{"/v1/countries/SK/charts/DAILY_TOP_TRACK/19.10.2026", http.StatusBadRequest, "", "bad_request"},
+		{"/v1/countries/SK/charts/HOURLY_TOP_TRACK/2026-10-19", http.StatusBadRequest, "", "bad_request"},
+		{"/v1/nothing", http.StatusNotFound, "", "not_found"},
+	}
+
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		var body struct {
+			Data  json.RawMessage `json:"data"`
+			Error *struct {
+				Code      string `json:"code"`
+				RequestID string `json:"request_id"`
+			} `json:"error"`
+		}
+
+		if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid envelope %q: %s", test.path, res.Body, err)
+		}
+
+		if res.Code != test.status {
+			t.Errorf("%s responded with %d, want %d", test.path, res.Code, test.status)
+		}
+
+		if len(test.error) != 0 {
+			if body.Error == nil || body.Error.Code != test.error || len(body.Error.RequestID) == 0 || body.Data != nil {
+				t.Errorf("%s: got envelope %s, want only an error with code %q and a request ID", test.path, res.Body, test.error)
+			}
+
+			continue
+		}
+
+		var country struct {
+			Code string `json:"code"`
+		}
+
+		if body.Error != nil || body.Data == nil {
+			t.Errorf("%s: got envelope %s, want only data", test.path, res.Body)
+		} else if len(test.code) != 0 && (json.Unmarshal(body.Data, &country) != nil || country.Code != test.code) {
+			t.Errorf("%s: got data %s, want country %s", test.path, body.Data, test.code)
+		}
+	}
+}
+
+func TestLatestResolvesToStoredCharts(t *testing.T) {
+	sqlDB := newTestDB(t)
+
+	if _, err := sqlDB.Exec("DELETE FROM chart_tracks WHERE country_code = 'CZ' AND date = ?", testDate); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := db.NewReader(sqlDB)
+	t.Cleanup(reader.Close)
+
+	routes := (&Server{Reader: reader}).Routes()
+
+	tests := []struct {
+		path     string
+		trackIDs []string
+	}{
+		{"/v1/countries/SK/charts/DAILY_TOP_TRACK/latest", []string{"t0", "t1"}},
+		{"/v1/countries/CZ/charts/DAILY_TOP_TRACK/latest", []string{"t2"}},
+		{"/v1/countries/CZ/charts/WEEKLY_TOP_TRACK/latest", []string{}},
+	}
+
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		var body struct {
+			Data []*model.TrackExt `json:"data"`
+		}
+
+		if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %s", test.path, err)
+		}
+
+		trackIDs := make([]string, 0)
+		for _, track := range body.Data {
+			if track != nil {
+				trackIDs = append(trackIDs, track.ID)
+			}
+		}
+
+		if res.Code != http.StatusOK || !slices.Equal(trackIDs, test.trackIDs) {
+			t.Errorf("%s responded with %d and %v, want %v", test.path, res.Code, trackIDs, test.trackIDs)
+		}
+	}
+}
@@ -0,0 +1,761 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	WebhookTrackEntered     = "track.entered"
+	WebhookCountryNumberOne = "country.number_one"
+	WebhookArtistNumberOne  = "artist.number_one"
+)
+
+var webhookEvents = []string{WebhookTrackEntered, WebhookCountryNumberOne, WebhookArtistNumberOne}
+
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+const (
+	webhookSignatureHeader = "X-Charter-Signature"
+	webhookTimestampHeader = "X-Charter-Timestamp"
+	webhookEventHeader     = "X-Charter-Event"
+	webhookDeliveryHeader  = "X-Charter-Delivery"
+	webhookMaxAttempts     = 8
+	webhookRetryBaseDelay  = 10 * time.Second
+	webhookRetryMaxDelay   = time.Hour
+	webhookTimeout         = 10 * time.Second
+	webhookPollInterval    = 5 * time.Second
+	webhookBatchSize       = 50
+	webhookDeliveryLimit   = 100
+	webhookMaxBodyBytes    = 64 << 10
+	webhookMaxErrorLength  = 512
+	webhookClaimLease      = time.Minute
+	webhookBusyRetryDelay  = time.Second
+	webhookEnqueueAttempts = 5
+)
+
+type WebhookDispatcher struct {
+	store    *db.WebhookStore
+	reader   *db.Reader
+	client   *http.Client
+	wake     chan bool
+	lock     sync.Mutex
+	inFlight map[int64]bool
+	commits  []*db.Commit
+	workers  sync.WaitGroup
+}
+
+func NewWebhookDispatcher(store *db.WebhookStore, reader *db.Reader) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		store:    store,
+		reader:   reader,
+		client:   &http.Client{Timeout: webhookTimeout, Transport: webhookTransport()},
+		wake:     make(chan bool, 1),
+		inFlight: make(map[int64]bool),
+	}
+}
+
+func (dispatcher *WebhookDispatcher) PublishCommit(commit *db.Commit) {
+	if len(commit.Charts) == 0 {
+		return
+	}
+
+	dispatcher.lock.Lock()
+	dispatcher.commits = append(dispatcher.commits, commit)
+	dispatcher.lock.Unlock()
+
+	dispatcher.notify()
+}
+
+func (dispatcher *WebhookDispatcher) Run(ctx context.Context) {
+	poll := time.NewTicker(webhookPollInterval)
+	defer poll.Stop()
+
+	defer dispatcher.workers.Wait()
+
+	for {
+		if err := dispatcher.enqueueCommits(); err != nil {
+			log.Printf("Enqueueing webhook deliveries failed, retrying later: %s\n", err)
+		}
+
+		dispatcher.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-dispatcher.wake:
+		case <-poll.C:
+		}
+	}
+}
+
+func (dispatcher *WebhookDispatcher) Flush() {
+	for attempt := 1; ; attempt++ {
+		err := dispatcher.enqueueCommits()
+		if err == nil {
+			return
+		}
+
+		if attempt == webhookEnqueueAttempts {
+			log.Printf("Dropping pending webhook deliveries, the DB stayed busy: %s\n", err)
+			return
+		}
+
+		time.Sleep(webhookBusyRetryDelay)
+	}
+}
+
+func (dispatcher *WebhookDispatcher) enqueueCommits() error {
+	dispatcher.lock.Lock()
+	commits := dispatcher.commits
+	dispatcher.commits = nil
+	dispatcher.lock.Unlock()
+
+	if len(commits) == 0 {
+		return nil
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("Skipping webhook deliveries of %d commits: %v\n", len(commits), rec)
+		}
+	}()
+
+	webhooks := dispatcher.store.GetWebhooks()
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	deliveries := make([]*model.WebhookDelivery, 0)
+
+	for _, commit := range commits {
+		for _, chart := range commit.Charts {
+			for _, event := range dispatcher.chartEvents(chart) {
+				for _, webhook := range webhooks {
+					if webhookMatches(webhook, event) {
+						deliveries = append(deliveries, newDelivery(webhook, event, commit.CommittedAt))
+					}
+				}
+			}
+		}
+	}
+
+	enqueued, err := dispatcher.store.SaveDeliveries(deliveries)
+	if err != nil {
+		dispatcher.lock.Lock()
+		dispatcher.commits = append(commits, dispatcher.commits...)
+		dispatcher.lock.Unlock()
+
+		return err
+	}
+
+	if enqueued != 0 {
+		log.Printf("Enqueued %d webhook deliveries\n", enqueued)
+	}
+
+	return nil
+}
+
+func (dispatcher *WebhookDispatcher) chartEvents(chart model.ChartKey) []*model.WebhookEventExt {
+	previousDate, movements := dispatcher.reader.GetChartMovements(chart.ChartType, chart.Date, chart.CountryCode)
+	if previousDate == 0 {
+		return nil
+	}
+
+	notable := make([]*model.ChartMovement, 0)
+	trackIDs := make([]string, 0)
+
+	for _, movement := range movements {
+		if movement.IsExit() {
+			continue
+		}
+
+		if movement.IsNew() || (movement.Position == 0 && movement.PreviousPosition != 0) {
+			notable = append(notable, movement)
+			trackIDs = append(trackIDs, movement.Track.SpotifyID)
+		}
+	}
+
+	if len(notable) == 0 {
+		return nil
+	}
+
+	artistIDs := dispatcher.reader.GetArtistIDsByTrackIDs(trackIDs)
+
+	allArtistIDs := make([]string, 0)
+	for _, trackArtistIDs := range artistIDs {
+		allArtistIDs = append(allArtistIDs, trackArtistIDs...)
+	}
+
+	artists := dispatcher.reader.GetArtistsExtByIDs(allArtistIDs)
+
+	events := make([]*model.WebhookEventExt, 0)
+
+	for _, movement := range notable {
+		trackArtists := make([]*model.ArtistExt, 0)
+		for _, artistID := range artistIDs[movement.Track.SpotifyID] {
+			if artist, ok := artists[artistID]; ok {
+				trackArtists = append(trackArtists, artist)
+			}
+		}
+
+		newEvent := func(eventType string, artistID string) *model.WebhookEventExt {
+			id := strings.Join([]string{eventType, chart.CountryCode, string(chart.ChartType), strconv.FormatInt(chart.Date, 10), movement.Track.SpotifyID}, ":")
+			if len(artistID) != 0 {
+				id += ":" + artistID
+			}
+
+			return &model.WebhookEventExt{
+				ID:          id,
+				Type:        eventType,
+				CountryCode: chart.CountryCode,
+				ChartType:   chart.ChartType,
+				Date:        chart.Date,
+				Movement:    movementExt(movement),
+				Artists:     trackArtists,
+				ArtistID:    artistID,
+			}
+		}
+
+		if movement.IsNew() {
+			events = append(events, newEvent(WebhookTrackEntered, ""))
+		}
+
+		if movement.Position == 0 {
+			events = append(events, newEvent(WebhookCountryNumberOne, ""))
+
+			for _, artist := range trackArtists {
+				events = append(events, newEvent(WebhookArtistNumberOne, artist.ID))
+			}
+		}
+	}
+
+	return events
+}
+
+func webhookMatches(webhook *model.Webhook, event *model.WebhookEventExt) bool {
+	if webhook.EventType != event.Type {
+		return false
+	}
+
+	if len(webhook.CountryCode) != 0 && webhook.CountryCode != event.CountryCode {
+		return false
+	}
+
+	if len(webhook.ChartType) != 0 && webhook.ChartType != event.ChartType {
+		return false
+	}
+
+	if len(webhook.ArtistID) == 0 {
+		return true
+	}
+
+	if len(event.ArtistID) != 0 {
+		return webhook.ArtistID == event.ArtistID
+	}
+
+	return slices.ContainsFunc(event.Artists, func(artist *model.ArtistExt) bool {
+		return artist.ID == webhook.ArtistID
+	})
+}
+
+func newDelivery(webhook *model.Webhook, event *model.WebhookEventExt, createdAt int64) *model.WebhookDelivery {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		panic(err)
+	}
+
+	return &model.WebhookDelivery{
+		Webhook:   webhook,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   string(payload),
+		Status:    model.DeliveryPending,
+		CreatedAt: createdAt,
+	}
+}
+
+func (dispatcher *WebhookDispatcher) notify() {
+	select {
+	case dispatcher.wake <- true:
+	default:
+	}
+}
+
+func (dispatcher *WebhookDispatcher) deliverDue(ctx context.Context) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("Delivering webhooks failed, retrying later: %v\n", rec)
+		}
+	}()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	busy := make([]int64, 0, len(dispatcher.inFlight))
+	for webhookID := range dispatcher.inFlight {
+		busy = append(busy, webhookID)
+	}
+
+	deliveries := dispatcher.store.GetDueDeliveries(time.Now().Unix(), busy, webhookBatchSize)
+
+	queues := make(map[int64][]*model.WebhookDelivery)
+	for _, delivery := range deliveries {
+		queues[delivery.Webhook.ID] = append(queues[delivery.Webhook.ID], delivery)
+	}
+
+	for webhookID, queue := range queues {
+		dispatcher.inFlight[webhookID] = true
+		dispatcher.workers.Add(1)
+
+		go dispatcher.deliverQueue(ctx, webhookID, queue)
+	}
+}
+
+func (dispatcher *WebhookDispatcher) deliverQueue(ctx context.Context, webhookID int64, queue []*model.WebhookDelivery) {
+	drained := false
+
+	defer dispatcher.workers.Done()
+
+	defer func() {
+		dispatcher.lock.Lock()
+		delete(dispatcher.inFlight, webhookID)
+		dispatcher.lock.Unlock()
+
+		if drained {
+			dispatcher.notify()
+		}
+	}()
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("[webhook %d] Delivering webhooks failed, retrying later: %v\n", webhookID, rec)
+		}
+	}()
+
+	for _, delivery := range queue {
+		if ctx.Err() != nil || !dispatcher.deliver(ctx, delivery) {
+			return
+		}
+	}
+
+	drained = true
+}
+
+func (dispatcher *WebhookDispatcher) deliver(ctx context.Context, delivery *model.WebhookDelivery) bool {
+	started := time.Now()
+
+	claimed, err := dispatcher.store.ClaimDelivery(delivery.ID, started.Unix(), started.Add(webhookClaimLease).Unix())
+	if err != nil {
+		log.Printf("[webhook %d] Claiming delivery %d failed, retrying later: %s\n", delivery.Webhook.ID, delivery.ID, err)
+		return false
+	}
+
+	if !claimed {
+		return true
+	}
+
+	attempt := &model.WebhookAttempt{
+		DeliveryID:  delivery.ID,
+		AttemptedAt: started.Unix(),
+	}
+
+	statusCode, err := dispatcher.post(ctx, delivery, started)
+	if ctx.Err() != nil {
+		return false
+	}
+
+	attempt.StatusCode = statusCode
+	attempt.DurationMs = time.Since(started).Milliseconds()
+
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	if err == nil {
+		delivery.Status = model.DeliverySucceeded
+		delivery.NextAttemptAt = 0
+		delivery.DeliveredAt = time.Now().Unix()
+	} else {
+		attempt.Error = truncate(err.Error(), webhookMaxErrorLength)
+
+		delivery.LastError = attempt.Error
+
+		if delivery.Attempts >= webhookMaxAttempts {
+			delivery.Status = model.DeliveryFailed
+			delivery.NextAttemptAt = 0
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(retryDelay(delivery.Attempts)).Unix()
+		}
+
+		log.Printf("[webhook %d] Delivery %d attempt %d failed: %s\n", delivery.Webhook.ID, delivery.ID, delivery.Attempts, attempt.Error)
+	}
+
+	for {
+		err := dispatcher.store.SaveAttempt(delivery, attempt)
+		if err == nil {
+			return true
+		}
+
+		log.Printf("[webhook %d] Recording delivery %d attempt %d failed, retrying: %s\n", delivery.Webhook.ID, delivery.ID, delivery.Attempts, err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(webhookBusyRetryDelay):
+		}
+	}
+}
+
+func (dispatcher *WebhookDispatcher) post(ctx context.Context, delivery *model.WebhookDelivery, sentAt time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+
+	req.Header.Set("Content-Type", jsonContentType)
+	req.Header.Set("User-Agent", "spotify-charter-webhooks/1")
+	req.Header.Set(webhookEventHeader, delivery.EventType)
+	req.Header.Set(webhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+signPayload(delivery.Webhook.Secret, timestamp, delivery.Payload))
+
+	res, err := dispatcher.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(res.Body, webhookMaxBodyBytes))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("receiver responded with status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+func webhookTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network string, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !isPublicAddr(addr) {
+				return fmt.Errorf("refusing to deliver to non-public address %s", host)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return transport
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+func signPayload(secret string, timestamp string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(payload))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := webhookRetryBaseDelay << (attempts - 1)
+	if delay <= 0 || delay > webhookRetryMaxDelay {
+		return webhookRetryMaxDelay
+	}
+
+	return delay
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+
+	return s[:length]
+}
+
+func (s *Server) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks := make([]*model.WebhookExt, 0)
+
+	for _, webhook := range s.Webhooks.store.GetWebhooks() {
+		webhooks = append(webhooks, webhookExt(webhook, false))
+	}
+
+	writeData(w, r, webhooks)
+}
+
+func (s *Server) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	request := &model.WebhookRequestExt{}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, webhookMaxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(request); err != nil {
+		writeError(w, r, badRequest("invalid request body: %s", err))
+		return
+	}
+
+	webhook, apiErr := newWebhook(request)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	if err := s.Webhooks.store.CreateWebhook(webhook); err != nil {
+		writeError(w, r, unavailable("%s, retry later", err))
+		return
+	}
+
+	writeDataStatus(w, r, http.StatusCreated, webhookExt(webhook, true))
+}
+
+func (s *Server) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, apiErr := s.webhook(r)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	writeData(w, r, webhookExt(webhook, false))
+}
+
+func (s *Server) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, apiErr := s.webhook(r)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	deleted, err := s.Webhooks.store.DeleteWebhook(webhook.ID, time.Now().Unix())
+	if err != nil {
+		writeError(w, r, unavailable("%s, retry later", err))
+		return
+	}
+
+	if !deleted {
+		writeError(w, r, notFound("webhook %d not found", webhook.ID))
+		return
+	}
+
+	writeData(w, r, webhookExt(webhook, false))
+}
+
+func (s *Server) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, apiErr := s.webhook(r)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	deliveries := s.Webhooks.store.GetDeliveries(webhook.ID, webhookDeliveryLimit)
+
+	deliveryIDs := make([]int64, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveryIDs = append(deliveryIDs, delivery.ID)
+	}
+
+	attempts := s.Webhooks.store.GetAttempts(deliveryIDs)
+
+	deliveriesExt := make([]*model.WebhookDeliveryExt, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveriesExt = append(deliveriesExt, deliveryExt(delivery, attempts[delivery.ID]))
+	}
+
+	writeData(w, r, deliveriesExt)
+}
+
+func (s *Server) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	webhook, apiErr := s.webhook(r)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(r.PathValue("delivery"), 10, 64)
+	if err != nil {
+		writeError(w, r, badRequest("invalid delivery ID '%s'", r.PathValue("delivery")))
+		return
+	}
+
+	delivery := s.Webhooks.store.GetDelivery(webhook.ID, deliveryID)
+	if delivery == nil {
+		writeError(w, r, notFound("delivery %d of webhook %d not found", deliveryID, webhook.ID))
+		return
+	}
+
+	replayOf := delivery.ID
+	if delivery.ReplayOf != 0 {
+		replayOf = delivery.ReplayOf
+	}
+
+	replay := &model.WebhookDelivery{
+		Webhook:   webhook,
+		EventID:   delivery.EventID,
+		EventType: delivery.EventType,
+		Payload:   delivery.Payload,
+		Status:    model.DeliveryPending,
+		ReplayOf:  replayOf,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	if _, err := s.Webhooks.store.SaveDelivery(replay); err != nil {
+		writeError(w, r, unavailable("%s, retry later", err))
+		return
+	}
+
+	s.Webhooks.notify()
+
+	writeDataStatus(w, r, http.StatusAccepted, deliveryExt(replay, nil))
+}
+
+func (s *Server) webhook(r *http.Request) (*model.Webhook, *apiError) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return nil, badRequest("invalid webhook ID '%s'", r.PathValue("id"))
+	}
+
+	webhook := s.Webhooks.store.GetWebhook(id)
+	if webhook == nil {
+		return nil, notFound("webhook %d not found", id)
+	}
+
+	return webhook, nil
+}
+
+func newWebhook(request *model.WebhookRequestExt) (*model.Webhook, *apiError) {
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || len(target.Host) == 0 {
+		return nil, badRequest("invalid webhook URL '%s', expected an absolute http or https URL", request.URL)
+	}
+
+	addr, err := netip.ParseAddr(target.Hostname())
+	if (err == nil && !isPublicAddr(addr)) || strings.EqualFold(target.Hostname(), "localhost") {
+		return nil, badRequest("invalid webhook URL '%s', loopback, private and link-local addresses are not allowed", request.URL)
+	}
+
+	if !slices.Contains(webhookEvents, request.Event) {
+		return nil, badRequest("unknown event '%s', expected one of %s", request.Event, strings.Join(webhookEvents, ", "))
+	}
+
+	if len(request.ChartType) != 0 {
+		if _, ok := model.ParseChartType(string(request.ChartType)); !ok {
+			return nil, badRequest("unknown chart type '%s'", request.ChartType)
+		}
+	}
+
+	secret := request.Secret
+	if len(secret) == 0 {
+		buf := make([]byte, 32)
+
+		if _, err := rand.Read(buf); err != nil {
+			panic(err)
+		}
+
+		secret = hex.EncodeToString(buf)
+	}
+
+	return &model.Webhook{
+		URL:         target.String(),
+		Secret:      secret,
+		EventType:   request.Event,
+		CountryCode: strings.ToUpper(request.CountryCode),
+		ArtistID:    request.ArtistID,
+		ChartType:   request.ChartType,
+		CreatedAt:   time.Now().Unix(),
+	}, nil
+}
+
+func webhookExt(webhook *model.Webhook, withSecret bool) *model.WebhookExt {
+	webhookExt := &model.WebhookExt{
+		ID:          webhook.ID,
+		URL:         webhook.URL,
+		Event:       webhook.EventType,
+		CountryCode: webhook.CountryCode,
+		ArtistID:    webhook.ArtistID,
+		ChartType:   webhook.ChartType,
+		CreatedAt:   webhook.CreatedAt,
+	}
+
+	if withSecret {
+		webhookExt.Secret = webhook.Secret
+	}
+
+	return webhookExt
+}
+
+func deliveryExt(delivery *model.WebhookDelivery, attempts []*model.WebhookAttempt) *model.WebhookDeliveryExt {
+	event := &model.WebhookEventExt{}
+	if err := json.Unmarshal([]byte(delivery.Payload), event); err != nil {
+		panic(err)
+	}
+
+	deliveryExt := &model.WebhookDeliveryExt{
+		ID:             delivery.ID,
+		WebhookID:      delivery.Webhook.ID,
+		Event:          event,
+		Status:         delivery.Status,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		ReplayOf:       delivery.ReplayOf,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+		Attempts:       make([]*model.WebhookAttemptExt, 0, len(attempts)),
+	}
+
+	for _, attempt := range attempts {
+		deliveryExt.Attempts = append(deliveryExt.Attempts, &model.WebhookAttemptExt{
+			AttemptedAt: attempt.AttemptedAt,
+			StatusCode:  attempt.StatusCode,
+			Error:       attempt.Error,
+			DurationMs:  attempt.DurationMs,
+		})
+	}
+
+	return deliveryExt
+}
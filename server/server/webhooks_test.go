@@ -0,0 +1,385 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"spotify-charter/db"
+	"spotify-charter/model"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type receivedWebhook struct {
+	header http.Header
+	body   string
+}
+
+type webhookReceiver struct {
+	*httptest.Server
+	lock     sync.Mutex
+	received []*receivedWebhook
+	statuses []int
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	receiver := &webhookReceiver{statuses: statuses}
+
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.lock.Lock()
+		defer receiver.lock.Unlock()
+
+		receiver.received = append(receiver.received, &receivedWebhook{header: r.Header, body: string(body)})
+
+		status := http.StatusNoContent
+		if len(receiver.statuses) != 0 {
+			status, receiver.statuses = receiver.statuses[0], receiver.statuses[1:]
+		}
+
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(receiver.Close)
+
+	return receiver
+}
+
+func (receiver *webhookReceiver) requests() []*receivedWebhook {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	return append([]*receivedWebhook(nil), receiver.received...)
+}
+
+func newTestDispatcher(t *testing.T) (*WebhookDispatcher, *db.WebhookStore, *sql.DB) {
+	sqlDB := newTestDB(t)
+
+	reader := db.NewReader(sqlDB)
+	t.Cleanup(reader.Close)
+
+	store := db.NewWebhookStore(sqlDB)
+	t.Cleanup(store.Close)
+
+	return NewWebhookDispatcher(store, reader), store, sqlDB
+}
+
+func createTestWebhook(t *testing.T, store *db.WebhookStore, url string, eventType string) *model.Webhook {
+	webhook := &model.Webhook{URL: url, Secret: "s3cret", EventType: eventType, CountryCode: "SK", CreatedAt: time.Now().Unix()}
+
+	if err := store.CreateWebhook(webhook); err != nil {
+		t.Fatal(err)
+	}
+
+	return webhook
+}
+
+func publishNow(t *testing.T, dispatcher *WebhookDispatcher, commit *db.Commit) {
+	dispatcher.PublishCommit(commit)
+
+	if err := dispatcher.enqueueCommits(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func deliverDueNow(dispatcher *WebhookDispatcher) {
+	dispatcher.deliverDue(context.Background())
+	dispatcher.workers.Wait()
+}
+
+var testCommit = &db.Commit{
+	Charts:      []model.ChartKey{{CountryCode: "SK", ChartType: model.DailyTopTrack, Date: testDate}},
+	CommittedAt: testDate + 3600,
+}
+
+func TestWebhookDeliveryLifecycle(t *testing.T) {
+	dispatcher, store, sqlDB := newTestDispatcher(t)
+	receiver := newWebhookReceiver(t, http.StatusServiceUnavailable)
+
+	dispatcher.client = receiver.Client()
+
+	webhook := createTestWebhook(t, store, receiver.URL, WebhookTrackEntered)
+
+	publishNow(t, dispatcher, testCommit)
+	publishNow(t, dispatcher, testCommit)
+
+	deliveries := store.GetDeliveries(webhook.ID, webhookDeliveryLimit)
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries after committing the same chart twice, want 1", len(deliveries))
+	}
+
+	deliverDueNow(dispatcher)
+
+	requests := receiver.requests()
+	if len(requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(requests))
+	}
+
+	request := requests[0]
+
+	timestamp := request.header.Get(webhookTimestampHeader)
+	if signature := request.header.Get(webhookSignatureHeader); signature != "sha256="+signPayload("s3cret", timestamp, request.body) {
+		t.Errorf("signature %q does not match the payload", signature)
+	}
+
+	event := &model.WebhookEventExt{}
+	if err := json.Unmarshal([]byte(request.body), event); err != nil {
+		t.Fatal(err)
+	}
+
+	if event.Type != WebhookTrackEntered || event.Movement.TrackID != "t0" {
+		t.Errorf("got %s event for track %s, want %s for t0", event.Type, event.Movement.TrackID, WebhookTrackEntered)
+	}
+
+	delivery := store.GetDelivery(webhook.ID, deliveries[0].ID)
+
+	if delivery.Status != model.DeliveryPending || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("after a failed attempt got status %s, %d attempts and last status %d", delivery.Status, delivery.Attempts, delivery.LastStatusCode)
+	}
+
+	if wait := time.Until(time.Unix(delivery.NextAttemptAt, 0)); wait < webhookRetryBaseDelay-2*time.Second || wait > webhookRetryBaseDelay {
+		t.Errorf("next attempt is due in %s, want about %s", wait, webhookRetryBaseDelay)
+	}
+
+	deliverDueNow(dispatcher)
+
+	if len(receiver.requests()) != 1 {
+		t.Fatalf("delivery was retried before its backoff elapsed")
+	}
+
+	if _, err := sqlDB.Exec("UPDATE webhook_deliveries SET next_attempt_at = 0 WHERE id = ?", delivery.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	deliverDueNow(dispatcher)
+
+	delivery = store.GetDelivery(webhook.ID, delivery.ID)
+	if delivery.Status != model.DeliverySucceeded || delivery.Attempts != 2 {
+		t.Fatalf("after the retry got status %s with %d attempts, want %s with 2", delivery.Status, delivery.Attempts, model.DeliverySucceeded)
+	}
+
+	if attempts := store.GetAttempts([]int64{delivery.ID})[delivery.ID]; len(attempts) != 2 {
+		t.Fatalf("got %d recorded attempts, want 2", len(attempts))
+	}
+
+	s := &Server{Reader: dispatcher.reader, Webhooks: dispatcher, AdminToken: "token"}
+	routes := s.Routes()
+
+	replay := httptest.NewRequest(http.MethodPost, "/v1/webhooks/1/deliveries/1/replay", nil)
+	replay.Header.Set("Authorization", "Bearer token")
+
+	res := httptest.NewRecorder()
+	routes.ServeHTTP(res, replay)
+
+	if res.Code != http.StatusAccepted {
+		t.Fatalf("replay responded with %d: %s", res.Code, res.Body)
+	}
+
+	deliverDueNow(dispatcher)
+
+	requests = receiver.requests()
+	if len(requests) != 3 {
+		t.Fatalf("receiver got %d requests after the replay, want 3", len(requests))
+	}
+
+	if requests[2].body != requests[0].body || requests[2].header.Get(webhookDeliveryHeader) == requests[0].header.Get(webhookDeliveryHeader) {
+		t.Errorf("replay should resend the original payload as a new delivery")
+	}
+}
+
+func lockTestDB(t *testing.T, sqlDB *sql.DB) func() {
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.ExecContext(context.Background(), "BEGIN IMMEDIATE"); err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		conn.ExecContext(context.Background(), "ROLLBACK")
+		conn.Close()
+	}
+}
+
+func TestWebhookDeliveryWhileDBIsBusy(t *testing.T) {
+	dispatcher, store, sqlDB := newTestDispatcher(t)
+
+	var received atomic.Int32
+
+	locked := make(chan func(), 1)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		locked <- lockTestDB(t, sqlDB)
+	}))
+	defer receiver.Close()
+
+	dispatcher.client = receiver.Client()
+
+	webhook := createTestWebhook(t, store, receiver.URL, WebhookTrackEntered)
+
+	publishNow(t, dispatcher, testCommit)
+
+	unlockBeforeClaim := lockTestDB(t, sqlDB)
+
+	deliverDueNow(dispatcher)
+
+	if received.Load() != 0 {
+		t.Fatalf("delivered without claiming the delivery first")
+	}
+
+	s := &Server{Reader: dispatcher.reader, Webhooks: dispatcher, AdminToken: "token"}
+
+	create := httptest.NewRequest(http.MethodPost, "/v1/webhooks", strings.NewReader(`{"url":"https://example.com/hook","event":"track.entered"}`))
+	create.Header.Set("Authorization", "Bearer token")
+
+	res := httptest.NewRecorder()
+	s.Routes().ServeHTTP(res, create)
+
+	if res.Code != http.StatusServiceUnavailable || len(res.Header().Get("Retry-After")) == 0 {
+		t.Errorf("creating a webhook on a busy DB responded with %d, want 503 with Retry-After", res.Code)
+	}
+
+	unlockBeforeClaim()
+
+	go func() {
+		unlock := <-locked
+		time.Sleep(3 * webhookBusyRetryDelay / 2)
+		unlock()
+	}()
+
+	deliverDueNow(dispatcher)
+
+	delivery := store.GetDeliveries(webhook.ID, webhookDeliveryLimit)[0]
+
+	if received.Load() != 1 || delivery.Status != model.DeliverySucceeded || delivery.Attempts != 1 {
+		t.Errorf("got %d requests and a %s delivery with %d attempts, want one recorded successful attempt",
+			received.Load(), delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestWebhookCommitDoesNotWaitForBusyDB(t *testing.T) {
+	dispatcher, store, sqlDB := newTestDispatcher(t)
+
+	webhook := createTestWebhook(t, store, "https://example.com/hook", WebhookTrackEntered)
+
+	unlock := lockTestDB(t, sqlDB)
+
+	started := time.Now()
+	dispatcher.PublishCommit(testCommit)
+
+	if elapsed := time.Since(started); elapsed > webhookBusyRetryDelay/2 {
+		t.Errorf("publishing a commit on a busy DB took %s", elapsed)
+	}
+
+	if err := dispatcher.enqueueCommits(); err != db.ErrBusy {
+		t.Fatalf("enqueueing on a busy DB returned %v, want %v", err, db.ErrBusy)
+	}
+
+	unlock()
+
+	if err := dispatcher.enqueueCommits(); err != nil {
+		t.Fatal(err)
+	}
+
+	if deliveries := store.GetDeliveries(webhook.ID, webhookDeliveryLimit); len(deliveries) != 1 {
+		t.Errorf("got %d deliveries once the DB was free again, want 1", len(deliveries))
+	}
+}
+
+func TestWebhookDeliveryIsConcurrentPerWebhook(t *testing.T) {
+	dispatcher, store, _ := newTestDispatcher(t)
+
+	release := make(chan bool)
+	arrived := make(chan string, 2)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- r.URL.Path
+
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+	}))
+	defer receiver.Close()
+	defer dispatcher.workers.Wait()
+	defer close(release)
+
+	dispatcher.client = receiver.Client()
+
+	createTestWebhook(t, store, receiver.URL+"/slow", WebhookTrackEntered)
+	createTestWebhook(t, store, receiver.URL+"/fast", WebhookTrackEntered)
+
+	publishNow(t, dispatcher, testCommit)
+	dispatcher.deliverDue(context.Background())
+
+	paths := make(map[string]bool)
+
+	for range 2 {
+		select {
+		case path := <-arrived:
+			paths[path] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %v were delivered while the slow receiver was blocked", paths)
+		}
+	}
+}
+
+func TestWebhookDeliveryRefusesLoopback(t *testing.T) {
+	dispatcher, store, _ := newTestDispatcher(t)
+	receiver := newWebhookReceiver(t)
+
+	webhook := createTestWebhook(t, store, receiver.URL, WebhookTrackEntered)
+
+	publishNow(t, dispatcher, testCommit)
+	deliverDueNow(dispatcher)
+
+	if len(receiver.requests()) != 0 {
+		t.Fatalf("delivered to a loopback receiver")
+	}
+
+	delivery := store.GetDeliveries(webhook.ID, webhookDeliveryLimit)[0]
+	if !strings.Contains(delivery.LastError, "non-public address") {
+		t.Errorf("got last error %q, want a refused non-public address", delivery.LastError)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{5, 160 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{64, time.Hour},
+	}
+
+	for _, test := range tests {
+		if got := retryDelay(test.attempts); got != test.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", test.attempts, got, test.want)
+		}
+	}
+}
+
+func TestNewWebhookRejectsInternalTargets(t *testing.T) {
+	for _, target := range []string{"http://169.254.169.254/latest", "http://127.0.0.1:8080/", "http://[::1]/", "http://10.1.2.3/", "http://localhost/", "ftp://example.com/"} {
+		if _, apiErr := newWebhook(&model.WebhookRequestExt{URL: target, Event: WebhookTrackEntered}); apiErr == nil {
+			t.Errorf("accepted webhook URL %q", target)
+		}
+	}
+
+	if _, apiErr := newWebhook(&model.WebhookRequestExt{URL: "https://example.com/hook", Event: WebhookTrackEntered}); apiErr != nil {
+		t.Errorf("rejected a public webhook URL: %s", apiErr)
+	}
+}
@@ -0,0 +1,37 @@
+package spotify
+
+import "spotify-charter/model"
+
+const MaxAlbumIDs = 20
+
+type GetAlbumsResp struct {
+	Albums []*Album `json:"albums"`
+}
+
+func (c APICLient) GetAlbums(ids []string) ([]*model.Album, error) {
+	body, err := c.fetchIDs("/v1/albums", ids, model.AlbumsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseAlbums(body)
+}
+
+func ParseAlbums(body []byte) ([]*model.Album, error) {
+	resp, err := decodeBody[GetAlbumsResp](body)
+	if err != nil {
+		return nil, err
+	}
+
+	albums := make([]*model.Album, 0)
+
+	for _, spotifyAlbum := range resp.Albums {
+		if spotifyAlbum == nil {
+			continue
+		}
+
+		albums = append(albums, spotifyAlbumToAlbum(spotifyAlbum))
+	}
+
+	return albums, nil
+}
@@ -0,0 +1,58 @@
+package spotify
+
+import "spotify-charter/model"
+
+const MaxArtistIDs = 50
+
+type Followers struct {
+	Total int `json:"total"`
+}
+
+type FullArtist struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Genres     []string  `json:"genres"`
+	Popularity int       `json:"popularity"`
+	Followers  Followers `json:"followers"`
+}
+
+type GetArtistsResp struct {
+	Artists []*FullArtist `json:"artists"`
+}
+
+func (c APICLient) GetArtists(ids []string) ([]*model.Artist, error) {
+	body, err := c.fetchIDs("/v1/artists", ids, model.ArtistsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseArtists(body)
+}
+
+func ParseArtists(body []byte) ([]*model.Artist, error) {
+	resp, err := decodeBody[GetArtistsResp](body)
+	if err != nil {
+		return nil, err
+	}
+
+	artists := make([]*model.Artist, 0)
+
+	for _, spotifyArtist := range resp.Artists {
+		if spotifyArtist == nil {
+			continue
+		}
+
+		genres := make([]string, 0, len(spotifyArtist.Genres))
+		genres = append(genres, spotifyArtist.Genres...)
+
+		artists = append(artists, &model.Artist{
+			SpotifyID:  spotifyArtist.ID,
+			Name:       spotifyArtist.Name,
+			Genres:     genres,
+			Popularity: spotifyArtist.Popularity,
+			Followers:  spotifyArtist.Followers.Total,
+		})
+	}
+
+	return artists, nil
+}
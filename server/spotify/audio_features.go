@@ -0,0 +1,67 @@
+package spotify
+
+import "spotify-charter/model"
+
+const MaxAudioFeaturesIDs = 100
+
+type AudioFeatures struct {
+	ID               string  `json:"id"`
+	Tempo            float64 `json:"tempo"`
+	Key              int     `json:"key"`
+	Mode             int     `json:"mode"`
+	TimeSignature    int     `json:"time_signature"`
+	Energy           float64 `json:"energy"`
+	Danceability     float64 `json:"danceability"`
+	Valence          float64 `json:"valence"`
+	Acousticness     float64 `json:"acousticness"`
+	Instrumentalness float64 `json:"instrumentalness"`
+	Liveness         float64 `json:"liveness"`
+	Speechiness      float64 `json:"speechiness"`
+	Loudness         float64 `json:"loudness"`
+}
+
+type GetAudioFeaturesResp struct {
+	AudioFeatures []*AudioFeatures `json:"audio_features"`
+}
+
+func (c APICLient) GetAudioFeatures(ids []string) ([]*model.AudioFeatures, error) {
+	body, err := c.fetchIDs("/v1/audio-features", ids, model.AudioFeaturesResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseAudioFeatures(body)
+}
+
+func ParseAudioFeatures(body []byte) ([]*model.AudioFeatures, error) {
+	resp, err := decodeBody[GetAudioFeaturesResp](body)
+	if err != nil {
+		return nil, err
+	}
+
+	audioFeatures := make([]*model.AudioFeatures, 0)
+
+	for _, spotifyFeatures := range resp.AudioFeatures {
+		if spotifyFeatures == nil {
+			continue
+		}
+
+		audioFeatures = append(audioFeatures, &model.AudioFeatures{
+			TrackID:          spotifyFeatures.ID,
+			Tempo:            spotifyFeatures.Tempo,
+			Key:              spotifyFeatures.Key,
+			Mode:             spotifyFeatures.Mode,
+			TimeSignature:    spotifyFeatures.TimeSignature,
+			Energy:           spotifyFeatures.Energy,
+			Danceability:     spotifyFeatures.Danceability,
+			Valence:          spotifyFeatures.Valence,
+			Acousticness:     spotifyFeatures.Acousticness,
+			Instrumentalness: spotifyFeatures.Instrumentalness,
+			Liveness:         spotifyFeatures.Liveness,
+			Speechiness:      spotifyFeatures.Speechiness,
+			Loudness:         spotifyFeatures.Loudness,
+		})
+	}
+
+	return audioFeatures, nil
+}
@@ -40,9 +40,7 @@ func (c *APICLient) Authorize() error {
 	req.Header.Add("Authorization", "Basic "+basicAuth)
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
-	client := http.DefaultClient
-
-	res, err := client.Do(req)
+	res, err := c.authClient.Do(req)
 	if err != nil {
 		return err
 	}
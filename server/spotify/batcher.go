@@ -0,0 +1,151 @@
+package spotify
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const batchWait = 50 * time.Millisecond
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrBatcherClosed = errors.New("batcher closed")
+)
+
+type batchResult[T any] struct {
+	value *T
+	err   error
+}
+
+type batchRequest[T any] struct {
+	id     string
+	result chan batchResult[T]
+}
+
+type Batcher[T any] struct {
+	maxIDs   int
+	wait     time.Duration
+	fetch    func(ids []string) ([]*T, error)
+	key      func(value *T) string
+	requests chan batchRequest[T]
+	done     chan bool
+}
+
+func NewBatcher[T any](maxIDs int, wait time.Duration, fetch func(ids []string) ([]*T, error), key func(value *T) string) *Batcher[T] {
+	batcher := &Batcher[T]{
+		maxIDs:   maxIDs,
+		wait:     wait,
+		fetch:    fetch,
+		key:      key,
+		requests: make(chan batchRequest[T]),
+		done:     make(chan bool),
+	}
+
+	go batcher.batchingRoutine()
+
+	return batcher
+}
+
+func (b *Batcher[T]) Get(id string) (*T, error) {
+	request := batchRequest[T]{
+		id:     id,
+		result: make(chan batchResult[T], 1),
+	}
+
+	select {
+	case b.requests <- request:
+	case <-b.done:
+		return nil, ErrBatcherClosed
+	}
+
+	result := <-request.result
+
+	return result.value, result.err
+}
+
+func (b *Batcher[T]) Close() {
+	close(b.done)
+}
+
+func (b *Batcher[T]) batchingRoutine() {
+	pending := make(map[string][]chan batchResult[T])
+
+	timer := time.NewTimer(b.wait)
+	timer.Stop()
+
+	for {
+		select {
+		case request := <-b.requests:
+			if len(pending) == 0 {
+				timer.Reset(b.wait)
+			}
+
+			pending[request.id] = append(pending[request.id], request.result)
+
+			if len(pending) >= b.maxIDs {
+				stopTimer(timer)
+
+				go b.dispatch(pending)
+
+				pending = make(map[string][]chan batchResult[T])
+			}
+		case <-timer.C:
+			if len(pending) != 0 {
+				go b.dispatch(pending)
+
+				pending = make(map[string][]chan batchResult[T])
+			}
+		case <-b.done:
+			stopTimer(timer)
+
+			if len(pending) != 0 {
+				b.dispatch(pending)
+			}
+
+			return
+		}
+	}
+}
+
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+}
+
+func (b *Batcher[T]) dispatch(pending map[string][]chan batchResult[T]) {
+	ids := make([]string, 0, len(pending))
+
+	for id := range pending {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	values, err := b.fetch(ids)
+
+	found := make(map[string]*T, len(values))
+
+	for _, value := range values {
+		found[b.key(value)] = value
+	}
+
+	for id, results := range pending {
+		result := batchResult[T]{
+			value: found[id],
+			err:   err,
+		}
+
+		if result.err == nil && result.value == nil {
+			result.err = ErrNotFound
+		}
+
+		for _, resultChan := range results {
+			resultChan <- result
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package spotify
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type batchedItem struct {
+	ID string
+}
+
+func TestBatcherSplitsAndFansOut(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+
+	tests := []struct {
+		name    string
+		maxIDs  int
+		ids     []string
+		missing []string
+		err     error
+		batches int
+	}{
+		{"single request", 3, []string{"a"}, nil, nil, 1},
+		{"full batch", 3, []string{"a", "b", "c"}, nil, nil, 1},
+		{"split batches", 3, []string{"a", "b", "c", "d", "e", "f", "g"}, nil, nil, 3},
+		{"duplicates share a lookup", 3, []string{"a", "a", "b", "b"}, nil, nil, 1},
+		{"missing items", 3, []string{"a", "b"}, []string{"b"}, nil, 1},
+		{"failed fetch", 3, []string{"a", "a", "b"}, nil, errFetch, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var lock sync.Mutex
+
+			batches := make([][]string, 0)
+
+			batcher := NewBatcher(test.maxIDs, 20*time.Millisecond, func(ids []string) ([]*batchedItem, error) {
+				lock.Lock()
+				batches = append(batches, ids)
+				lock.Unlock()
+
+				if test.err != nil {
+					return nil, test.err
+				}
+
+				items := make([]*batchedItem, 0, len(ids))
+				for _, id := range ids {
+					if !slices.Contains(test.missing, id) {
+						items = append(items, &batchedItem{ID: id})
+					}
+				}
+
+				return items, nil
+			}, func(item *batchedItem) string { return item.ID })
+			defer batcher.Close()
+
+			results := make([]string, len(test.ids))
+
+			var wait sync.WaitGroup
+
+			for index, id := range test.ids {
+				wait.Add(1)
+
+				go func() {
+					defer wait.Done()
+
+					item, err := batcher.Get(id)
+					if err != nil {
+						results[index] = err.Error()
+					} else {
+						results[index] = item.ID
+					}
+				}()
+			}
+
+			wait.Wait()
+
+			for index, id := range test.ids {
+				want := id
+				if test.err != nil {
+					want = test.err.Error()
+				} else if slices.Contains(test.missing, id) {
+					want = ErrNotFound.Error()
+				}
+
+				if results[index] != want {
+					t.Errorf("Get(%q) returned %q, want %q", id, results[index], want)
+				}
+			}
+
+			if len(batches) != test.batches {
+				t.Fatalf("fetched %d batches %v, want %d", len(batches), batches, test.batches)
+			}
+
+			fetched := make([]string, 0)
+			for _, batch := range batches {
+				if len(batch) > test.maxIDs {
+					t.Errorf("fetched %d IDs in one batch, want at most %d", len(batch), test.maxIDs)
+				}
+
+				fetched = append(fetched, batch...)
+			}
+
+			slices.Sort(fetched)
+
+			unique := slices.Clone(test.ids)
+			slices.Sort(unique)
+
+			if unique = slices.Compact(unique); !slices.Equal(fetched, unique) {
+				t.Errorf("fetched %v, want each of %v once", fetched, unique)
+			}
+		})
+	}
+}
+
+func TestBatcherGetAfterClose(t *testing.T) {
+	batcher := NewBatcher(3, time.Hour, func(ids []string) ([]*batchedItem, error) {
+		items := make([]*batchedItem, 0, len(ids))
+		for _, id := range ids {
+			items = append(items, &batchedItem{ID: id})
+		}
+
+		return items, nil
+	}, func(item *batchedItem) string { return item.ID })
+
+	pending := make(chan error, 1)
+
+	go func() {
+		_, err := batcher.Get("a")
+		pending <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	batcher.Close()
+
+	select {
+	case err := <-pending:
+		if err != nil && !errors.Is(err, ErrBatcherClosed) {
+			t.Errorf("a Get pending at Close returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("a Get pending at Close never returned")
+	}
+
+	closed := make(chan error, 1)
+
+	go func() {
+		_, err := batcher.Get("b")
+		closed <- err
+	}()
+
+	select {
+	case err := <-closed:
+		if !errors.Is(err, ErrBatcherClosed) {
+			t.Errorf("Get after Close returned %v, want %v", err, ErrBatcherClosed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Get after Close blocked")
+	}
+}
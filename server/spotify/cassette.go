@@ -0,0 +1,337 @@
+package spotify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type CassetteMode string
+
+const (
+	Passthrough CassetteMode = "passthrough"
+	Record      CassetteMode = "record"
+	Replay      CassetteMode = "replay"
+)
+
+const (
+	cassetteExt   = ".json"
+	redactedValue = "REDACTED"
+)
+
+var scrubbedFields = []string{"access_token", "refresh_token"}
+
+type Cassette struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        string      `json:"body"`
+}
+
+type CassetteTransport struct {
+	core      http.RoundTripper
+	mode      CassetteMode
+	dir       string
+	mutex     sync.Mutex
+	cassettes map[string]*Cassette
+}
+
+func ParseCassetteMode(s string) (CassetteMode, bool) {
+	switch mode := CassetteMode(s); mode {
+	case Passthrough, Record, Replay:
+		return mode, true
+	}
+
+	return "", false
+}
+
+func NewCassetteTransport(core http.RoundTripper, mode CassetteMode, dir string) (*CassetteTransport, error) {
+	transport := &CassetteTransport{
+		core:      core,
+		mode:      mode,
+		dir:       dir,
+		cassettes: make(map[string]*Cassette),
+	}
+
+	switch mode {
+	case Record:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	case Replay:
+		if err := transport.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return transport, nil
+}
+
+func (t *CassetteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.mode != Record && t.mode != Replay {
+		return t.core.RoundTrip(r)
+	}
+
+	requestBody, err := readRequestBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cassetteKey(r.Method, r.URL.String(), requestBody)
+
+	if t.mode == Replay {
+		return t.replay(r, key)
+	}
+
+	res, err := t.core.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette := &Cassette{
+		Method:      r.Method,
+		URL:         r.URL.String(),
+		RequestBody: requestBody,
+		Status:      res.StatusCode,
+		Header:      res.Header.Clone(),
+		Body:        scrubBody(body),
+	}
+
+	cassette.Header.Del("Set-Cookie")
+
+	if err := t.save(key, cassette); err != nil {
+		return nil, err
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	return res, nil
+}
+
+func (t *CassetteTransport) replay(r *http.Request, key string) (*http.Response, error) {
+	t.mutex.Lock()
+	cassette := t.cassettes[key]
+	t.mutex.Unlock()
+
+	if cassette == nil {
+		var err error
+
+		if cassette, err = t.assembleBatch(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cassette.Status, http.StatusText(cassette.Status)),
+		StatusCode:    cassette.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cassette.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(cassette.Body)),
+		ContentLength: int64(len(cassette.Body)),
+		Request:       r,
+	}, nil
+}
+
+func (t *CassetteTransport) assembleBatch(r *http.Request) (*Cassette, error) {
+	ids := r.URL.Query().Get("ids")
+	if r.Method != http.MethodGet || len(ids) == 0 {
+		return nil, fmt.Errorf("no cassette for %s %s", r.Method, r.URL)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var field string
+
+	items := make(map[string]json.RawMessage)
+
+	for _, cassette := range t.cassettes {
+		recordedField, recordedItems, ok := splitBatch(cassette, r.URL.Path)
+		if !ok {
+			continue
+		}
+
+		field = recordedField
+
+		for id, item := range recordedItems {
+			items[id] = item
+		}
+	}
+
+	assembled := make([]json.RawMessage, 0)
+
+	for _, id := range strings.Split(ids, ",") {
+		item, ok := items[id]
+		if !ok {
+			return nil, fmt.Errorf("no cassette for %s %s", r.Method, r.URL)
+		}
+
+		assembled = append(assembled, item)
+	}
+
+	body, err := json.Marshal(map[string][]json.RawMessage{field: assembled})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cassette{
+		Method: r.Method,
+		URL:    r.URL.String(),
+		Status: http.StatusOK,
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   string(body),
+	}, nil
+}
+
+func splitBatch(cassette *Cassette, path string) (string, map[string]json.RawMessage, bool) {
+	if cassette.Method != http.MethodGet || cassette.Status != http.StatusOK {
+		return "", nil, false
+	}
+
+	recordedURL, err := http.NewRequest(cassette.Method, cassette.URL, nil)
+	if err != nil || recordedURL.URL.Path != path {
+		return "", nil, false
+	}
+
+	ids := recordedURL.URL.Query().Get("ids")
+	if len(ids) == 0 {
+		return "", nil, false
+	}
+
+	var resp map[string][]json.RawMessage
+
+	if err := json.Unmarshal([]byte(cassette.Body), &resp); err != nil || len(resp) != 1 {
+		return "", nil, false
+	}
+
+	for field, recordedItems := range resp {
+		recordedIDs := strings.Split(ids, ",")
+		if len(recordedIDs) != len(recordedItems) {
+			return "", nil, false
+		}
+
+		items := make(map[string]json.RawMessage, len(recordedIDs))
+
+		for index, id := range recordedIDs {
+			items[id] = recordedItems[index]
+		}
+
+		return field, items, true
+	}
+
+	return "", nil, false
+}
+
+func (t *CassetteTransport) save(key string, cassette *Cassette) error {
+	data, err := json.MarshalIndent(cassette, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return os.WriteFile(filepath.Join(t.dir, key+cassetteExt), data, 0o644)
+}
+
+func (t *CassetteTransport) load() error {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != cassetteExt {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(t.dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		cassette := &Cassette{}
+
+		if err := json.Unmarshal(data, cassette); err != nil {
+			return fmt.Errorf("cassette '%s': %w", entry.Name(), err)
+		}
+
+		t.cassettes[strings.TrimSuffix(entry.Name(), cassetteExt)] = cassette
+	}
+
+	if len(t.cassettes) == 0 {
+		return errors.New("no cassettes found in '" + t.dir + "'")
+	}
+
+	return nil
+}
+
+func readRequestBody(r *http.Request) (string, error) {
+	if r.Body == nil {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return string(body), nil
+}
+
+func cassetteKey(method string, url string, body string) string {
+	hash := sha256.Sum256([]byte(method + " " + url + "\n" + body))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func scrubBody(body []byte) string {
+	var fields map[string]json.RawMessage
+
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return string(body)
+	}
+
+	scrubbed := false
+
+	for _, field := range scrubbedFields {
+		if _, ok := fields[field]; ok {
+			fields[field] = json.RawMessage(`"` + redactedValue + `"`)
+			scrubbed = true
+		}
+	}
+
+	if !scrubbed {
+		return string(body)
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return string(body)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,177 @@
+package spotify
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+var recordedResponses = map[string]string{
+	"/api/token":       `{"access_token":"secret-token","token_type":"Bearer","expires_in":3600}`,
+	"/v1/albums":       `{"albums":[{"id":"a1","name":"One"},{"id":"a2","name":"Two"}]}`,
+	"/v1/artists/r1":   `{"id":"r1","name":"Art One"}`,
+	"/v1/audio-fields": `not json`,
+}
+
+func cassetteRoundTrip(t *testing.T, transport http.RoundTripper, method string, rawURL string, body string) (int, http.Header, string, error) {
+	var requestBody io.Reader
+	if len(body) != 0 {
+		requestBody = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, rawURL, requestBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return 0, nil, "", err
+	}
+
+	defer res.Body.Close()
+
+	payload, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res.StatusCode, res.Header, string(payload), nil
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	recorded := 0
+
+	core := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorded++
+
+		res := jsonResponse(req, recordedResponses[req.URL.Path])
+		res.Header.Set("Set-Cookie", "session=1")
+
+		return res, nil
+	})
+
+	recorder, err := NewCassetteTransport(core, Record, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{http.MethodPost, "https://accounts.spotify.com/api/token", "grant_type=client_credentials"},
+		{http.MethodGet, "https://api.spotify.com/v1/albums?ids=a1,a2", ""},
+		{http.MethodGet, "https://api.spotify.com/v1/artists/r1", ""},
+		{http.MethodGet, "https://api.spotify.com/v1/audio-fields", ""},
+	}
+
+	for _, request := range requests {
+		if _, _, body, err := cassetteRoundTrip(t, recorder, request.method, request.url, request.body); err != nil {
+			t.Fatal(err)
+		} else if parsed, _ := url.Parse(request.url); body != recordedResponses[parsed.Path] {
+			t.Errorf("recording %s returned %q, want the unscrubbed response", request.url, body)
+		}
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != len(requests) || recorded != len(requests) {
+		t.Fatalf("recorded %d requests into %d cassettes, want %d", recorded, len(entries), len(requests))
+	}
+
+	replayer, err := NewCassetteTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("replay reached the network")
+	}), Replay, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		want   string
+	}{
+		{http.MethodPost, "https://accounts.spotify.com/api/token", "grant_type=client_credentials", `{"access_token":"REDACTED","expires_in":3600,"token_type":"Bearer"}`},
+		{http.MethodGet, "https://api.spotify.com/v1/albums?ids=a1,a2", "", recordedResponses["/v1/albums"]},
+		{http.MethodGet, "https://api.spotify.com/v1/albums?ids=a2", "", `{"albums":[{"id":"a2","name":"Two"}]}`},
+		{http.MethodGet, "https://api.spotify.com/v1/albums?ids=a2,a1", "", `{"albums":[{"id":"a2","name":"Two"},{"id":"a1","name":"One"}]}`},
+		{http.MethodGet, "https://api.spotify.com/v1/artists/r1", "", recordedResponses["/v1/artists/r1"]},
+		{http.MethodGet, "https://api.spotify.com/v1/audio-fields", "", "not json"},
+		{http.MethodPost, "https://accounts.spotify.com/api/token", "grant_type=refresh_token", ""},
+		{http.MethodGet, "https://api.spotify.com/v1/albums?ids=a1,a3", "", ""},
+		{http.MethodGet, "https://api.spotify.com/v1/artists/r2", "", ""},
+	}
+
+	for _, test := range tests {
+		status, header, body, err := cassetteRoundTrip(t, replayer, test.method, test.url, test.body)
+
+		if len(test.want) == 0 {
+			if err == nil || !strings.HasPrefix(err.Error(), "no cassette for") {
+				t.Errorf("replaying unrecorded %s %s got error %v, want a missing cassette", test.method, test.url, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("replaying %s %s: %s", test.method, test.url, err)
+			continue
+		}
+
+		if status != http.StatusOK || body != test.want {
+			t.Errorf("replaying %s %s returned %d %q, want 200 %q", test.method, test.url, status, body, test.want)
+		}
+
+		if len(header.Get("Set-Cookie")) != 0 {
+			t.Errorf("replaying %s %s returned a recorded cookie", test.method, test.url)
+		}
+	}
+}
+
+func TestCassetteModes(t *testing.T) {
+	forwarded := 0
+
+	core := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		forwarded++
+
+		return jsonResponse(req, `{}`), nil
+	})
+
+	passthrough, err := NewCassetteTransport(core, Passthrough, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := cassetteRoundTrip(t, passthrough, http.MethodGet, "https://api.spotify.com/v1/artists/r1", ""); err != nil || forwarded != 1 {
+		t.Errorf("passthrough forwarded %d requests with error %v, want 1", forwarded, err)
+	}
+
+	if _, err := NewCassetteTransport(core, Replay, t.TempDir()); err == nil {
+		t.Errorf("replaying from an empty directory did not fail")
+	}
+
+	tests := []struct {
+		value string
+		mode  CassetteMode
+		ok    bool
+	}{
+		{"passthrough", Passthrough, true},
+		{"record", Record, true},
+		{"replay", Replay, true},
+		{"", "", false},
+		{"Replay", "", false},
+	}
+
+	for _, test := range tests {
+		if mode, ok := ParseCassetteMode(test.value); mode != test.mode || ok != test.ok {
+			t.Errorf("ParseCassetteMode(%q) = %q, %t, want %q, %t", test.value, mode, ok, test.mode, test.ok)
+		}
+	}
+}
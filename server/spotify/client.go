@@ -1,36 +1,141 @@
 package spotify
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
+	"spotify-charter/model"
+	"strings"
 )
 
 const baseURL = "https://api.spotify.com"
 
 type APICLient struct {
-	httpClient   *http.Client
-	clientID     string
-	clientSecret string
-	accessToken  string
+	httpClient           *http.Client
+	authClient           *http.Client
+	clientID             string
+	clientSecret         string
+	accessToken          string
+	trackBatcher         *Batcher[model.Track]
+	albumBatcher         *Batcher[model.Album]
+	artistBatcher        *Batcher[model.Artist]
+	audioFeaturesBatcher *Batcher[model.AudioFeatures]
+	responseHook         ResponseHook
+	ctx                  context.Context
 }
 
-func NewAPIClient(clientID string, clientSecret string) *APICLient {
+type ResponseHook func(kind model.ResponseKind, body []byte)
+
+func NewAPIClient(clientID string, clientSecret string, core http.RoundTripper) *APICLient {
 	client := &APICLient{
 		clientID:     clientID,
 		clientSecret: clientSecret,
 	}
 
+	client.authClient = &http.Client{
+		Transport: core,
+	}
+
 	client.httpClient = &http.Client{
 		Transport: AuthInterceptor{
-			core:        http.DefaultTransport,
+			core:        core,
 			accessToken: &client.accessToken,
 		},
 	}
 
+	client.trackBatcher = NewBatcher(MaxTrackIDs, batchWait,
+		func(ids []string) ([]*model.Track, error) { return client.GetTracks(ids) },
+		func(track *model.Track) string { return track.SpotifyID })
+
+	client.albumBatcher = NewBatcher(MaxAlbumIDs, batchWait,
+		func(ids []string) ([]*model.Album, error) { return client.GetAlbums(ids) },
+		func(album *model.Album) string { return album.SpotifyID })
+
+	client.artistBatcher = NewBatcher(MaxArtistIDs, batchWait,
+		func(ids []string) ([]*model.Artist, error) { return client.GetArtists(ids) },
+		func(artist *model.Artist) string { return artist.SpotifyID })
+
+	client.audioFeaturesBatcher = NewBatcher(MaxAudioFeaturesIDs, batchWait,
+		func(ids []string) ([]*model.AudioFeatures, error) { return client.GetAudioFeatures(ids) },
+		func(audioFeatures *model.AudioFeatures) string { return audioFeatures.TrackID })
+
 	return client
 }
 
+func (c *APICLient) Close() {
+	c.trackBatcher.Close()
+	c.albumBatcher.Close()
+	c.artistBatcher.Close()
+	c.audioFeaturesBatcher.Close()
+}
+
+func (c *APICLient) SetResponseHook(hook ResponseHook) {
+	c.responseHook = hook
+}
+
+func (c *APICLient) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+func (c *APICLient) GetTrack(id string) (*model.Track, error) {
+	return c.trackBatcher.Get(id)
+}
+
+func (c *APICLient) GetAlbum(id string) (*model.Album, error) {
+	return c.albumBatcher.Get(id)
+}
+
+func (c *APICLient) GetArtist(id string) (*model.Artist, error) {
+	return c.artistBatcher.Get(id)
+}
+
+func (c *APICLient) GetTrackAudioFeatures(id string) (*model.AudioFeatures, error) {
+	return c.audioFeaturesBatcher.Get(id)
+}
+
+func (c APICLient) fetch(req *http.Request) ([]byte, error) {
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, regErrRespToErr(res)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+func (c APICLient) fetchIDs(path string, ids []string, kind model.ResponseKind) ([]byte, error) {
+	req, err := http.NewRequest("GET", baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	query := req.URL.Query()
+	query.Add("ids", strings.Join(ids, ","))
+
+	req.URL.RawQuery = query.Encode()
+
+	body, err := c.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.responseHook != nil {
+		c.responseHook(kind, body)
+	}
+
+	return body, nil
+}
+
 func decodeResp[T interface{}](body *io.ReadCloser) (*T, error) {
 	var resp T
 
@@ -40,3 +145,13 @@ func decodeResp[T interface{}](body *io.ReadCloser) (*T, error) {
 
 	return &resp, nil
 }
+
+func decodeBody[T interface{}](body []byte) (*T, error) {
+	var resp T
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,69 @@
+package spotify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"spotify-charter/model"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type contextKey struct{}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func jsonResponse(req *http.Request, body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+func TestBatchedLookupsUseClientSettings(t *testing.T) {
+	var lock sync.Mutex
+
+	contexts := make([]any, 0)
+
+	client := NewAPIClient("id", "secret", roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		lock.Lock()
+		contexts = append(contexts, req.Context().Value(contextKey{}))
+		lock.Unlock()
+
+		return jsonResponse(req, `{"albums":[{"id":"a1","name":"One","label":"Label"}]}`), nil
+	}))
+	defer client.Close()
+
+	hooked := make([]model.ResponseKind, 0)
+
+	client.SetResponseHook(func(kind model.ResponseKind, body []byte) {
+		lock.Lock()
+		hooked = append(hooked, kind)
+		lock.Unlock()
+	})
+	client.SetContext(context.WithValue(context.Background(), contextKey{}, "scrape"))
+
+	album, err := client.GetAlbum("a1")
+	if err != nil {
+		t.Fatalf("GetAlbum failed: %s", err)
+	}
+
+	if album.Label != "Label" {
+		t.Errorf("got label %q, want %q", album.Label, "Label")
+	}
+
+	if len(hooked) != 1 || hooked[0] != model.AlbumsResponse {
+		t.Errorf("response hook ran for %v, want one %s response", hooked, model.AlbumsResponse)
+	}
+
+	if len(contexts) != 1 || contexts[0] != "scrape" {
+		t.Errorf("requests carried contexts %v, want the client context", contexts)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 )
 
 type AuthErrResp struct {
@@ -23,17 +24,40 @@ func authErrRespToErr(body *io.ReadCloser) error {
 }
 
 type RegErrResp struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
+	Error struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"error"`
 }
 
-func regErrRespToErr(body *io.ReadCloser) error {
-	resp, err := decodeResp[RegErrResp](body)
-	if err != nil {
-		return err
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api err [%d]: %s", e.Status, e.Message)
+}
+
+func IsUnavailable(err error) bool {
+	var apiErr *APIError
+
+	if !errors.As(err, &apiErr) {
+		return false
 	}
 
-	msg := fmt.Sprintf("api err [%d]: %s", resp.Status, resp.Message)
+	return apiErr.Status == http.StatusNotFound || apiErr.Status == http.StatusForbidden
+}
 
-	return errors.New(msg)
+func regErrRespToErr(res *http.Response) error {
+	apiErr := &APIError{
+		Status:  res.StatusCode,
+		Message: http.StatusText(res.StatusCode),
+	}
+
+	if resp, err := decodeResp[RegErrResp](&res.Body); err == nil && len(resp.Error.Message) != 0 {
+		apiErr.Message = resp.Error.Message
+	}
+
+	return apiErr
 }
@@ -1,14 +1,27 @@
 package spotify
 
 import (
+	"encoding/json"
 	"net/http"
 	"spotify-charter/model"
+	"strconv"
 )
 
+type Copyright struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
 type Album struct {
-	ID     string  `json:"id"`
-	Name   string  `json:"name"`
-	Images []Image `json:"images"`
+	ID                   string      `json:"id"`
+	Name                 string      `json:"name"`
+	Images               []Image     `json:"images"`
+	ReleaseDate          string      `json:"release_date"`
+	ReleaseDatePrecision string      `json:"release_date_precision"`
+	AlbumType            string      `json:"album_type"`
+	TotalTracks          int         `json:"total_tracks"`
+	Label                string      `json:"label"`
+	Copyrights           []Copyright `json:"copyrights"`
 }
 
 type Artists struct {
@@ -21,66 +34,151 @@ type Image struct {
 	Width uint   `json:"width"`
 }
 
+type LinkedFrom struct {
+	ID string `json:"id"`
+}
+
+type ExternalIDs struct {
+	ISRC string `json:"isrc"`
+}
+
 type Track struct {
-	ID      string    `json:"id"`
-	Name    string    `json:"name"`
-	Album   Album     `json:"album"`
-	Artists []Artists `json:"artists"`
+	Type        string      `json:"type"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Album       Album       `json:"album"`
+	Artists     []Artists   `json:"artists"`
+	DurationMs  int         `json:"duration_ms"`
+	Explicit    bool        `json:"explicit"`
+	Popularity  int         `json:"popularity"`
+	DiscNumber  int         `json:"disc_number"`
+	TrackNumber int         `json:"track_number"`
+	ExternalIDs ExternalIDs `json:"external_ids"`
+	PreviewURL  string      `json:"preview_url"`
+	IsPlayable  *bool       `json:"is_playable"`
+	LinkedFrom  *LinkedFrom `json:"linked_from"`
 }
 
+const (
+	trackItemType     = "track"
+	playlistPageLimit = 50
+)
+
 type Item struct {
-	Track Track `json:"track"`
+	IsLocal bool   `json:"is_local"`
+	Track   *Track `json:"track"`
 }
 
 type GetPlaylistResp struct {
 	Items []Item `json:"items"`
 }
 
-func (c APICLient) GetPlaylist(id string) ([]*model.Track, error) {
-	req, err := http.NewRequest("GET", baseURL+"/v1/playlists/"+id+"/tracks", nil)
+type playlistPage struct {
+	Items []json.RawMessage `json:"items"`
+	Next  *string           `json:"next"`
+}
+
+type playlistItems struct {
+	Items []json.RawMessage `json:"items"`
+}
+
+func (c APICLient) GetPlaylist(id string, market string) ([]*model.Track, error) {
+	body, err := c.FetchPlaylist(id, market)
 	if err != nil {
 		return nil, err
 	}
 
-	query := req.URL.Query()
-	query.Add("fields", "items(track(album(id,name,images(url,width)),artists(id,name),id,name))")
-	query.Add("limit", "5")
+	return ParsePlaylist(body)
+}
 
-	req.URL.RawQuery = query.Encode()
+func (c APICLient) FetchPlaylist(id string, market string) ([]byte, error) {
+	items := make([]json.RawMessage, 0)
+
+	for offset := 0; ; offset += playlistPageLimit {
+		body, err := c.fetchPlaylistPage(id, market, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		page, err := decodeBody[playlistPage](body)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, page.Items...)
+
+		if page.Next == nil || len(page.Items) == 0 {
+			break
+		}
+	}
+
+	return json.Marshal(playlistItems{Items: items})
+}
 
-	res, err := c.httpClient.Do(req)
+func (c APICLient) fetchPlaylistPage(id string, market string, offset int) ([]byte, error) {
+	req, err := http.NewRequest("GET", baseURL+"/v1/playlists/"+id+"/tracks", nil)
 	if err != nil {
 		return nil, err
 	}
 
-	defer res.Body.Close()
-
-	if res.StatusCode != http.StatusOK {
-		return nil, regErrRespToErr(&res.Body)
+	query := req.URL.Query()
+	query.Add("fields", "next,items(is_local,track(type,album(id,name,images(url,width),release_date,release_date_precision,album_type,total_tracks),"+
+		"artists(id,name),id,name,"+
+		"duration_ms,explicit,popularity,disc_number,track_number,external_ids(isrc),preview_url,is_playable,linked_from(id)))")
+	query.Add("limit", strconv.Itoa(playlistPageLimit))
+	query.Add("offset", strconv.Itoa(offset))
+
+	if len(market) != 0 {
+		query.Add("market", market)
 	}
 
-	resp, err := decodeResp[GetPlaylistResp](&res.Body)
+	req.URL.RawQuery = query.Encode()
+
+	return c.fetch(req)
+}
+
+func ParsePlaylist(body []byte) ([]*model.Track, error) {
+	resp, err := decodeBody[GetPlaylistResp](body)
 	if err != nil {
 		return nil, err
 	}
 
 	tracks := make([]*model.Track, 0)
 
-	for _, spotifyTrack := range resp.Items {
-		tracks = append(tracks, spotifyTrackToTrack(&spotifyTrack.Track))
+	for _, item := range resp.Items {
+		if !item.isTrack() {
+			tracks = append(tracks, nil)
+			continue
+		}
+
+		tracks = append(tracks, spotifyTrackToTrack(item.Track))
 	}
 
 	return tracks, nil
 }
 
-func spotifyTrackToTrack(track *Track) *model.Track {
-	album := model.Album{
-		SpotifyID: track.Album.ID,
-		Name:      track.Name,
-		Images:    make([]model.Image, 0),
+func (item *Item) isTrack() bool {
+	if item.IsLocal || item.Track == nil || len(item.Track.ID) == 0 {
+		return false
 	}
 
-	for _, spotifyImage := range track.Album.Images {
+	return len(item.Track.Type) == 0 || item.Track.Type == trackItemType
+}
+
+func spotifyAlbumToAlbum(spotifyAlbum *Album) *model.Album {
+	album := &model.Album{
+		SpotifyID:            spotifyAlbum.ID,
+		Name:                 spotifyAlbum.Name,
+		Images:               make([]model.Image, 0),
+		ReleaseDate:          spotifyAlbum.ReleaseDate,
+		ReleaseDatePrecision: spotifyAlbum.ReleaseDatePrecision,
+		AlbumType:            spotifyAlbum.AlbumType,
+		TotalTracks:          spotifyAlbum.TotalTracks,
+		Label:                spotifyAlbum.Label,
+		Copyrights:           make([]model.Copyright, 0),
+	}
+
+	for _, spotifyImage := range spotifyAlbum.Images {
 		image := model.Image{
 			URL:   spotifyImage.URL,
 			Width: spotifyImage.Width,
@@ -89,6 +187,21 @@ func spotifyTrackToTrack(track *Track) *model.Track {
 		album.Images = append(album.Images, image)
 	}
 
+	for _, spotifyCopyright := range spotifyAlbum.Copyrights {
+		copyright := model.Copyright{
+			Text: spotifyCopyright.Text,
+			Type: spotifyCopyright.Type,
+		}
+
+		album.Copyrights = append(album.Copyrights, copyright)
+	}
+
+	return album
+}
+
+func spotifyTrackToTrack(track *Track) *model.Track {
+	album := spotifyAlbumToAlbum(&track.Album)
+
 	artists := make([]model.Artist, 0)
 
 	for _, spotifyArtist := range track.Artists {
@@ -100,10 +213,25 @@ func spotifyTrackToTrack(track *Track) *model.Track {
 		artists = append(artists, artist)
 	}
 
+	linkedFromID := ""
+
+	if track.LinkedFrom != nil && track.LinkedFrom.ID != track.ID {
+		linkedFromID = track.LinkedFrom.ID
+	}
+
 	return &model.Track{
-		SpotifyID: track.ID,
-		Name:      track.Name,
-		Album:     album,
-		Artists:   artists,
+		SpotifyID:    track.ID,
+		Name:         track.Name,
+		Album:        *album,
+		Artists:      artists,
+		DurationMs:   track.DurationMs,
+		Explicit:     track.Explicit,
+		Popularity:   track.Popularity,
+		DiscNumber:   track.DiscNumber,
+		TrackNumber:  track.TrackNumber,
+		ISRC:         track.ExternalIDs.ISRC,
+		PreviewURL:   track.PreviewURL,
+		IsPlayable:   track.IsPlayable == nil || *track.IsPlayable,
+		LinkedFromID: linkedFromID,
 	}
 }
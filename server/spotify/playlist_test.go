@@ -0,0 +1,115 @@
+package spotify
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParsePlaylistSkipsNonTrackItems(t *testing.T) {
+	body, err := os.ReadFile("testdata/playlist.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracks, err := ParsePlaylist(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"t1", "", "", "", "", "t2m"}
+
+	if len(tracks) != len(want) {
+		t.Fatalf("got %d chart slots, want %d", len(tracks), len(want))
+	}
+
+	for position, trackID := range want {
+		track := tracks[position]
+
+		if len(trackID) == 0 {
+			if track != nil {
+				t.Errorf("position %d holds track %q, want an empty slot", position, track.SpotifyID)
+			}
+
+			continue
+		}
+
+		if track == nil || track.SpotifyID != trackID {
+			t.Errorf("position %d holds %v, want track %q", position, track, trackID)
+		}
+	}
+
+	if tracks[5].LinkedFromID != "t2" {
+		t.Errorf("relinked track has linked from ID %q, want %q", tracks[5].LinkedFromID, "t2")
+	}
+}
+
+func TestItemIsTrack(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		want bool
+	}{
+		{"track", Item{Track: &Track{Type: "track", ID: "t1"}}, true},
+		{"track without a type", Item{Track: &Track{ID: "t1"}}, true},
+		{"null track", Item{}, false},
+		{"local file", Item{IsLocal: true, Track: &Track{Type: "track", ID: "t1"}}, false},
+		{"episode", Item{Track: &Track{Type: "episode", ID: "e1"}}, false},
+		{"empty ID", Item{Track: &Track{Type: "track"}}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.item.isTrack(); got != test.want {
+			t.Errorf("%s: isTrack() = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGetPlaylistReadsAllPages(t *testing.T) {
+	const total = 120
+
+	offsets := make([]string, 0)
+
+	client := NewAPIClient("id", "secret", roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		query := req.URL.Query()
+		offsets = append(offsets, query.Get("offset"))
+
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		limit, _ := strconv.Atoi(query.Get("limit"))
+
+		items := make([]string, 0)
+		for index := offset; index < total && index < offset+limit; index++ {
+			items = append(items, fmt.Sprintf(`{"is_local":false,"track":{"type":"track","id":"t%d","name":"Track %d"}}`, index, index))
+		}
+
+		next := "null"
+		if offset+limit < total {
+			next = fmt.Sprintf(`"%s/v1/playlists/p1/tracks?offset=%d"`, baseURL, offset+limit)
+		}
+
+		return jsonResponse(req, `{"items":[`+strings.Join(items, ",")+`],"next":`+next+`}`), nil
+	}))
+	defer client.Close()
+
+	tracks, err := client.GetPlaylist("p1", "SK")
+	if err != nil {
+		t.Fatalf("GetPlaylist failed: %s", err)
+	}
+
+	if len(tracks) != total {
+		t.Fatalf("got %d tracks, want %d", len(tracks), total)
+	}
+
+	for position, track := range tracks {
+		if want := fmt.Sprintf("t%d", position); track == nil || track.SpotifyID != want {
+			t.Fatalf("position %d holds %v, want track %q", position, track, want)
+		}
+	}
+
+	if want := []string{"0", "50", "100"}; strings.Join(offsets, ",") != strings.Join(want, ",") {
+		t.Errorf("requested offsets %v, want %v", offsets, want)
+	}
+}
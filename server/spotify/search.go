@@ -0,0 +1,94 @@
+package spotify
+
+import (
+	"net/http"
+	"net/url"
+	"spotify-charter/model"
+)
+
+type Owner struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+}
+
+type SimplifiedPlaylist struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Owner       Owner  `json:"owner"`
+}
+
+type PlaylistPage struct {
+	Items []*SimplifiedPlaylist `json:"items"`
+}
+
+type PlaylistsResp struct {
+	Playlists PlaylistPage `json:"playlists"`
+}
+
+func (c APICLient) SearchPlaylists(query string, market string) ([]*model.Playlist, error) {
+	req, err := http.NewRequest("GET", baseURL+"/v1/search", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	params := req.URL.Query()
+	params.Add("q", query)
+	params.Add("type", "playlist")
+	params.Add("limit", "20")
+
+	if len(market) != 0 {
+		params.Add("market", market)
+	}
+
+	req.URL.RawQuery = params.Encode()
+
+	return c.getPlaylists(req)
+}
+
+func (c APICLient) GetCategoryPlaylists(categoryID string, country string) ([]*model.Playlist, error) {
+	req, err := http.NewRequest("GET", baseURL+"/v1/browse/categories/"+url.PathEscape(categoryID)+"/playlists", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	params := req.URL.Query()
+	params.Add("limit", "50")
+
+	if len(country) != 0 {
+		params.Add("country", country)
+	}
+
+	req.URL.RawQuery = params.Encode()
+
+	return c.getPlaylists(req)
+}
+
+func (c APICLient) getPlaylists(req *http.Request) ([]*model.Playlist, error) {
+	body, err := c.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := decodeBody[PlaylistsResp](body)
+	if err != nil {
+		return nil, err
+	}
+
+	playlists := make([]*model.Playlist, 0)
+
+	for _, spotifyPlaylist := range resp.Playlists.Items {
+		if spotifyPlaylist == nil {
+			continue
+		}
+
+		playlists = append(playlists, &model.Playlist{
+			SpotifyID:   spotifyPlaylist.ID,
+			Name:        spotifyPlaylist.Name,
+			Description: spotifyPlaylist.Description,
+			OwnerID:     spotifyPlaylist.Owner.ID,
+		})
+	}
+
+	return playlists, nil
+}
@@ -0,0 +1,37 @@
+package spotify
+
+import "spotify-charter/model"
+
+const MaxTrackIDs = 50
+
+type GetTracksResp struct {
+	Tracks []*Track `json:"tracks"`
+}
+
+func (c APICLient) GetTracks(ids []string) ([]*model.Track, error) {
+	body, err := c.fetchIDs("/v1/tracks", ids, model.TracksResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseTracks(body)
+}
+
+func ParseTracks(body []byte) ([]*model.Track, error) {
+	resp, err := decodeBody[GetTracksResp](body)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]*model.Track, 0)
+
+	for _, spotifyTrack := range resp.Tracks {
+		if spotifyTrack == nil {
+			continue
+		}
+
+		tracks = append(tracks, spotifyTrackToTrack(spotifyTrack))
+	}
+
+	return tracks, nil
+}